@@ -5,6 +5,10 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/janpfeifer/GoSpot/internal/game"
 	"github.com/janpfeifer/GoSpot/internal/server"
@@ -14,8 +18,34 @@ var (
 	flagAddr    = flag.String("addr", "", "Address to listen on (default: auto-port on localhost)")
 	flagDevMode = flag.Bool("dev", false, "Enable development mode: set version to random value, "+
 		"to force WASM reload on every restart")
+	flagPlayersFile = flag.String("players_file", "gospot_players.json", "File where the statistics of the players are saved. "+
+		"If empty, they are only kept in memory and lost when the server stops")
+	flagLeaderboardFile = flag.String("leaderboard_file", "gospot_leaderboard.json", "File where the best solo games are saved. "+
+		"If empty, they are only kept in memory and lost when the server stops")
+	flagReplaysDir = flag.String("replays_dir", "gospot_replays", "Directory where the replays of the games are saved. "+
+		"If empty, only the last ones are kept in memory")
+	flagMatchLatencySpread = flag.Duration("match_latency_spread", 0, "If set, quick matches only group players whose "+
+		"measured latencies differ by at most this much, until they have waited for a while")
+	flagJSONOnly = flag.Bool("json_only", false, "Send all websocket messages as JSON, instead of the compact binary "+
+		"encoding of the frequent ones, to debug the protocol")
+	flagSnapshotFile = flag.String("snapshot_file", "gospot_snapshot.json", "File where the tables are saved when the "+
+		"server shuts down, and restored from when it starts again. If empty, the games in progress are lost on restarts")
+	flagTablesDir = flag.String("tables_dir", "", "Directory where the server instances that share their tables keep "+
+		"which instance runs each table, see -broker. If empty, the tables are not shared")
+	flagBroker = flag.String("broker", "", "Address of the broker relaying the players between the instances that "+
+		"share their tables, see -tables_dir and -serve_broker")
+	flagServeBroker = flag.Bool("serve_broker", false, "Serve the broker at the -broker address, for the other "+
+		"instances to connect to")
+	flagInstanceID = flag.String("instance_id", "", "ID of this instance among the ones sharing their tables. "+
+		"If empty, a random one")
+	flagAdminToken = flag.String("admin_token", "", "Token of the admin dashboard and API, at /admin. "+
+		"If empty, it's taken from the "+adminTokenEnv+" environment variable, and if that's empty too they are disabled")
 )
 
+// adminTokenEnv is the environment variable with the admin token, if not given with -admin_token: unlike flags,
+// it's not listed with the process.
+const adminTokenEnv = "GOSPOT_ADMIN_TOKEN"
+
 func main() {
 	flag.Parse()
 
@@ -23,14 +53,71 @@ func main() {
 		game.Version = ""
 	}
 	started := make(chan *server.ServerState, 1)
-	ctx := context.Background()
+	// Cloud Run sends SIGTERM before stopping an instance: the server then saves the tables, see -snapshot_file.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	go func() {
 		state := <-started
 		fmt.Printf("GoSpot server listening on http://%s\n", state.Address)
 	}()
 
-	if err := server.Run(ctx, *flagAddr, started); err != nil {
+	cfg := server.Config{Addr: *flagAddr, MatchLatencySpread: *flagMatchLatencySpread, JSONOnly: *flagJSONOnly,
+		SnapshotFile: *flagSnapshotFile}
+	if *flagPlayersFile != "" {
+		players, err := server.NewFilePlayerStore(*flagPlayersFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cfg.Players = players
+	}
+	leaderboard, err := server.NewLeaderboard(*flagLeaderboardFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	cfg.Leaderboard = leaderboard
+	if *flagReplaysDir != "" {
+		replays, err := server.NewDirReplayStore(*flagReplaysDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cfg.Replays = replays
+	}
+	if *flagTablesDir != "" {
+		if *flagBroker == "" {
+			log.Fatal("-tables_dir requires a -broker to relay the players between the instances")
+		}
+		tables, err := server.NewDirTableStore(*flagTablesDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cfg.TableStore = tables
+	}
+	if *flagServeBroker {
+		ln, err := net.Listen("tcp", *flagBroker)
+		if err != nil {
+			log.Fatal(err)
+		}
+		go func() {
+			if err := server.ServeBroker(ctx, ln); err != nil {
+				log.Fatal(err)
+			}
+		}()
+	}
+	if *flagBroker != "" {
+		broker, err := server.DialBroker(*flagBroker)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer broker.Close()
+		cfg.Broker = broker
+	}
+	cfg.InstanceID = *flagInstanceID
+	cfg.AdminToken = *flagAdminToken
+	if cfg.AdminToken == "" {
+		cfg.AdminToken = os.Getenv(adminTokenEnv)
+	}
+	if err := server.RunWithConfig(ctx, cfg, started); err != nil {
 		log.Fatal(err)
 	}
 }
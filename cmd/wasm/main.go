@@ -26,6 +26,18 @@ func main() {
 	// Game route for a specific game room
 	app.RouteWithRegexp("^/game/.*", func() app.Composer { return &frontend.Game{} })
 
+	// Watch route for spectators of a game room
+	app.RouteWithRegexp("^/watch/.*", func() app.Composer { return &frontend.Watch{} })
+
+	// Profile route with the player's statistics
+	app.Route("/profile", func() app.Composer { return &frontend.Profile{} })
+
+	// Leaderboard route with the best solo games
+	app.Route("/leaderboard", func() app.Composer { return &frontend.Leaderboard{} })
+
+	// Replay route to step through a finished game
+	app.RouteWithRegexp("^/replay/.*", func() app.Composer { return &frontend.ReplayViewer{} })
+
 	// Initialize the global app state manager
 	frontend.InitState()
 
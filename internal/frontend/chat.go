@@ -0,0 +1,160 @@
+package frontend
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/janpfeifer/GoSpot/internal/game"
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+	"k8s.io/klog/v2"
+)
+
+// ChatPanel shows the chat of the current table and lets the player send messages.
+//
+// If Collapsible, it starts collapsed showing only the number of unread messages,
+// so it doesn't get in the way during the game.
+type ChatPanel struct {
+	app.Compo
+	Collapsible bool
+
+	expanded bool
+	draft    string
+	lastSeen time.Time // Timestamp of the last message seen, to count the unread ones.
+}
+
+func (c *ChatPanel) OnMount(ctx app.Context) {
+	klog.V(1).Infof("ChatPanel: OnMount called")
+	// Messages sent before mounting (e.g.: in the lobby) are not considered unread.
+	if len(State.Chat) > 0 {
+		c.lastSeen = State.Chat[len(State.Chat)-1].Timestamp
+	}
+	State.Listeners["chat"] = func() {
+		ctx.Dispatch(func(ctx app.Context) {
+			c.markSeen()
+		})
+	}
+}
+
+func (c *ChatPanel) OnDismount() {
+	delete(State.Listeners, "chat")
+}
+
+// isOpen returns whether the messages are visible.
+func (c *ChatPanel) isOpen() bool {
+	return !c.Collapsible || c.expanded
+}
+
+// markSeen marks all current messages as seen, if they are visible.
+func (c *ChatPanel) markSeen() {
+	if c.isOpen() && len(State.Chat) > 0 {
+		c.lastSeen = State.Chat[len(State.Chat)-1].Timestamp
+	}
+}
+
+func (c *ChatPanel) unreadCount() int {
+	count := 0
+	for _, msg := range slices.Backward(State.Chat) {
+		if !msg.Timestamp.After(c.lastSeen) {
+			break
+		}
+		count++
+	}
+	return count
+}
+
+func (c *ChatPanel) onToggle(ctx app.Context, e app.Event) {
+	e.PreventDefault()
+	c.expanded = !c.expanded
+	c.markSeen()
+}
+
+func (c *ChatPanel) onDraftChange(ctx app.Context, e app.Event) {
+	c.draft = ctx.JSSrc().Get("value").String()
+}
+
+func (c *ChatPanel) onSend(ctx app.Context, e app.Event) {
+	e.PreventDefault()
+	if c.draft == "" {
+		return
+	}
+	State.SendChat(c.draft)
+	c.draft = ""
+}
+
+func (c *ChatPanel) renderMessage(msg game.ChatMessage) app.UI {
+	timeStr := msg.Timestamp.Local().Format("15:04")
+	if msg.System {
+		return app.Li().Class("chat-message").Body(
+			app.Span().Class("ins").Text(fmt.Sprintf("%s %s", timeStr, msg.Text)),
+		)
+	}
+	var name app.UI = app.Span().Text(msg.SenderName + ":")
+	if State.Player != nil && msg.SenderID == State.Player.ID {
+		name = app.Strong().Text(msg.SenderName + ":")
+	}
+	return app.Li().Class("chat-message").Body(
+		app.Small().Class("ins").Text(timeStr),
+		app.Img().
+			Src(fmt.Sprintf("/web/images/symbol_%02d.png", msg.SenderSymbol)).
+			Style("width", "20px").Style("height", "20px").Style("vertical-align", "middle"),
+		name,
+		app.Span().Text(msg.Text),
+	)
+}
+
+func (c *ChatPanel) Render() app.UI {
+	title := "Chat"
+	if c.Collapsible {
+		icon := "▸"
+		if c.expanded {
+			icon = "▾"
+		}
+		title = fmt.Sprintf("%s Chat", icon)
+		if unread := c.unreadCount(); unread > 0 && !c.expanded {
+			title = fmt.Sprintf("%s (%d)", title, unread)
+		}
+	}
+
+	var header app.UI
+	if c.Collapsible {
+		header = app.Header().Style("cursor", "pointer").OnClick(c.onToggle).Text(title)
+	} else {
+		header = app.Header().Text(title)
+	}
+	if !c.isOpen() {
+		return app.Article().Class("chat-panel").Body(header)
+	}
+
+	// The list is rendered in reverse, in a "column-reverse" container, so it stays scrolled to the bottom.
+	messages := make([]app.UI, 0, len(State.Chat))
+	for _, msg := range slices.Backward(State.Chat) {
+		messages = append(messages, c.renderMessage(msg))
+	}
+	if len(messages) == 0 {
+		messages = append(messages, app.Li().Class("chat-message ins").Text("No messages yet. Say hi!"))
+	}
+
+	return app.Article().Class("chat-panel").Body(
+		header,
+		app.Ul().Class("chat-messages").Body(messages...),
+		app.Form().OnSubmit(c.onSend).Body(
+			app.Div().Style("display", "flex").Style("gap", "0.5rem").Body(
+				app.Input().
+					Type("text").
+					Placeholder("Type a message...").
+					MaxLength(game.MaxChatLength).
+					AutoComplete(false).
+					Value(c.draft).
+					OnInput(c.onDraftChange).
+					Style("margin-bottom", "0").
+					Style("flex", "1"),
+				app.Button().
+					Type("submit").
+					Text("Send").
+					Style("margin-bottom", "0").
+					Style("width", "auto"),
+			),
+		),
+	)
+}
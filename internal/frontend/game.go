@@ -33,6 +33,8 @@ type Game struct {
 	winnerShineIDs []string
 	lastTopCard    string
 	lastRound      int
+	penaltyUntil   time.Time
+	reconnecting   bool
 
 	onUpdate func()
 }
@@ -57,6 +59,20 @@ func (g *Game) OnMount(ctx app.Context) {
 			g.State = State.Table
 			g.Error = State.Error
 
+			if State.Rejection != nil {
+				g.onClickRejected(ctx, State.Rejection)
+				State.Rejection = nil
+			}
+
+			// A click sent just before the connection was lost may never be answered: allow clicking again.
+			if g.reconnecting && !State.Reconnecting && !g.glowRed {
+				g.actionPending = false
+				g.clickedSymbol = -1
+				g.matchedSymbol = -1
+				g.glowYellow = false
+			}
+			g.reconnecting = State.Reconnecting
+
 			// Detect round change to unblock actionPending and play sounds
 			if State.Round != g.lastRound {
 				// Round changed! Check if we were waiting for a result
@@ -117,6 +133,8 @@ func (g *Game) OnMount(ctx app.Context) {
 				klog.Infof("Game component: State updated. Player count: %d", len(g.State.Players))
 				if !g.State.Started {
 					ctx.Navigate("/table/" + g.GameID)
+				} else if State.IsSpectating() {
+					ctx.Navigate("/watch/" + g.GameID)
 				}
 			} else if g.Error != "" {
 				klog.Infof("Game component: Error received. Error: %s", g.Error)
@@ -170,7 +188,7 @@ func (g *Game) OnNav(ctx app.Context) {
 	klog.Infof("Game component: Connecting to game ID: %s", g.GameID)
 	if State.Conn == nil || State.Table == nil || State.Table.ID != g.GameID {
 		// Connect to WS
-		if err := State.ConnectWS(g.GameID); err != nil {
+		if err := State.ConnectWS(g.GameID, false); err != nil {
 			g.Error = fmt.Sprintf("Failed to connect to game: %v", err)
 			klog.Errorf("Game component: Error connecting: %v", err)
 		}
@@ -187,7 +205,6 @@ func (g *Game) onReady(ctx app.Context, e app.Event) {
 	State.SendStart()
 }
 
-const PenaltyDuration = 2 * time.Second
 const GlowDuration = 500 * time.Millisecond
 const WinnerShineDuration = 2 * time.Second
 
@@ -198,7 +215,7 @@ func (g *Game) onSymbolClick(ctx app.Context, symbol int) {
 
 	g.actionPending = true
 	g.clickedSymbol = symbol
-	matched := slices.Contains(State.TargetCard, symbol)
+	matched := isLocalMatch(currentMode().Info().ID, symbol)
 
 	if matched {
 		g.matchedSymbol = symbol
@@ -210,27 +227,99 @@ func (g *Game) onSymbolClick(ctx app.Context, symbol int) {
 		})
 
 	} else {
-		// No match: set penalty time.
+		// No match: the server will also reject the click and set the penalty, but we
+		// give immediate feedback.
 		State.PlaySound("/web/sounds/wrong.mp3")
-		g.glowRed = true
-		if State.Player != nil {
-			State.Player.InPenalty = true
-		}
-		time.AfterFunc(PenaltyDuration, func() {
-			ctx.Dispatch(func(ctx app.Context) {
-				g.glowRed = false
-				g.actionPending = false
-				g.clickedSymbol = -1
-				if State.Player != nil {
-					State.Player.InPenalty = false
-				}
-			})
-		})
+		g.startPenalty(ctx, tableSettings().PenaltyDuration)
 	}
 
 	State.SendClick(symbol)
 }
 
+// isLocalMatch returns whether a click on symbol is a match according to the rules of the game mode,
+// as far as the client can tell: the server has the final word.
+func isLocalMatch(mode game.GameModeID, symbol int) bool {
+	otherTopCardHas := func() bool {
+		for id, card := range State.TopCards {
+			if id != State.Player.ID && slices.Contains(card, symbol) {
+				return true
+			}
+		}
+		return false
+	}
+	switch mode {
+	case game.ModeHotPotato:
+		return slices.Contains(State.TopCard, symbol) && otherTopCardHas()
+	case game.ModePoisonedGift:
+		return slices.Contains(State.TargetCard, symbol) && otherTopCardHas()
+	case game.ModeTriplet:
+		count := 0
+		for _, card := range State.Grid {
+			if slices.Contains(card, symbol) {
+				count++
+			}
+		}
+		return count == 3
+	default:
+		return slices.Contains(State.TargetCard, symbol)
+	}
+}
+
+// startPenalty shows the red glow and blocks clicks for the given duration.
+// If a penalty is already running, it is extended if needed.
+func (g *Game) startPenalty(ctx app.Context, duration time.Duration) {
+	g.actionPending = true
+	g.glowRed = true
+	g.glowYellow = false
+	g.matchedSymbol = -1
+	if State.Player != nil {
+		State.Player.InPenalty = true
+	}
+	until := time.Now().Add(duration)
+	if until.Before(g.penaltyUntil) {
+		return
+	}
+	g.penaltyUntil = until
+	time.AfterFunc(duration, func() {
+		ctx.Dispatch(func(ctx app.Context) {
+			if time.Now().Before(g.penaltyUntil) {
+				// Penalty was extended in the meantime.
+				return
+			}
+			g.glowRed = false
+			g.actionPending = false
+			g.clickedSymbol = -1
+			if State.Player != nil {
+				State.Player.InPenalty = false
+			}
+		})
+	})
+}
+
+// onClickRejected handles the server rejecting the player's click.
+func (g *Game) onClickRejected(ctx app.Context, reject *game.RejectMessage) {
+	klog.Infof("Game component: Click on %d rejected: %s", reject.Symbol, reject.Reason)
+	switch reject.Reason {
+	case game.RejectWrongSymbol, game.RejectInPenalty:
+		if !g.glowRed {
+			// We thought it was a match (e.g.: our card was out-of-date), the server disagreed.
+			State.PlaySound("/web/sounds/wrong.mp3")
+			g.clickedSymbol = reject.Symbol
+		}
+		if reject.PenaltyRemaining > 0 {
+			g.startPenalty(ctx, reject.PenaltyRemaining)
+		}
+	default:
+		// No penalty: simply allow the player to click again.
+		if !g.glowRed {
+			g.actionPending = false
+			g.clickedSymbol = -1
+			g.matchedSymbol = -1
+			g.glowYellow = false
+		}
+	}
+}
+
 func (g *Game) getGlowFilter(s int, isPlayerCard bool) string {
 	if isPlayerCard {
 		if s == g.clickedSymbol {
@@ -251,6 +340,16 @@ func (g *Game) getGlowFilter(s int, isPlayerCard bool) string {
 }
 
 func (g *Game) renderCard(symbols []int, size int, isClickable bool) app.UI {
+	return renderCardSVG(symbols, size, isClickable, g.actionPending, func(s int) string {
+		return g.getGlowFilter(s, isClickable)
+	})
+}
+
+// renderCardSVG renders a card as an SVG with its symbols arranged in a circle.
+//
+// If isClickable, clicking on a symbol calls the JS function triggerSymbolClick, unless disabled.
+// glowFilter returns the filter to apply to each symbol, or "none".
+func renderCardSVG(symbols []int, size int, isClickable, disabled bool, glowFilter func(s int) string) app.UI {
 	if len(symbols) == 0 {
 		return app.Div().Class("card-svg").Style("width", "100%").Style("max-width", fmt.Sprintf("%dpx", size)).Style("aspect-ratio", "1 / 1").Body(
 			app.P().Style("text-align", "center").Text("No card"),
@@ -303,7 +402,7 @@ func (g *Game) renderCard(symbols []int, size int, isClickable bool) app.UI {
 		if isClickable {
 			disabledClass := ""
 			onClickAttr := fmt.Sprintf(`onclick="triggerSymbolClick(%d)"`, s)
-			if g.actionPending {
+			if disabled {
 				disabledClass = " disabled"
 				onClickAttr = ""
 			}
@@ -323,7 +422,7 @@ func (g *Game) renderCard(symbols []int, size int, isClickable bool) app.UI {
 		}
 
 		filterStyle := ""
-		filter := g.getGlowFilter(s, isClickable)
+		filter := glowFilter(s)
 		if filter != "none" {
 			filterStyle = fmt.Sprintf(`style="filter: %s; transition: filter 0.1s ease-in-out;"`, filter)
 		} else {
@@ -350,23 +449,103 @@ func applyShineStyles(elem app.HTMLLi) app.HTMLLi {
 		Style("box-shadow", "inset 0 0 12px 4px rgba(255, 215, 0, 0.6), 0 0 15px 5px rgba(255, 215, 0, 0.4)")
 }
 
+// applyPenaltyStyles applies the red aura inline styles to an element, for players in penalty.
+func applyPenaltyStyles(elem app.HTMLLi) app.HTMLLi {
+	return elem.
+		Style("background-color", "rgba(255, 0, 0, 0.15)").
+		Style("box-shadow", "inset 0 0 12px 4px rgba(255, 0, 0, 0.5), 0 0 15px 5px rgba(255, 0, 0, 0.35)")
+}
+
+// renderGrid renders the cards face up on the table, all of them clickable.
+func (g *Game) renderGrid(cards [][]int) app.UI {
+	var items []app.UI
+	for _, card := range cards {
+		items = append(items, app.Div().Body(g.renderCard(card, 200, true)))
+	}
+	return app.Div().Body(
+		app.Div().Class("card-grid").Body(items...),
+		app.P().Class("ins").Style("text-align", "center").Text(fmt.Sprintf("%d cards left in the pile", State.PileSize)),
+	)
+}
+
+// renderTopCards renders the top cards of the players, for the game modes where they are public.
+func (g *Game) renderTopCards(players []*game.Player) app.UI {
+	var items []app.UI
+	for _, p := range players {
+		card, ok := State.TopCards[p.ID]
+		if !ok {
+			continue
+		}
+		name := p.Name
+		if p.ID == State.Player.ID {
+			name = "You"
+		}
+		items = append(items, app.Div().Body(
+			app.P().Style("text-align", "center").Style("margin-bottom", "0").Text(name),
+			g.renderCard(card, 200, false),
+		))
+	}
+	if len(items) == 0 {
+		return app.P().Aria("busy", "true").Text("Dealing...")
+	}
+	return app.Div().Class("card-grid").Body(items...)
+}
+
+// renderGameOver shows the result of the game, for the players that didn't win.
+func (g *Game) renderGameOver() app.UI {
+	winner := "nobody"
+	for _, p := range g.State.Players {
+		if p.ID == g.State.WinnerID {
+			winner = p.Name
+		}
+	}
+	if g.State.WinnerTeam != 0 {
+		winner = "Team " + game.TeamName(g.State.WinnerTeam)
+	}
+	return app.Article().Body(
+		app.H3().Text("Game over"),
+		app.P().Text(fmt.Sprintf("%s won this game of %s.", winner, g.State.Mode().Info().Name)),
+	)
+}
+
 func (g *Game) renderPlayerList(players []*game.Player) app.UI {
+	info := g.State.Mode().Info()
+	race := info.Race
+	if info.Teams {
+		// Group the players by team, each under a header.
+		players = slices.Clone(players)
+		slices.SortStableFunc(players, func(a, b *game.Player) int { return a.Team - b.Team })
+	}
 	var listItems []app.UI
-	for _, p := range players {
+	for i, p := range players {
+		if info.Teams && (i == 0 || players[i-1].Team != p.Team) {
+			header := "Team " + game.TeamName(p.Team)
+			if p.Team != 0 && p.Team == g.State.WinnerTeam {
+				header += " 🏆"
+			}
+			listItems = append(listItems, app.Li().Class("player-item-game").Body(app.Small().Text(header)))
+		}
 		var text string
 		var crown app.UI = app.Text("")
 
-		if p.Score == 0 {
-			text = fmt.Sprintf("%s: %s", p.Name, p.TimeTaken)
-			if p.ID == g.State.WinnerID {
-				crown = app.Span().Class("system-font").Text("👑 ")
-			}
+		if p.Finished && race {
+			text = fmt.Sprintf("%s: %s", p.Name, game.FormatDuration(p.TimeTaken))
 		} else {
 			text = fmt.Sprintf("%s (%d)", p.Name, p.Score)
 		}
+		if g.State.Won(p) {
+			crown = app.Span().Class("system-font").Text("👑 ")
+		}
 		li := app.Li().Class("player-item-game")
 		if slices.Contains(g.winnerShineIDs, p.ID) {
 			li = applyShineStyles(li)
+		} else if p.InPenalty {
+			li = applyPenaltyStyles(li)
+		}
+
+		if p.Disconnected {
+			text += " (offline)"
+			li = li.Style("opacity", "0.5")
 		}
 
 		var nameElem app.UI = app.Span().Text(text)
@@ -397,6 +576,7 @@ func (g *Game) Render() app.UI {
 			app.Article().Body(
 				app.H2().Text("Game Error"),
 				app.P().Style("color", "red").Text(g.Error),
+				renderReload(),
 				app.A().Href("#").OnClick(func(ctx app.Context, e app.Event) {
 					State.Error = ""
 					ctx.Navigate("/")
@@ -410,11 +590,12 @@ func (g *Game) Render() app.UI {
 		content = app.Div().Aria("busy", "true").Text("Connecting to game...")
 	} else if g.State.Started {
 		// Render Game Page using SVG and HTML
+		info := g.State.Mode().Info()
 		var otherPlayers []*game.Player
 		var finishedPlayers []*game.Player
 		for _, p := range g.State.Players {
 			if p.ID != State.Player.ID {
-				if p.Score == 0 {
+				if p.Finished && info.Race {
 					finishedPlayers = append(finishedPlayers, p)
 				} else {
 					otherPlayers = append(otherPlayers, p)
@@ -423,6 +604,9 @@ func (g *Game) Render() app.UI {
 		}
 
 		slices.SortFunc(otherPlayers, func(a, b *game.Player) int {
+			if info.HigherScoreWins {
+				return b.Score - a.Score
+			}
 			return a.Score - b.Score
 		})
 		otherPlayers = append(otherPlayers, finishedPlayers...)
@@ -439,17 +623,44 @@ func (g *Game) Render() app.UI {
 			currentPlayer = State.Player
 		}
 
-		var playerCardArea app.UI
-		if currentPlayer.Score == 0 {
-			playerCardArea = app.Img().Src("/web/images/win.png").Style("max-width", "520px").Style("max-height", "100%").Style("width", "100%").Style("height", "auto").Style("aspect-ratio", "1 / 1").Style("object-fit", "contain")
-		} else {
+		// The player's card area has what they click on, the target area what they have to match it with.
+		var playerCardArea, targetArea app.UI
+		switch info.ID {
+		case game.ModeTriplet:
+			playerCardArea = g.renderGrid(State.Grid)
+			targetArea = app.Text("")
+		case game.ModePoisonedGift:
+			playerCardArea = g.renderCard(State.TargetCard, 520, true)
+			targetArea = g.renderTopCards(g.State.Players)
+		case game.ModeHotPotato:
 			playerCardArea = g.renderCard(State.TopCard, 520, true)
+			targetArea = g.renderTopCards(otherPlayers)
+		default:
+			playerCardArea = g.renderCard(State.TopCard, 520, true)
+			targetArea = g.renderCard(State.TargetCard, 520, false)
+		}
+		if currentPlayer.Finished {
+			won := info.Race || currentPlayer.ID == g.State.WinnerID
+			if info.Teams && g.State.Finished {
+				// Finishing one's cards is not enough, the whole team has to.
+				won = g.State.Won(currentPlayer)
+			}
+			if won {
+				playerCardArea = app.Img().Src("/web/images/win.png").Style("max-width", "520px").Style("max-height", "100%").Style("width", "100%").Style("height", "auto").Style("aspect-ratio", "1 / 1").Style("object-fit", "contain")
+			} else {
+				playerCardArea = g.renderGameOver()
+			}
 		}
 
 		// Create New Game button if finished
 		var tryAgainBtn app.UI
 		var createNewGameBtn app.UI
-		if currentPlayer.Score == 0 {
+		var replayLink app.UI
+		if g.State.Finished && g.State.ReplayID != "" {
+			replayLink = app.A().Href("/replay/"+g.State.ReplayID).Role("button").Class("secondary outline").
+				Style("margin-top", "1rem").Text("Watch Replay")
+		}
+		if currentPlayer.Finished {
 			if len(g.State.Players) == 1 {
 				tryAgainBtn = app.Button().Text("Try Again!").OnClick(func(ctx app.Context, e app.Event) {
 					g.showSoloModal = true
@@ -462,20 +673,43 @@ func (g *Game) Render() app.UI {
 			}).Style("margin-top", "1rem")
 		}
 
+		// Between the games of a match, and of the rounds of a tournament.
+		var nextGame, nextRound app.UI = app.Text(""), app.Text("")
+		if match := g.State.Match; match != nil && g.State.Finished && !match.Over() {
+			if len(g.State.Players) > 0 && g.State.Players[0].ID == State.Player.ID {
+				nextGame = app.Button().Text(fmt.Sprintf("Next Game (%d of %d)", match.Played+1, match.Games)).
+					OnClick(func(ctx app.Context, e app.Event) { State.SendStart() }).Style("margin-top", "1rem")
+			} else {
+				nextGame = app.P().Class("ins").Text("Waiting for the creator to start the next game of the match...")
+			}
+		}
+		if tournament := g.State.Tournament; tournament != nil && g.State.Finished {
+			if tableID := tournament.TableOf(State.Player.ID); tableID != "" && tableID != g.GameID {
+				nextRound = app.A().Href("/table/"+tableID).Role("button").Style("margin-top", "1rem").
+					Text("Play the Next Round")
+			}
+		}
+
 		content = app.Div().Class("game-grid").Body(
 			// First Column: Players List
 			app.Div().Class("game-column").Class("players-column").Body(
 				g.renderPlayerList(append([]*game.Player{currentPlayer}, otherPlayers...)),
+				nextGame,
+				nextRound,
 				tryAgainBtn,
 				createNewGameBtn,
+				replayLink,
+				renderMatchScoreboard(g.State),
+				renderBracket(g.State.Tournament, State.Player.ID),
+				&ChatPanel{Collapsible: true},
 			),
 			// Second Column: Player's Card
 			app.Div().Class("game-column").Class("card-column").Body(
 				playerCardArea,
 			),
-			// Third Column: Target Card
+			// Third Column: Target Card, or the cards to match in other modes
 			app.Div().Class("game-column").Class("card-column").Body(
-				g.renderCard(State.TargetCard, 520, false),
+				targetArea,
 			),
 		)
 	} else {
@@ -492,7 +726,7 @@ func (g *Game) Render() app.UI {
 						Src(fmt.Sprintf("/web/images/symbol_%02d.png", g.randomSymbol)).
 						Style("width", "8em").
 						Style("height", "8em"),
-					app.P().Text("You are playing against the clock. Try to discard 10 cards as fast as you can!"),
+					app.P().Text(soloText()),
 				),
 				app.Footer().Body(
 					app.Button().Text("Ready").OnClick(g.onReady),
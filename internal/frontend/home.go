@@ -1,19 +1,28 @@
 package frontend
 
 import (
+	"encoding/json"
 	"fmt"
 	"math/rand"
+	"net/http"
+	"time"
 
 	"github.com/janpfeifer/GoSpot/internal/game"
 	"github.com/maxence-charriere/go-app/v10/pkg/app"
 	"k8s.io/klog/v2"
 )
 
+// OpenTablesRefresh is how often the list of open tables is refreshed on the Home page.
+const OpenTablesRefresh = 5 * time.Second
+
 // Home is the landing page component
 type Home struct {
 	app.Compo
-	TableName string
-	login     *Login
+	TableName  string
+	OpenTables []game.TableSummary
+	login      *Login
+
+	stopRefresh chan struct{}
 }
 
 func (h *Home) OnMount(ctx app.Context) {
@@ -25,13 +34,126 @@ func (h *Home) OnMount(ctx app.Context) {
 		})
 	}
 	State.Listeners["home"] = func() {
-		ctx.Dispatch(func(ctx app.Context) {})
+		ctx.Dispatch(func(ctx app.Context) {
+			if State.Queue != nil && State.Queue.TableID != "" {
+				// Matched: the game starts automatically once all players join the table.
+				tableID := State.Queue.TableID
+				State.Queue = nil
+				ctx.Navigate("/table/" + tableID)
+			}
+		})
 	}
 	State.SyncMusic()
+	h.startRefresh(ctx)
 }
 
 func (h *Home) OnDismount() {
 	delete(State.Listeners, "home")
+	if State.Queue != nil && State.Queue.TableID == "" {
+		State.LeaveQueue()
+	}
+	if h.stopRefresh != nil {
+		close(h.stopRefresh)
+		h.stopRefresh = nil
+	}
+}
+
+// startRefresh fetches the open tables now, and then every OpenTablesRefresh, until the component is dismounted.
+func (h *Home) startRefresh(ctx app.Context) {
+	if !app.IsClient || h.stopRefresh != nil {
+		return
+	}
+	stop := make(chan struct{})
+	h.stopRefresh = stop
+	ctx.Async(func() {
+		ticker := time.NewTicker(OpenTablesRefresh)
+		defer ticker.Stop()
+		for {
+			tables, err := fetchOpenTables()
+			if err != nil {
+				klog.Errorf("Home: Failed to fetch open tables: %v", err)
+			} else {
+				ctx.Dispatch(func(ctx app.Context) {
+					h.OpenTables = tables
+				})
+			}
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			}
+		}
+	})
+}
+
+// fetchOpenTables requests the list of public tables from the server.
+func fetchOpenTables() ([]game.TableSummary, error) {
+	u := app.Window().URL()
+	u.Path = "/api/tables"
+	u.RawQuery = ""
+	resp, err := http.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %s", resp.Status)
+	}
+	var tables []game.TableSummary
+	if err := json.NewDecoder(resp.Body).Decode(&tables); err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
+
+// renderOpenTables lists the public tables: the ones waiting for players can be joined, the others watched.
+func (h *Home) renderOpenTables() app.UI {
+	var content app.UI
+	if len(h.OpenTables) == 0 {
+		content = app.P().Class("ins").Text("No open tables right now: create one and make it public!")
+	} else {
+		var rows []app.UI
+		for _, t := range h.OpenTables {
+			modeName := string(t.Mode)
+			if mode := game.LookupGameMode(t.Mode); mode != nil {
+				modeName = mode.Info().Name
+			}
+			var action app.UI
+			switch {
+			case t.Started:
+				action = app.A().Href("/watch/" + t.ID).Text("Watch")
+			case t.Players >= t.MaxPlayers:
+				action = app.A().Href("/watch/" + t.ID).Text("Full, watch")
+			default:
+				action = app.A().Href("/table/" + t.ID).Text("Join")
+			}
+			status := "Waiting for players"
+			if t.Started {
+				status = "In game"
+			}
+			rows = append(rows, app.Tr().Body(
+				app.Td().Text(t.Name),
+				app.Td().Text(modeName),
+				app.Td().Text(fmt.Sprintf("%d/%d", t.Players, t.MaxPlayers)),
+				app.Td().Text(status),
+				app.Td().Body(action),
+			))
+		}
+		content = app.Table().Body(
+			app.THead().Body(app.Tr().Body(
+				app.Th().Text("Table"),
+				app.Th().Text("Mode"),
+				app.Th().Text("Players"),
+				app.Th().Text("Status"),
+				app.Th(),
+			)),
+			app.TBody().Body(rows...),
+		)
+	}
+	return app.Article().Body(
+		app.Header().Body(app.H3().Text("Open tables")),
+		content,
+	)
 }
 
 func (h *Home) OnNav(ctx app.Context) {
@@ -58,10 +180,56 @@ func (h *Home) onCreateTable(ctx app.Context, e app.Event) {
 
 func (h *Home) onCreateSoloGame(ctx app.Context, e app.Event) {
 	e.PreventDefault()
-	soloName := fmt.Sprintf("Solo-%d", rand.Intn(1000000))
+	soloName := fmt.Sprintf("%s%d", game.SoloTablePrefix, rand.Intn(1000000))
 	ctx.Navigate("/table/" + soloName)
 }
 
+func (h *Home) onQuickMatch(ctx app.Context, e app.Event) {
+	if err := State.JoinQueue(); err != nil {
+		State.QueueError = fmt.Sprintf("Failed to join the quick match queue: %v", err)
+	}
+}
+
+func (h *Home) onLeaveQueue(ctx app.Context, e app.Event) {
+	State.LeaveQueue()
+}
+
+// renderQuickMatch renders the "Quick Match" button or, while waiting in the queue, its status.
+func (h *Home) renderQuickMatch() app.UI {
+	var content app.UI
+	if q := State.Queue; q != nil {
+		status := "Joining the queue..."
+		if q.Waiting > 0 {
+			status = fmt.Sprintf("Position %d of %d waiting players, waited %s.", q.Position, q.Waiting, q.Waited.Round(time.Second))
+			if q.Waited < q.Timeout {
+				status += fmt.Sprintf(" A match is made with %d players, or with fewer after %s.", q.TargetSize, q.Timeout.Round(time.Second))
+			} else {
+				status += " A match is made as soon as someone else joins."
+			}
+		}
+		content = app.Div().Body(
+			app.P().Aria("busy", "true").Text("Looking for players..."),
+			app.P().Text(status),
+			app.Button().Class("secondary").Text("Leave Queue").OnClick(h.onLeaveQueue),
+		)
+	} else {
+		var errMsg app.UI = app.Text("")
+		if State.QueueError != "" {
+			errMsg = app.P().Style("color", "var(--pico-del-color)").Text(State.QueueError)
+		}
+		content = app.Div().Body(
+			app.P().Text("Play with whoever is looking for a game: the game starts as soon as enough players are found."),
+			errMsg,
+			renderReload(),
+			app.Button().Text("Quick Match").OnClick(h.onQuickMatch),
+		)
+	}
+	return app.Article().Body(
+		app.Header().Body(app.H3().Text("Quick Match")),
+		content,
+	)
+}
+
 func (h *Home) onLogout(ctx app.Context, e app.Event) {
 	e.PreventDefault()
 	State.Player = nil
@@ -115,6 +283,11 @@ func (h *Home) Render() app.UI {
 					app.Button().Type("button").Class("secondary outline").Style("height", "100%").Style("width", "100%").Text("Create Solo Game").OnClick(h.onCreateSoloGame),
 				),
 			),
+			app.Footer().Body(
+				app.A().Href("/leaderboard").Text("Solo leaderboard"),
+			),
 		),
+		h.renderQuickMatch(),
+		h.renderOpenTables(),
 	)
 }
@@ -0,0 +1,223 @@
+package frontend
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/janpfeifer/GoSpot/internal/game"
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+	"k8s.io/klog/v2"
+)
+
+// leaderboardPeriodNames are the labels of the leaderboard periods.
+var leaderboardPeriodNames = map[game.LeaderboardPeriod]string{
+	game.PeriodDaily:   "Today",
+	game.PeriodWeekly:  "This week",
+	game.PeriodAllTime: "All time",
+}
+
+// Leaderboard is the page with the best solo games, for the selected rules and period.
+type Leaderboard struct {
+	app.Compo
+	Key     game.LeaderboardKey
+	Period  game.LeaderboardPeriod
+	Entries []game.LeaderboardEntry
+	Error   string
+	loading bool
+}
+
+func (l *Leaderboard) OnAppUpdate(ctx app.Context) {
+	klog.Infof("Leaderboard component: App update available, reloading...")
+	ctx.Reload()
+}
+
+func (l *Leaderboard) OnNav(ctx app.Context) {
+	klog.V(1).Infof("Leaderboard: OnNav called")
+	if State.Player == nil || State.Player.ID == "" {
+		ctx.Navigate("/?return=" + url.QueryEscape("/leaderboard"))
+		return
+	}
+	State.SyncMusic()
+	if l.Key.Mode == "" {
+		// Default to the rules of the "Create Solo Game" button.
+		defaults := game.DefaultTableSettings()
+		l.Key = game.LeaderboardKey{Mode: defaults.Mode, DeckOrder: defaults.DeckOrder, HandSize: game.SoloCardsPerPlayer}
+		l.Period = game.PeriodDaily
+	}
+	l.fetch(ctx)
+}
+
+// fetch requests the selected leaderboard from the server.
+func (l *Leaderboard) fetch(ctx app.Context) {
+	l.loading = true
+	l.Error = ""
+	key, period := l.Key, l.Period
+	ctx.Async(func() {
+		entries, err := fetchLeaderboard(key, period)
+		ctx.Dispatch(func(ctx app.Context) {
+			if key != l.Key || period != l.Period {
+				// Selection changed in the meantime.
+				return
+			}
+			l.loading = false
+			if err != nil {
+				klog.Errorf("Leaderboard: Failed to fetch %s (%s): %v", key, period, err)
+				l.Error = "Failed to load the leaderboard, please try again later."
+				return
+			}
+			l.Entries = entries
+		})
+	})
+}
+
+// fetchLeaderboard requests the best solo games of the leaderboard and period from the server.
+func fetchLeaderboard(key game.LeaderboardKey, period game.LeaderboardPeriod) ([]game.LeaderboardEntry, error) {
+	u := app.Window().URL()
+	u.Path = "/api/leaderboard"
+	u.RawQuery = url.Values{
+		"mode":       {string(key.Mode)},
+		"deck_order": {strconv.Itoa(key.DeckOrder)},
+		"hand_size":  {strconv.Itoa(key.HandSize)},
+		"period":     {string(period)},
+	}.Encode()
+	resp, err := http.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %s", resp.Status)
+	}
+	var entries []game.LeaderboardEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (l *Leaderboard) onModeChange(ctx app.Context, e app.Event) {
+	l.Key.Mode = game.GameModeID(ctx.JSSrc().Get("value").String())
+	l.fetch(ctx)
+}
+
+func (l *Leaderboard) onDeckOrderChange(ctx app.Context, e app.Event) {
+	if order, err := strconv.Atoi(ctx.JSSrc().Get("value").String()); err == nil {
+		l.Key.DeckOrder = order
+		l.fetch(ctx)
+	}
+}
+
+func (l *Leaderboard) onHandSizeChange(ctx app.Context, e app.Event) {
+	if size, err := strconv.Atoi(ctx.JSSrc().Get("value").String()); err == nil && size > 0 {
+		l.Key.HandSize = size
+		l.fetch(ctx)
+	}
+}
+
+func (l *Leaderboard) Render() app.UI {
+	if State.Player == nil {
+		return app.Main().Class("container")
+	}
+
+	var modeOptions []app.UI
+	for _, modeID := range []game.GameModeID{game.ModeTower, game.ModeWell} {
+		modeOptions = append(modeOptions, app.Option().
+			Value(string(modeID)).
+			Selected(modeID == l.Key.Mode).
+			Text(game.LookupGameMode(modeID).Info().Name))
+	}
+	var deckOptions []app.UI
+	for _, order := range game.DeckOrders {
+		size := game.TableSettings{DeckOrder: order}.DeckSize()
+		deckOptions = append(deckOptions, app.Option().
+			Value(strconv.Itoa(order)).
+			Selected(order == l.Key.DeckOrder).
+			Text(fmt.Sprintf("%d cards, %d symbols per card", size, order+1)))
+	}
+	var periodTabs []app.UI
+	for _, period := range game.LeaderboardPeriods {
+		class := "secondary outline"
+		if period == l.Period {
+			class = "secondary"
+		}
+		periodTabs = append(periodTabs, app.Button().
+			Class(class).
+			Text(leaderboardPeriodNames[period]).
+			OnClick(func(ctx app.Context, e app.Event) {
+				l.Period = period
+				l.fetch(ctx)
+			}))
+	}
+
+	return app.Main().Class("container").Body(
+		&TopBar{ShowLogout: true},
+		app.Article().Body(
+			app.Header().Body(
+				app.H2().Text("Solo Leaderboard"),
+				app.P().Text("The fastest solo games, timed by the server. Only games with the default penalty and bonus rules are ranked."),
+			),
+			app.Div().Class("grid").Body(
+				app.Label().Text("Mode").Body(
+					app.Select().OnChange(l.onModeChange).Body(modeOptions...),
+				),
+				app.Label().Text("Deck").Body(
+					app.Select().OnChange(l.onDeckOrderChange).Body(deckOptions...),
+				),
+				app.Label().Text("Cards").Body(
+					app.Input().
+						Type("number").
+						Min(1).
+						Value(l.Key.HandSize).
+						OnChange(l.onHandSizeChange),
+				),
+			),
+			app.Div().Role("group").Body(periodTabs...),
+			l.renderEntries(),
+			app.Footer().Body(
+				app.A().Href("/").Text("Back to tables"),
+			),
+		),
+	)
+}
+
+// renderEntries renders the ranking of the selected leaderboard.
+func (l *Leaderboard) renderEntries() app.UI {
+	switch {
+	case l.Error != "":
+		return app.P().Style("color", "var(--pico-del-color)").Text(l.Error)
+	case l.loading:
+		return app.P().Aria("busy", "true").Text("Loading leaderboard...")
+	case len(l.Entries) == 0:
+		return app.P().Text("No solo games ranked yet for these rules: be the first!")
+	}
+	var rows []app.UI
+	for i, entry := range l.Entries {
+		row := app.Tr()
+		if entry.PlayerID == State.Player.ID {
+			row = row.Style("font-weight", "bold")
+		}
+		rows = append(rows, row.Body(
+			app.Td().Text(fmt.Sprintf("%d.", i+1)),
+			app.Td().Body(
+				app.Img().
+					Src(fmt.Sprintf("/web/images/symbol_%02d.png", entry.Symbol)).
+					Style("width", "24px").Style("height", "24px").Style("vertical-align", "middle").Style("margin-right", "8px"),
+				app.Text(entry.Name),
+			),
+			app.Td().Text(game.FormatDuration(entry.Duration)),
+			app.Td().Text(entry.EndTime.Local().Format("2006-01-02 15:04")),
+		))
+	}
+	return app.Table().Body(
+		app.THead().Body(app.Tr().Body(
+			app.Th().Text("#"),
+			app.Th().Text("Player"),
+			app.Th().Text("Time"),
+			app.Th().Text("Date"),
+		)),
+		app.TBody().Body(rows...),
+	)
+}
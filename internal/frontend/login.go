@@ -93,7 +93,7 @@ func (l *Login) onLogin(ctx app.Context, e app.Event) {
 	}
 
 	player := game.Player{
-		ID:     fmt.Sprintf("%d", time.Now().UnixNano()),
+		ID:     game.NewPlayerID(),
 		Name:   State.PendingName,
 		Symbol: State.SymbolID,
 	}
@@ -0,0 +1,117 @@
+package frontend
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/janpfeifer/GoSpot/internal/game"
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// renderMatchScoreboard lists the players of the match of the table by accumulated points, with the points
+// won in each game played so far.
+func renderMatchScoreboard(table *game.Table) app.UI {
+	match := table.Match
+	if match == nil {
+		return app.Text("")
+	}
+	names := make(map[string]string, len(table.Players))
+	for _, p := range table.Players {
+		names[p.ID] = p.Name
+	}
+
+	headers := []app.UI{app.Th().Text("Player")}
+	for i := range match.Results {
+		headers = append(headers, app.Th().Text(fmt.Sprintf("G%d", i+1)))
+	}
+	headers = append(headers, app.Th().Text("Total"))
+
+	var rows []app.UI
+	for i, id := range match.Standings() {
+		name, ok := names[id]
+		if !ok {
+			name = id + " (left)"
+		}
+		if id == match.WinnerID {
+			name = "👑 " + name
+		}
+		cells := []app.UI{app.Td().Text(fmt.Sprintf("%d. %s", i+1, name))}
+		for _, result := range match.Results {
+			points := "-"
+			for position, resultID := range result {
+				if resultID == id {
+					points = fmt.Sprint(game.FinishPoints(position))
+				}
+			}
+			cells = append(cells, app.Td().Text(points))
+		}
+		cells = append(cells, app.Td().Style("font-weight", "bold").Text(fmt.Sprint(match.Points[id])))
+		rows = append(rows, app.Tr().Body(cells...))
+	}
+
+	status := fmt.Sprintf("Match: game %d of %d", min(match.Played+1, match.Games), match.Games)
+	if match.Over() {
+		status = fmt.Sprintf("Match over after %d games", match.Played)
+	} else if table.Finished {
+		status = fmt.Sprintf("Match: %d of %d games played", match.Played, match.Games)
+	}
+	return app.Article().Body(
+		app.Header().Text(status),
+		app.Table().Class("striped").Body(
+			app.THead().Body(app.Tr().Body(headers...)),
+			app.TBody().Body(rows...),
+		),
+	)
+}
+
+// renderBracket shows the rounds of the tournament, with the tables of each round and who advanced from them.
+// The table of the player in the current round is highlighted.
+func renderBracket(t *game.Tournament, playerID string) app.UI {
+	if t == nil {
+		return app.Text("")
+	}
+	var rounds []app.UI
+	for i, round := range t.Rounds {
+		var tables []app.UI
+		for _, tt := range round.Tables {
+			var players []string
+			for _, id := range tt.PlayerIDs {
+				name := t.Player(id).Name
+				for _, advanced := range tt.Advanced {
+					if advanced == id {
+						name += " ✓"
+					}
+				}
+				players = append(players, name)
+			}
+			status := "playing"
+			if tt.Done {
+				status = "done"
+			}
+			li := app.Li()
+			if i == len(t.Rounds)-1 && t.TableOf(playerID) == tt.TableID {
+				li = li.Style("font-weight", "bold")
+			}
+			tables = append(tables, li.Body(
+				app.A().Href("/watch/"+tt.TableID).Text(tt.TableID),
+				app.Text(fmt.Sprintf(" (%s): %s", status, strings.Join(players, ", "))),
+			))
+		}
+		rounds = append(rounds, app.Div().Body(
+			app.Strong().Text(fmt.Sprintf("Round %d", i+1)),
+			app.Ul().Body(tables...),
+		))
+	}
+
+	status := fmt.Sprintf("Tournament: %d players, round %d", len(t.Players), len(t.Rounds))
+	if t.Over() {
+		status = fmt.Sprintf("Tournament won by %s 🏆", t.Player(t.WinnerID).Name)
+	} else if t.TableOf(playerID) == "" && playerID != "" {
+		status += " (you were eliminated)"
+	}
+	return app.Article().Body(
+		app.Header().Text(status),
+		app.Small().Text(fmt.Sprintf("Players advancing from each table are marked with ✓ (up to %d per table).", t.Advance)),
+		app.Div().Body(rounds...),
+	)
+}
@@ -0,0 +1,128 @@
+package frontend
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/janpfeifer/GoSpot/internal/game"
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+	"k8s.io/klog/v2"
+)
+
+// Profile is the page with the lifetime statistics of the logged in player.
+type Profile struct {
+	app.Compo
+	Stats   *game.PlayerStats
+	Error   string
+	loading bool
+}
+
+func (p *Profile) OnAppUpdate(ctx app.Context) {
+	klog.Infof("Profile component: App update available, reloading...")
+	ctx.Reload()
+}
+
+func (p *Profile) OnNav(ctx app.Context) {
+	klog.V(1).Infof("Profile: OnNav called")
+	if State.Player == nil || State.Player.ID == "" {
+		ctx.Navigate("/?return=" + url.QueryEscape("/profile"))
+		return
+	}
+	State.SyncMusic()
+	p.loading = true
+	p.Error = ""
+	playerID := State.Player.ID
+	ctx.Async(func() {
+		stats, err := fetchPlayerStats(playerID)
+		ctx.Dispatch(func(ctx app.Context) {
+			p.loading = false
+			if err != nil {
+				klog.Errorf("Profile: Failed to fetch statistics: %v", err)
+				p.Error = "Failed to load your statistics, please try again later."
+				return
+			}
+			p.Stats = stats
+		})
+	})
+}
+
+// fetchPlayerStats requests the statistics of the player from the server.
+func fetchPlayerStats(playerID string) (*game.PlayerStats, error) {
+	u := app.Window().URL()
+	u.Path = "/api/players/" + url.PathEscape(playerID)
+	u.RawQuery = ""
+	resp, err := http.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %s", resp.Status)
+	}
+	var stats game.PlayerStats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+func (p *Profile) Render() app.UI {
+	if State.Player == nil {
+		return app.Main().Class("container")
+	}
+
+	var content app.UI
+	switch {
+	case p.Error != "":
+		content = app.P().Style("color", "var(--pico-del-color)").Text(p.Error)
+	case p.loading || p.Stats == nil:
+		content = app.P().Aria("busy", "true").Text("Loading statistics...")
+	case p.Stats.GamesPlayed == 0:
+		content = app.P().Text("You haven't finished any game yet: your statistics will show up here once you do.")
+	default:
+		content = p.renderStats()
+	}
+
+	return app.Main().Class("container").Body(
+		&TopBar{ShowLogout: true},
+		app.Article().Body(
+			app.Header().Body(
+				app.H2().Body(
+					app.Img().
+						Src(fmt.Sprintf("/web/images/symbol_%02d.png", State.Player.Symbol)).
+						Style("width", "48px").Style("height", "48px").Style("vertical-align", "middle").Style("margin-right", "12px"),
+					app.Text(State.Player.Name),
+				),
+			),
+			content,
+			app.Footer().Body(
+				app.A().Href("/").Text("Back to tables"),
+			),
+		),
+	)
+}
+
+// renderStats renders the table of statistics.
+func (p *Profile) renderStats() app.UI {
+	stats := p.Stats
+	row := func(label, value string) app.UI {
+		return app.Tr().Body(app.Th().Scope("row").Text(label), app.Td().Text(value))
+	}
+	rows := []app.UI{
+		row("Games played", fmt.Sprintf("%d", stats.GamesPlayed)),
+		row("Wins", fmt.Sprintf("%d (%.0f%%)", stats.Wins, 100*float64(stats.Wins)/float64(stats.GamesPlayed))),
+		row("Matches found", fmt.Sprintf("%d", stats.Matches)),
+		row("Average time per match", game.FormatDuration(stats.AvgTimePerMatch())),
+		row("Bonus discards", fmt.Sprintf("%d", stats.BonusDiscards)),
+	}
+	for _, mode := range game.GameModes {
+		info := mode.Info()
+		if fastest, ok := stats.FastestSolo[info.ID]; ok {
+			rows = append(rows, row("Fastest solo game of "+info.Name, game.FormatDuration(fastest)))
+		}
+	}
+	rows = append(rows, row("Last played", stats.LastPlayed.Local().Format("2006-01-02 15:04")))
+	return app.Table().Body(app.TBody().Body(rows...))
+}
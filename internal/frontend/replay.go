@@ -0,0 +1,288 @@
+package frontend
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/janpfeifer/GoSpot/internal/game"
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+	"k8s.io/klog/v2"
+)
+
+// ReplayViewer steps through the log of a finished game, card by card, showing every click with its timing.
+type ReplayViewer struct {
+	app.Compo
+	ReplayID string
+	Replay   *game.Replay
+	Error    string
+
+	steps   [][]game.GameEvent // See game.Replay.Steps.
+	step    int
+	playing bool
+}
+
+func (r *ReplayViewer) OnAppUpdate(ctx app.Context) {
+	klog.Infof("ReplayViewer component: App update available, reloading...")
+	ctx.Reload()
+}
+
+func (r *ReplayViewer) OnDismount() {
+	r.playing = false
+}
+
+func (r *ReplayViewer) OnNav(ctx app.Context) {
+	klog.V(1).Infof("ReplayViewer: OnNav called")
+	if State.Player == nil || State.Player.ID == "" {
+		ctx.Navigate("/?return=" + url.QueryEscape(app.Window().URL().Path))
+		return
+	}
+	State.SyncMusic()
+
+	r.ReplayID = strings.TrimPrefix(app.Window().URL().Path, "/replay/")
+	if r.ReplayID == "" {
+		r.Error = "No replay ID provided"
+		return
+	}
+	r.Error = ""
+	r.Replay = nil
+	r.playing = false
+	replayID := r.ReplayID
+	ctx.Async(func() {
+		replay, err := fetchReplay(replayID)
+		ctx.Dispatch(func(ctx app.Context) {
+			if err != nil {
+				klog.Errorf("ReplayViewer: Failed to fetch replay %s: %v", replayID, err)
+				r.Error = fmt.Sprintf("Failed to load replay: %v", err)
+				return
+			}
+			r.Replay = replay
+			r.steps = replay.Steps()
+			r.step = 0
+		})
+	})
+}
+
+// fetchReplay requests the replay from the server.
+func fetchReplay(replayID string) (*game.Replay, error) {
+	u := app.Window().URL()
+	u.Path = "/api/replays/" + url.PathEscape(replayID)
+	u.RawQuery = ""
+	resp, err := http.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("replay not found")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %s", resp.Status)
+	}
+	var replay game.Replay
+	if err := json.NewDecoder(resp.Body).Decode(&replay); err != nil {
+		return nil, err
+	}
+	return &replay, nil
+}
+
+// goTo moves to the step, if it exists.
+func (r *ReplayViewer) goTo(step int) {
+	if step >= 0 && step < len(r.steps) {
+		r.step = step
+	}
+}
+
+func (r *ReplayViewer) onPrev(ctx app.Context, e app.Event) {
+	r.playing = false
+	r.goTo(r.step - 1)
+}
+
+func (r *ReplayViewer) onNext(ctx app.Context, e app.Event) {
+	r.playing = false
+	r.goTo(r.step + 1)
+}
+
+func (r *ReplayViewer) onFirst(ctx app.Context, e app.Event) {
+	r.playing = false
+	r.goTo(0)
+}
+
+func (r *ReplayViewer) onLast(ctx app.Context, e app.Event) {
+	r.playing = false
+	r.goTo(len(r.steps) - 1)
+}
+
+// onPlay plays the replay from the current step, at the pace of the original game.
+func (r *ReplayViewer) onPlay(ctx app.Context, e app.Event) {
+	if r.playing {
+		r.playing = false
+		return
+	}
+	if r.step == len(r.steps)-1 {
+		r.step = 0
+	}
+	r.playing = true
+	r.scheduleNext(ctx)
+}
+
+// scheduleNext moves to the next step after the time it took in the original game, while playing.
+func (r *ReplayViewer) scheduleNext(ctx app.Context) {
+	if !r.playing || r.step+1 >= len(r.steps) {
+		r.playing = false
+		return
+	}
+	wait := r.steps[r.step+1][0].Time - r.steps[r.step][0].Time
+	replayID, step := r.ReplayID, r.step
+	ctx.After(max(wait, 200*time.Millisecond), func(ctx app.Context) {
+		if !r.playing || r.ReplayID != replayID || r.step != step {
+			// Paused, or moved to another step in the meantime.
+			return
+		}
+		r.step++
+		r.scheduleNext(ctx)
+	})
+}
+
+// describeEvent returns the description of an event of the replay.
+func (r *ReplayViewer) describeEvent(e game.GameEvent) string {
+	name := r.Replay.Player(e.PlayerID).Name
+	switch e.Type {
+	case game.EventDeal:
+		return "Cards dealt, the game starts."
+	case game.EventClick:
+		return fmt.Sprintf("%s clicked a match (latency %v, compensated by delaying it %v: it counts at %s).",
+			name, e.Latency.Round(time.Millisecond), e.Delay.Round(time.Millisecond), game.FormatDuration(e.Time+e.Delay))
+	case game.EventReject:
+		return fmt.Sprintf("%s clicked, rejected: %s (latency %v).", name, strings.ReplaceAll(string(e.Reason), "_", " "),
+			e.Latency.Round(time.Millisecond))
+	case game.EventRoundWin:
+		return fmt.Sprintf("%s wins round %d.", name, e.Round)
+	case game.EventBonus:
+		return fmt.Sprintf("%s discards %d bonus cards.", name, e.Discards)
+	case game.EventFinish:
+		return fmt.Sprintf("%s finished.", name)
+	case game.EventGameOver:
+		if e.PlayerID == "" {
+			return "Game over."
+		}
+		return fmt.Sprintf("Game over, %s wins!", name)
+	}
+	return string(e.Type)
+}
+
+// renderEvents lists the events of the current step, with their time.
+func (r *ReplayViewer) renderEvents(events []game.GameEvent) app.UI {
+	var rows []app.UI
+	for _, e := range events {
+		var symbol app.UI = app.Text("")
+		if e.Type == game.EventClick || e.Type == game.EventReject || e.Type == game.EventRoundWin {
+			symbol = app.Img().
+				Src(fmt.Sprintf("/web/images/symbol_%02d.png", e.Symbol)).
+				Style("width", "24px").Style("height", "24px").Style("vertical-align", "middle")
+		}
+		row := app.Tr()
+		if e.Type == game.EventReject {
+			row = row.Style("color", "var(--pico-del-color)")
+		}
+		rows = append(rows, row.Body(
+			app.Td().Style("font-family", "monospace").Text(game.FormatDuration(e.Time)),
+			app.Td().Body(symbol),
+			app.Td().Text(r.describeEvent(e)),
+		))
+	}
+	return app.Table().Class("striped").Body(app.TBody().Body(rows...))
+}
+
+// renderSnapshot renders the cards on the table, and the scores, at the start of the step.
+func (r *ReplayViewer) renderSnapshot(snapshot *game.ReplaySnapshot) app.UI {
+	noGlow := func(int) string { return "none" }
+	var cards []app.UI
+	for _, card := range snapshot.Grid {
+		cards = append(cards, app.Div().Body(renderCardSVG(card, 160, false, true, noGlow)))
+	}
+	var scores []app.UI
+	for _, p := range r.Replay.Players {
+		name := p.Name
+		if p.Bot != "" {
+			name += fmt.Sprintf(" (%s bot)", p.Bot)
+		}
+		li := app.Li()
+		if p.ID == r.Replay.WinnerID && r.step == len(r.steps)-1 {
+			li = applyShineStyles(li)
+		}
+		scores = append(scores, li.Body(
+			app.Img().
+				Src(fmt.Sprintf("/web/images/symbol_%02d.png", p.Symbol)).
+				Style("width", "24px").Style("height", "24px").Style("vertical-align", "middle").Style("margin-right", "8px"),
+			app.Text(fmt.Sprintf("%s (%d)", name, snapshot.Scores[p.ID])),
+		))
+		if card, ok := snapshot.TopCards[p.ID]; ok {
+			cards = append(cards, app.Div().Body(
+				app.P().Style("text-align", "center").Style("margin-bottom", "0").Text(p.Name),
+				renderCardSVG(card, 160, false, true, noGlow),
+			))
+		}
+	}
+	var target app.UI = app.Text("")
+	if len(snapshot.TargetCard) > 0 {
+		target = app.Div().Style("max-width", "320px").Style("margin", "0 auto").Body(
+			renderCardSVG(snapshot.TargetCard, 320, false, true, noGlow),
+		)
+	}
+	return app.Div().Class("grid").Body(
+		app.Ul().Body(scores...),
+		app.Div().Body(
+			target,
+			app.Div().Class("card-grid").Body(cards...),
+		),
+	)
+}
+
+func (r *ReplayViewer) Render() app.UI {
+	if State.Player == nil {
+		return app.Main().Class("container")
+	}
+
+	var content app.UI
+	switch {
+	case r.Error != "":
+		content = app.P().Style("color", "var(--pico-del-color)").Text(r.Error)
+	case r.Replay == nil || len(r.steps) == 0:
+		content = app.P().Aria("busy", "true").Text("Loading replay...")
+	default:
+		events := r.steps[r.step]
+		playText := "▶ Play"
+		if r.playing {
+			playText = "⏸ Pause"
+		}
+		content = app.Div().Body(
+			app.P().Text(fmt.Sprintf("%s on table %s, %s. Step %d of %d, at %s.",
+				(&game.Table{Settings: r.Replay.Settings}).Mode().Info().Name, r.Replay.TableID, r.Replay.StartTime.Local().Format("2006-01-02 15:04"),
+				r.step+1, len(r.steps), game.FormatDuration(events[0].Time))),
+			app.Div().Role("group").Body(
+				app.Button().Class("secondary").Text("⏮").Title("First").Disabled(r.step == 0).OnClick(r.onFirst),
+				app.Button().Class("secondary").Text("◀").Title("Previous").Disabled(r.step == 0).OnClick(r.onPrev),
+				app.Button().Text(playText).OnClick(r.onPlay),
+				app.Button().Class("secondary").Text("▶").Title("Next").Disabled(r.step == len(r.steps)-1).OnClick(r.onNext),
+				app.Button().Class("secondary").Text("⏭").Title("Last").Disabled(r.step == len(r.steps)-1).OnClick(r.onLast),
+			),
+			r.renderSnapshot(events[0].Snapshot),
+			r.renderEvents(events),
+		)
+	}
+
+	return app.Main().Class("container").Body(
+		&TopBar{ShowLogout: true},
+		app.Article().Body(
+			app.Header().Body(app.H2().Text("Replay")),
+			content,
+			app.Footer().Body(
+				app.A().Href("/").Text("Back to tables"),
+			),
+		),
+	)
+}
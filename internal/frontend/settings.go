@@ -0,0 +1,216 @@
+package frontend
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/janpfeifer/GoSpot/internal/game"
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+	"k8s.io/klog/v2"
+)
+
+// tableSettings returns the settings of the current table, or the default ones if not connected to a table.
+func tableSettings() game.TableSettings {
+	if State.Table == nil {
+		return game.DefaultTableSettings()
+	}
+	return State.Table.Settings
+}
+
+// currentMode returns the game mode of the current table, or the default one if not connected to a table.
+func currentMode() game.GameMode {
+	if State.Table == nil {
+		return game.GameModes[0]
+	}
+	return State.Table.Mode()
+}
+
+// soloText explains the goal of a solo game with the current table settings.
+func soloText() string {
+	info := currentMode().Info()
+	if !info.Race {
+		return "You are playing against the clock. " + info.Description
+	}
+	settings := tableSettings()
+	return fmt.Sprintf("You are playing against the clock. Try to discard %d cards as fast as you can!",
+		settings.HandSize(1, settings.DeckSize()-1))
+}
+
+// SettingsPanel shows the settings of the table in the lobby.
+// The creator (Editable) can change them, for everyone else they are read-only.
+type SettingsPanel struct {
+	app.Compo
+	Settings game.TableSettings
+	Editable bool
+}
+
+// update applies the change to a copy of the current settings, and sends them to the server if valid.
+// The server broadcasts the new state back, so the panel is updated from there.
+func (p *SettingsPanel) update(change func(settings *game.TableSettings)) {
+	settings := p.Settings
+	change(&settings)
+	if err := settings.Validate(); err != nil {
+		klog.Errorf("SettingsPanel: Invalid settings: %v", err)
+		return
+	}
+	State.SendSettings(settings)
+}
+
+// onIntChange returns an event handler that parses the value of the input as an integer and sets it with set.
+func (p *SettingsPanel) onIntChange(set func(settings *game.TableSettings, value int)) app.EventHandler {
+	return func(ctx app.Context, e app.Event) {
+		value, err := strconv.Atoi(ctx.JSSrc().Get("value").String())
+		if err != nil {
+			return
+		}
+		p.update(func(settings *game.TableSettings) { set(settings, value) })
+	}
+}
+
+func (p *SettingsPanel) onPenaltyChange(ctx app.Context, e app.Event) {
+	seconds, err := strconv.ParseFloat(ctx.JSSrc().Get("value").String(), 64)
+	if err != nil {
+		return
+	}
+	p.update(func(settings *game.TableSettings) {
+		settings.PenaltyDuration = time.Duration(seconds * float64(time.Second))
+	})
+}
+
+func (p *SettingsPanel) onModeChange(ctx app.Context, e app.Event) {
+	mode := game.GameModeID(ctx.JSSrc().Get("value").String())
+	p.update(func(settings *game.TableSettings) { settings.Mode = mode })
+}
+
+func (p *SettingsPanel) onSymbolBonusChange(ctx app.Context, e app.Event) {
+	enabled := ctx.JSSrc().Get("checked").Bool()
+	p.update(func(settings *game.TableSettings) { settings.PlayerSymbolBonus = enabled })
+}
+
+func (p *SettingsPanel) Render() app.UI {
+	settings := p.Settings
+	disabled := !p.Editable
+	info := (&game.Table{Settings: settings}).Mode().Info()
+	symbolBonus := info.ID == game.ModeTower || info.ID == game.ModeTeamTower // Modes with the player symbol bonus
+
+	var modeOptions []app.UI
+	for _, mode := range game.GameModes {
+		modeInfo := mode.Info()
+		modeOptions = append(modeOptions, app.Option().
+			Value(string(modeInfo.ID)).
+			Selected(modeInfo.ID == info.ID).
+			Text(modeInfo.Name))
+	}
+
+	var deckOptions []app.UI
+	for _, order := range game.DeckOrders {
+		size := game.TableSettings{DeckOrder: order}.DeckSize()
+		deckOptions = append(deckOptions, app.Option().
+			Value(strconv.Itoa(order)).
+			Selected(order == settings.DeckOrder).
+			Text(fmt.Sprintf("%d cards, %d symbols per card", size, order+1)))
+	}
+
+	numberInput := func(value, minValue, maxValue int, onChange app.EventHandler) app.HTMLInput {
+		return app.Input().
+			Type("number").
+			Min(minValue).
+			Max(maxValue).
+			Value(strconv.Itoa(value)).
+			Disabled(disabled).
+			OnChange(onChange)
+	}
+
+	return app.Article().Body(
+		app.Header().Text("Settings"),
+		app.Label().Body(
+			app.Text("Game mode"),
+			app.Select().
+				Disabled(disabled).
+				OnChange(p.onModeChange).
+				Body(modeOptions...),
+			app.Small().Text(info.Description),
+		),
+		app.Div().Class("grid").Body(
+			app.Label().Body(
+				app.Text("Deck"),
+				app.Select().
+					Disabled(disabled).
+					OnChange(p.onIntChange(func(s *game.TableSettings, v int) {
+						s.DeckOrder = v
+						// Cards per player may no longer fit in the new deck.
+						s.CardsPerPlayer = min(s.CardsPerPlayer, s.DeckSize()-1)
+					})).
+					Body(deckOptions...),
+			),
+			app.Label().Body(
+				app.Text("Cards per player (0 to split the deck)"),
+				numberInput(settings.CardsPerPlayer, 0, settings.DeckSize()-1,
+					p.onIntChange(func(s *game.TableSettings, v int) { s.CardsPerPlayer = v })).
+					Disabled(disabled || !info.Race),
+			),
+			app.Label().Body(
+				app.Text("Max players"),
+				numberInput(settings.MaxPlayers, 1, game.MaxTablePlayers,
+					p.onIntChange(func(s *game.TableSettings, v int) { s.MaxPlayers = v })),
+			),
+		),
+		app.Div().Class("grid").Body(
+			app.Label().Body(
+				app.Text("Penalty for wrong clicks (seconds)"),
+				app.Input().
+					Type("number").
+					Min(0).
+					Max(game.MaxPenaltyDuration.Seconds()).
+					Step(0.5).
+					Value(strconv.FormatFloat(settings.PenaltyDuration.Seconds(), 'f', -1, 64)).
+					Disabled(disabled).
+					OnChange(p.onPenaltyChange),
+			),
+			app.Label().Body(
+				app.Text("Bonus discards"),
+				numberInput(settings.BonusDiscards, 1, game.MaxBonusDiscards,
+					p.onIntChange(func(s *game.TableSettings, v int) { s.BonusDiscards = v })).
+					Disabled(disabled || !settings.PlayerSymbolBonus || !symbolBonus),
+			),
+			app.Label().Body(
+				app.Input().
+					Type("checkbox").
+					Role("switch").
+					Checked(settings.PlayerSymbolBonus).
+					Disabled(disabled || !symbolBonus).
+					OnChange(p.onSymbolBonusChange),
+				app.Text("Player symbol bonus"),
+			),
+		),
+		app.Div().Class("grid").Body(
+			app.Label().Body(
+				app.Text("Games per match"),
+				numberInput(settings.MatchGames, 1, game.MaxMatchGames,
+					p.onIntChange(func(s *game.TableSettings, v int) { s.MatchGames = v })),
+			),
+			app.Label().Body(
+				app.Text("Tournament table size (0 for no tournament)"),
+				numberInput(settings.TournamentTableSize, 0, game.MaxTablePlayers,
+					p.onIntChange(func(s *game.TableSettings, v int) {
+						if v == 1 {
+							v = 2
+						}
+						s.TournamentTableSize = v
+						// Players advancing must fit in the new table size.
+						s.TournamentAdvance = max(1, min(s.TournamentAdvance, v-1))
+					})),
+			),
+			app.Label().Body(
+				app.Text("Players advancing per table"),
+				numberInput(settings.TournamentAdvance, 1, max(1, settings.TournamentTableSize-1),
+					p.onIntChange(func(s *game.TableSettings, v int) { s.TournamentAdvance = v })).
+					Disabled(disabled || settings.TournamentTableSize == 0),
+			),
+		),
+		app.Small().Text(fmt.Sprintf("In a match, each game gives %v points by finish order. In a tournament, "+
+			"the players are split across tables when the game starts, and the best of each table advance to the "+
+			"next round, until the final table.", game.MatchPoints)),
+	)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"slices"
 	"time"
 
 	"github.com/coder/websocket"
@@ -20,6 +21,38 @@ type GlobalClientState struct {
 	Error  string
 	Conn   *websocket.Conn
 
+	// TableID of the current connection, used to reconnect.
+	TableID string
+
+	// Spectator is true if the current connection was made only to watch the table.
+	Spectator bool
+
+	// Reconnecting is true while trying to reconnect after losing the connection.
+	Reconnecting bool
+
+	// RestartNotice is set when the server announces it's restarting (see game.RestartMessage), and shown while
+	// reconnecting.
+	RestartNotice string
+
+	// Notice is the last notice of the operators of the server (see game.NoticeMessage), shown until dismissed.
+	Notice string
+
+	// Encoding negotiated with the server for the current connection, see game.WelcomeMessage.
+	Encoding game.Encoding
+
+	// ReloadNeeded is set when the server can't talk to this version of the client: the Error asks to reload
+	// the page, to get the current version.
+	ReloadNeeded bool
+
+	// resyncRequested is set after asking the server for the full state, because a state delta was missed,
+	// until it arrives.
+	resyncRequested bool
+
+	// Queue is the last status received while waiting in the quick match queue, or nil if not queued.
+	// Once matched, its TableID is set.
+	Queue      *game.QueueStatusMessage
+	QueueError string // Why the player was dropped from the queue, if they were.
+
 	// Login State (persistent across re-renders)
 	PendingName string
 	SymbolID    int
@@ -36,6 +69,13 @@ type GlobalClientState struct {
 	TargetCard []int
 	Round      int
 	ScoringIDs []string
+	TopCards   map[string][]int    // Top cards of all players, for game modes where they are public
+	Grid       [][]int             // Cards face up on the table, for game modes that use it
+	PileSize   int                 // Number of cards in the draw pile, for game modes that use it
+	Rejection  *game.RejectMessage // Last click rejected by the server, reset once handled by the Game component
+
+	// Chat messages of the current table, oldest first.
+	Chat []game.ChatMessage
 
 	// Listeners for state updates
 	Listeners map[string]func()
@@ -236,17 +276,19 @@ func InitState() {
 }
 
 // ConnectWS connects to the server and sends a join message.
-func (s *GlobalClientState) ConnectWS(tableID string) error {
+// If spectator is true, it joins the table only to watch.
+func (s *GlobalClientState) ConnectWS(tableID string, spectator bool) error {
 	if s.Conn != nil {
 		klog.Infof("ConnectWS: Closing existing connection")
-		s.Conn.CloseNow()
+		oldConn := s.Conn
+		s.Conn = nil // Signals the readLoop not to reconnect.
+		oldConn.CloseNow()
 	}
+	s.TableID = tableID
+	s.Spectator = spectator
+	s.Queue = nil
 
-	scheme := "ws"
-	if app.Window().URL().Scheme == "https" {
-		scheme = "wss"
-	}
-	wsURL := fmt.Sprintf("%s://%s/ws", scheme, app.Window().URL().Host)
+	wsURL := serverWSURL()
 	klog.Infof("ConnectWS: Connecting to %s (Table: %s)", wsURL, tableID)
 
 	// We use a context that lasts for the duration of the connection setup.
@@ -259,24 +301,35 @@ func (s *GlobalClientState) ConnectWS(tableID string) error {
 		return fmt.Errorf("dial failed: %w", err)
 	}
 
-	s.Conn = conn
-	klog.Infof("ConnectWS: Connected, sending Join message...")
+	klog.Infof("ConnectWS: Connected, sending Hello and Join messages...")
+	if err := sendHello(ctx, conn); err != nil {
+		conn.CloseNow()
+		klog.Errorf("ConnectWS: %v", err)
+		return err
+	}
 
-	// Send join message
+	// Send join message: the server resumes our seat (and hand) if we are reconnecting with the same player ID.
 	joinMsg, err := game.NewWsMessage(game.MsgTypeJoin, game.JoinMessage{
-		TableID: tableID,
-		Player:  *s.Player,
+		TableID:   tableID,
+		Player:    *s.Player,
+		Spectator: spectator,
 	})
 	if err != nil {
+		conn.CloseNow()
 		klog.Errorf("ConnectWS: Failed to create join message: %v", err)
 		return fmt.Errorf("failed to create join message: %w", err)
 	}
 
 	if err := wsjson.Write(ctx, conn, joinMsg); err != nil {
+		conn.CloseNow()
 		klog.Errorf("ConnectWS: Failed to send join: %v", err)
 		return fmt.Errorf("failed to send join: %w", err)
 	}
 
+	s.Conn = conn
+	s.Encoding = game.EncodingJSON // Until the server tells otherwise.
+	s.resyncRequested = false
+	s.Chat = nil // The server sends the table's chat history after joining.
 	klog.Infof("ConnectWS: Join message sent. Starting read loop.")
 	// Start reading loop in background
 	go s.readLoop(conn)
@@ -284,14 +337,113 @@ func (s *GlobalClientState) ConnectWS(tableID string) error {
 	return nil
 }
 
+// sendHello starts the handshake with the server, which answers with a game.WelcomeMessage.
+func sendHello(ctx context.Context, conn *websocket.Conn) error {
+	helloMsg, err := game.NewWsMessage(game.MsgTypeHello, game.HelloMessage{
+		ProtocolVersion: game.ProtocolVersion,
+		Version:         game.Version,
+		Encodings:       clientEncodings(),
+		Features:        []game.Feature{game.FeatureDeltas},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create hello message: %w", err)
+	}
+	if err := wsjson.Write(ctx, conn, helloMsg); err != nil {
+		return fmt.Errorf("failed to send hello: %w", err)
+	}
+	return nil
+}
+
+// clientEncodings returns the encodings the client asks the server for, by preference.
+// Opening the page with "?protocol=json" sticks to JSON, to debug the protocol with the browser tools.
+func clientEncodings() []game.Encoding {
+	if app.Window().URL().Query().Get("protocol") == string(game.EncodingJSON) {
+		return []game.Encoding{game.EncodingJSON}
+	}
+	return game.Encodings
+}
+
+// serverWSURL returns the URL of the server's websocket endpoint.
+func serverWSURL() string {
+	scheme := "ws"
+	if app.Window().URL().Scheme == "https" {
+		scheme = "wss"
+	}
+	return fmt.Sprintf("%s://%s/ws", scheme, app.Window().URL().Host)
+}
+
+// JoinQueue connects to the server and waits in the quick match queue.
+// The server sends QueueStatusMessage updates to State.Queue, until it sets the TableID of the match.
+func (s *GlobalClientState) JoinQueue() error {
+	if s.Conn != nil {
+		klog.Infof("JoinQueue: Closing existing connection")
+		oldConn := s.Conn
+		s.Conn = nil
+		oldConn.CloseNow()
+	}
+	s.TableID = "" // The queue connection is not reconnected if lost.
+	s.Spectator = false
+	s.QueueError = ""
+
+	wsURL := serverWSURL()
+	klog.Infof("JoinQueue: Connecting to %s", wsURL)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, wsURL, nil)
+	if err != nil {
+		klog.Errorf("JoinQueue: Dial failed: %v", err)
+		return fmt.Errorf("dial failed: %w", err)
+	}
+	if err := sendHello(ctx, conn); err != nil {
+		conn.CloseNow()
+		klog.Errorf("JoinQueue: %v", err)
+		return err
+	}
+	queueMsg, err := game.NewWsMessage(game.MsgTypeQueue, game.QueueMessage{Player: *s.Player})
+	if err != nil {
+		conn.CloseNow()
+		return fmt.Errorf("failed to create queue message: %w", err)
+	}
+	if err := wsjson.Write(ctx, conn, queueMsg); err != nil {
+		conn.CloseNow()
+		klog.Errorf("JoinQueue: Failed to send queue message: %v", err)
+		return fmt.Errorf("failed to send queue message: %w", err)
+	}
+
+	s.Conn = conn
+	s.Encoding = game.EncodingJSON
+	s.Queue = &game.QueueStatusMessage{}
+	go s.readLoop(conn)
+	return nil
+}
+
+// LeaveQueue leaves the quick match queue, if waiting in it.
+func (s *GlobalClientState) LeaveQueue() {
+	if s.Queue == nil {
+		return
+	}
+	klog.Infof("LeaveQueue: Leaving the quick match queue")
+	s.Queue = nil
+	if s.Conn != nil && s.TableID == "" {
+		conn := s.Conn
+		s.Conn = nil
+		_ = conn.Close(websocket.StatusNormalClosure, "Left the queue")
+	}
+	s.Notify()
+}
+
 func (s *GlobalClientState) readLoop(conn *websocket.Conn) {
 	ctx := context.Background()
 	klog.Infof("readLoop: started")
 	for {
+		typ, data, err := conn.Read(ctx)
 		var msg game.WsMessage
-		err := wsjson.Read(ctx, conn, &msg)
+		if err == nil {
+			msg, err = game.DecodeMessage(data, typ == websocket.MessageBinary)
+		}
 		if err != nil {
 			klog.Errorf("readLoop: WS read error: %v", err)
+			s.onConnectionLost(conn, err)
 			break
 		}
 
@@ -300,6 +452,95 @@ func (s *GlobalClientState) readLoop(conn *websocket.Conn) {
 	}
 }
 
+// Backoff parameters used to reconnect after losing the connection.
+// The attempts span roughly the server's reconnection grace period.
+const (
+	reconnectInitialDelay = 500 * time.Millisecond
+	reconnectMaxDelay     = 8 * time.Second
+	reconnectMaxAttempts  = 10
+)
+
+// onConnectionLost is called when the read loop of conn fails.
+// Unless the connection was closed on purpose, it reconnects in the background.
+func (s *GlobalClientState) onConnectionLost(conn *websocket.Conn, err error) {
+	if s.Conn != conn {
+		// Connection was replaced or closed by us.
+		return
+	}
+	s.Conn = nil
+	if s.ReloadNeeded {
+		// Reconnecting with the same version of the client won't help.
+		return
+	}
+	if s.Queue != nil && s.Queue.TableID == "" {
+		// Dropped from the quick match queue before being matched.
+		s.Queue = nil
+		s.QueueError = "Lost connection to the quick match queue."
+		s.Notify()
+		return
+	}
+	if status := websocket.CloseStatus(err); status == websocket.StatusNormalClosure || status == websocket.StatusGoingAway {
+		// Server closed the connection on purpose (e.g.: table cancelled).
+		return
+	}
+	if s.TableID == "" {
+		return
+	}
+	go s.reconnect(s.TableID, s.Spectator)
+}
+
+// reconnect tries to connect again to the table with exponential backoff.
+// It gives up if the connection is re-established elsewhere (e.g.: the player navigated to another table).
+func (s *GlobalClientState) reconnect(tableID string, spectator bool) {
+	s.Reconnecting = true
+	s.Notify()
+	defer func() {
+		s.Reconnecting = false
+		s.RestartNotice = ""
+		s.Notify()
+	}()
+
+	delay := reconnectInitialDelay
+	for attempt := 1; attempt <= reconnectMaxAttempts; attempt++ {
+		time.Sleep(delay)
+		if s.Conn != nil || s.TableID != tableID {
+			klog.Infof("reconnect: Connection re-established elsewhere, stop reconnecting")
+			return
+		}
+		klog.Infof("reconnect: Attempt %d to reconnect to table %s", attempt, tableID)
+		if err := s.ConnectWS(tableID, spectator); err == nil {
+			klog.Infof("reconnect: Reconnected to table %s", tableID)
+			return
+		}
+		delay = min(2*delay, reconnectMaxDelay)
+	}
+	klog.Errorf("reconnect: Giving up reconnecting to table %s", tableID)
+	s.Error = "Lost connection to the server."
+	s.Table = nil
+}
+
+// IsSpectating returns whether the player is only watching the current table: either because they
+// asked to, or because they joined after the game started.
+func (s *GlobalClientState) IsSpectating() bool {
+	if s.Spectator {
+		return true
+	}
+	if s.Table == nil || s.Player == nil {
+		return false
+	}
+	for _, p := range s.Table.Players {
+		if p.ID == s.Player.ID {
+			return false
+		}
+	}
+	for _, p := range s.Table.Spectators {
+		if p.ID == s.Player.ID {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *GlobalClientState) handleMessage(msg game.WsMessage) {
 	switch msg.Type {
 	case game.MsgTypeState:
@@ -317,6 +558,35 @@ func (s *GlobalClientState) handleMessage(msg game.WsMessage) {
 		klog.Infof("handleMessage: State updated. Players: %d", len(stateMsg.Table.Players))
 		State.Table = &stateMsg.Table
 		State.Error = ""
+		s.resyncRequested = false
+		s.SyncMusic()
+		s.Notify()
+
+	case game.MsgTypeStateDelta:
+		p, err := msg.Parse()
+		if err != nil {
+			klog.Errorf("handleMessage: Failed to parse state delta message: %v", err)
+			return
+		}
+		delta, ok := p.(*game.StateDeltaMessage)
+		if !ok {
+			klog.Errorf("handleMessage: Expected StateDeltaMessage, got: %T", p)
+			return
+		}
+		if State.Table != nil && State.Table.ID == delta.TableID && delta.Revision <= State.Table.Revision {
+			// Delayed delta, already covered by a full state.
+			return
+		}
+		var table *game.Table
+		if State.Table != nil {
+			table, err = State.Table.ApplyDelta(delta)
+		}
+		if table == nil {
+			klog.Warningf("handleMessage: Failed to apply state delta to revision %d, asking for the full state: %v", delta.BaseRevision, err)
+			s.SendResync()
+			return
+		}
+		State.Table = table
 		s.SyncMusic()
 		s.Notify()
 
@@ -354,6 +624,62 @@ func (s *GlobalClientState) handleMessage(msg game.WsMessage) {
 		State.TargetCard = updateMsg.TargetCard
 		State.Round = updateMsg.Round
 		State.ScoringIDs = updateMsg.ScoringIDs
+		State.TopCards = updateMsg.TopCards
+		State.Grid = updateMsg.Grid
+		State.PileSize = updateMsg.PileSize
+		s.Notify()
+
+	case game.MsgTypeReject:
+		p, err := msg.Parse()
+		if err != nil {
+			klog.Errorf("handleMessage: Failed to parse reject message: %v", err)
+			return
+		}
+		rejectMsg, ok := p.(*game.RejectMessage)
+		if !ok {
+			return
+		}
+
+		klog.Infof("handleMessage: Click on symbol %d rejected: %s (penalty remaining %v)",
+			rejectMsg.Symbol, rejectMsg.Reason, rejectMsg.PenaltyRemaining)
+		State.Rejection = rejectMsg
+		s.Notify()
+
+	case game.MsgTypeChat:
+		p, err := msg.Parse()
+		if err != nil {
+			klog.Errorf("handleMessage: Failed to parse chat message: %v", err)
+			return
+		}
+		chatMsg, ok := p.(*game.ChatMessage)
+		if !ok {
+			return
+		}
+
+		// Messages may arrive out of order: keep them sorted by timestamp.
+		idx := len(State.Chat)
+		for idx > 0 && State.Chat[idx-1].Timestamp.After(chatMsg.Timestamp) {
+			idx--
+		}
+		State.Chat = slices.Insert(State.Chat, idx, *chatMsg)
+		if excess := len(State.Chat) - game.ChatHistorySize; excess > 0 {
+			State.Chat = slices.Delete(State.Chat, 0, excess)
+		}
+		s.Notify()
+
+	case game.MsgTypeQueueStatus:
+		p, err := msg.Parse()
+		if err != nil {
+			klog.Errorf("handleMessage: Failed to parse queue status message: %v", err)
+			return
+		}
+		statusMsg, ok := p.(*game.QueueStatusMessage)
+		if !ok || State.Queue == nil {
+			return
+		}
+
+		klog.Infof("handleMessage: Queue position %d of %d, table %q", statusMsg.Position, statusMsg.Waiting, statusMsg.TableID)
+		State.Queue = statusMsg
 		s.Notify()
 
 	case game.MsgTypePing:
@@ -366,14 +692,130 @@ func (s *GlobalClientState) handleMessage(msg game.WsMessage) {
 		if !ok {
 			return
 		}
-		pongMsg, _ := game.NewWsMessage(game.MsgTypePong, game.PongMessage{
+		s.writeEncoded(game.MsgTypePong, game.PongMessage{
 			ServerTime: ping.ServerTime,
 			ClientTime: time.Now().UnixNano(),
 		})
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
-		defer cancel()
-		wsjson.Write(ctx, s.Conn, pongMsg)
+
+	case game.MsgTypeWelcome:
+		p, err := msg.Parse()
+		if err != nil {
+			klog.Errorf("handleMessage: Failed to parse welcome message: %v", err)
+			return
+		}
+		welcome, ok := p.(*game.WelcomeMessage)
+		if !ok {
+			return
+		}
+		if welcome.Error != "" {
+			klog.Errorf("handleMessage: Server version %s (protocol %d) refused the connection: %s",
+				welcome.Version, welcome.ProtocolVersion, welcome.Error)
+			s.needsReload(welcome.Error)
+			return
+		}
+		klog.Infof("handleMessage: Server version %s (protocol %d), using the %s encoding, features %v",
+			welcome.Version, welcome.ProtocolVersion, welcome.Encoding, welcome.Features)
+		s.Encoding = welcome.Encoding
+
+	case game.MsgTypeRestart:
+		p, err := msg.Parse()
+		if err != nil {
+			klog.Errorf("handleMessage: Failed to parse restart message: %v", err)
+			return
+		}
+		restart, ok := p.(*game.RestartMessage)
+		if !ok {
+			return
+		}
+		// The server closes the connection next, and the game resumes once reconnected.
+		klog.Infof("handleMessage: Server restarting: %s", restart.Message)
+		State.RestartNotice = restart.Message
+		s.Notify()
+
+	case game.MsgTypeNotice:
+		p, err := msg.Parse()
+		if err != nil {
+			klog.Errorf("handleMessage: Failed to parse notice message: %v", err)
+			return
+		}
+		notice, ok := p.(*game.NoticeMessage)
+		if !ok {
+			return
+		}
+		klog.Infof("handleMessage: Server notice: %s", notice.Message)
+		State.Notice = notice.Message
+		s.Notify()
+
+	default:
+		if msg.Optional {
+			klog.Warningf("handleMessage: Ignoring unknown optional message type %q", msg.Type)
+			return
+		}
+		klog.Errorf("handleMessage: Unknown message type %q", msg.Type)
+		s.needsReload(fmt.Sprintf("The server sent a message this version of the game doesn't know (%q), please reload the page.", msg.Type))
+	}
+}
+
+// needsReload shows the error and asks the player to reload the page, because the client is incompatible
+// with the server.
+func (s *GlobalClientState) needsReload(message string) {
+	s.ReloadNeeded = true
+	s.Error = message
+	s.Table = nil
+	if s.Queue != nil {
+		s.Queue = nil
+		s.QueueError = message
+	}
+	s.SyncMusic()
+	s.Notify()
+}
+
+// renderReload renders a button to reload the page, if the client is incompatible with the server.
+func renderReload() app.UI {
+	if !State.ReloadNeeded {
+		return app.Text("")
+	}
+	return app.P().Body(
+		app.Button().Text("Reload").OnClick(func(ctx app.Context, e app.Event) {
+			app.Window().Get("location").Call("reload")
+		}),
+	)
+}
+
+// writeEncoded sends a message to the server in the encoding negotiated for the connection.
+func (s *GlobalClientState) writeEncoded(msgType game.MessageType, payload any) {
+	if s.Conn == nil {
+		return
+	}
+	data, isBinary, err := game.EncodeMessage(s.Encoding, msgType, payload)
+	if err != nil {
+		klog.Errorf("writeEncoded: Failed to encode %s message: %v", msgType, err)
+		return
+	}
+	typ := websocket.MessageText
+	if isBinary {
+		typ = websocket.MessageBinary
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+	s.Conn.Write(ctx, typ, data)
+}
+
+// SendResync asks the server for the full state of the table, after missing a state delta.
+// It's only sent once, until the full state arrives.
+func (s *GlobalClientState) SendResync() {
+	if s.Conn == nil || s.resyncRequested {
+		return
+	}
+	msg, err := game.NewWsMessage(game.MsgTypeResync, nil)
+	if err != nil {
+		klog.Errorf("SendResync: Failed to create resync message: %v", err)
+		return
+	}
+	s.resyncRequested = true
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+	wsjson.Write(ctx, s.Conn, msg)
 }
 
 // SendStart sends a start message to the server
@@ -411,9 +853,94 @@ func (s *GlobalClientState) SendClick(symbol int) {
 	if s.Conn == nil {
 		return
 	}
-	msg, err := game.NewWsMessage(game.MsgTypeClick, game.ClickMessage{Symbol: symbol})
+	s.writeEncoded(game.MsgTypeClick, game.ClickMessage{Symbol: symbol, Round: s.Round})
+}
+
+// SendChat sends a chat message to the server, which rebroadcasts it to the table.
+func (s *GlobalClientState) SendChat(text string) {
+	if s.Conn == nil {
+		return
+	}
+	msg, err := game.NewWsMessage(game.MsgTypeChat, game.ChatMessage{Text: text})
+	if err != nil {
+		klog.Errorf("SendChat: Failed to create chat message: %v", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+	wsjson.Write(ctx, s.Conn, msg)
+}
+
+// SendSettings sends the new table settings to the server: only the creator can change them, before the game starts.
+func (s *GlobalClientState) SendSettings(settings game.TableSettings) {
+	if s.Conn == nil {
+		return
+	}
+	msg, err := game.NewWsMessage(game.MsgTypeSettings, game.SettingsMessage{Settings: settings})
+	if err != nil {
+		klog.Errorf("SendSettings: Failed to create settings message: %v", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+	wsjson.Write(ctx, s.Conn, msg)
+}
+
+// SendAddBot asks the server to add a bot player with the given level to the table: only the creator can do it.
+func (s *GlobalClientState) SendAddBot(level game.BotLevel) {
+	if s.Conn == nil {
+		return
+	}
+	msg, err := game.NewWsMessage(game.MsgTypeAddBot, game.AddBotMessage{Level: level})
+	if err != nil {
+		klog.Errorf("SendAddBot: Failed to create add bot message: %v", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+	wsjson.Write(ctx, s.Conn, msg)
+}
+
+// SendRemoveBot asks the server to remove a bot player from the table: only the creator can do it.
+func (s *GlobalClientState) SendRemoveBot(playerID string) {
+	if s.Conn == nil {
+		return
+	}
+	msg, err := game.NewWsMessage(game.MsgTypeRemoveBot, game.RemoveBotMessage{PlayerID: playerID})
+	if err != nil {
+		klog.Errorf("SendRemoveBot: Failed to create remove bot message: %v", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+	wsjson.Write(ctx, s.Conn, msg)
+}
+
+// SendVisibility asks the server to make the table public (listed in the open tables) or private:
+// only the creator can do it.
+func (s *GlobalClientState) SendVisibility(public bool) {
+	if s.Conn == nil {
+		return
+	}
+	msg, err := game.NewWsMessage(game.MsgTypeVisibility, game.VisibilityMessage{Public: public})
+	if err != nil {
+		klog.Errorf("SendVisibility: Failed to create visibility message: %v", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+	wsjson.Write(ctx, s.Conn, msg)
+}
+
+// SendTeam asks the server to move a player (or the sender, if playerID is empty) to a team, in team modes:
+// only the creator can change the team of other players.
+func (s *GlobalClientState) SendTeam(playerID string, team int) {
+	if s.Conn == nil {
+		return
+	}
+	msg, err := game.NewWsMessage(game.MsgTypeTeam, game.TeamMessage{PlayerID: playerID, Team: team})
 	if err != nil {
-		klog.Errorf("SendClick: Failed to create click message: %v", err)
+		klog.Errorf("SendTeam: Failed to create team message: %v", err)
 		return
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
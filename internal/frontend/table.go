@@ -3,6 +3,7 @@ package frontend
 import (
 	"fmt"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/janpfeifer/GoSpot/internal/game"
@@ -23,6 +24,7 @@ type Table struct {
 	showSoloModal   bool
 	soloModalShown  bool // Track if we have already auto-shown it
 	randomSymbol    int
+	botLevel        game.BotLevel // Level of the next bot to add
 
 	onUpdate func()
 }
@@ -38,6 +40,7 @@ func (t *Table) OnMount(ctx app.Context) {
 	t.showSoloModal = false
 	t.soloModalShown = false
 	t.randomSymbol = rand.Intn(57) // 0 to 56
+	t.botLevel = game.BotMedium
 	t.onUpdate = func() {
 		klog.Infof("Table component: Notify received")
 		ctx.Dispatch(func(ctx app.Context) {
@@ -47,13 +50,22 @@ func (t *Table) OnMount(ctx app.Context) {
 				klog.Infof("Table component: State updated. Player count: %d", len(t.State.Players))
 
 				// Automatically show solo modal if this is a "Solo-" game and it's our first time noticing it
-				if !t.soloModalShown && len(t.State.Players) == 1 && strings.HasPrefix(t.TableID, "Solo-") {
+				if !t.soloModalShown && len(t.State.Players) == 1 && game.IsSoloTable(t.TableID) {
 					t.soloModalShown = true
 					t.showSoloModal = true
 				}
 
 				if t.State.Started {
-					ctx.Navigate("/game/" + t.TableID)
+					if State.IsSpectating() {
+						ctx.Navigate("/watch/" + t.TableID)
+					} else {
+						ctx.Navigate("/game/" + t.TableID)
+					}
+				} else if tournament := t.State.Tournament; tournament != nil && !State.IsSpectating() {
+					// The tournament started: go to our table of the current round.
+					if tableID := tournament.TableOf(State.Player.ID); tableID != "" && tableID != t.TableID {
+						ctx.Navigate("/table/" + tableID)
+					}
 				}
 			} else if t.Error != "" {
 				klog.Infof("Table component: Error received. Error: %s", t.Error)
@@ -93,9 +105,9 @@ func (t *Table) OnNav(ctx app.Context) {
 	}
 
 	klog.Infof("Table component: Connecting to table ID: %s", t.TableID)
-	if State.Conn == nil || State.Table == nil || State.Table.ID != t.TableID {
+	if State.Conn == nil || State.Table == nil || State.Table.ID != t.TableID || State.Spectator {
 		// Connect to WS
-		if err := State.ConnectWS(t.TableID); err != nil {
+		if err := State.ConnectWS(t.TableID, false); err != nil {
 			t.Error = fmt.Sprintf("Failed to connect to table: %v", err)
 			klog.Errorf("Table component: Error connecting: %v", err)
 		}
@@ -127,6 +139,44 @@ func (t *Table) onCancel(ctx app.Context, e app.Event) {
 	ctx.Navigate("/")
 }
 
+func (t *Table) onBotLevelChange(ctx app.Context, e app.Event) {
+	t.botLevel = game.BotLevel(ctx.JSSrc().Get("value").String())
+}
+
+func (t *Table) onAddBot(ctx app.Context, e app.Event) {
+	State.SendAddBot(t.botLevel)
+}
+
+func (t *Table) onVisibilityChange(ctx app.Context, e app.Event) {
+	State.SendVisibility(ctx.JSSrc().Get("checked").Bool())
+}
+
+// renderTeamSelect renders the team of the player in the lobby of team modes: as a selector, if the player can
+// change it (it's their own, or the user is the creator), or as text otherwise.
+func (t *Table) renderTeamSelect(p *game.Player, editable bool) app.UI {
+	if !editable {
+		return app.Small().Style("margin-left", "8px").Text("Team " + game.TeamName(p.Team))
+	}
+	playerID := p.ID
+	options := []app.UI{app.Option().Value("0").Selected(p.Team == 0).Text("No team")}
+	for team := 1; team <= game.MaxTeams; team++ {
+		options = append(options, app.Option().
+			Value(strconv.Itoa(team)).
+			Selected(p.Team == team).
+			Text("Team "+game.TeamName(team)))
+	}
+	return app.Select().
+		Style("width", "auto").Style("margin", "0 0 0 8px").Style("display", "inline-block").
+		OnChange(func(ctx app.Context, e app.Event) {
+			team, err := strconv.Atoi(ctx.JSSrc().Get("value").String())
+			if err != nil {
+				return
+			}
+			State.SendTeam(playerID, team)
+		}).
+		Body(options...)
+}
+
 func (t *Table) onToggleSound(ctx app.Context, e app.Event) {
 	e.PreventDefault()
 	State.ToggleSound()
@@ -144,6 +194,7 @@ func (t *Table) Render() app.UI {
 			app.Article().Body(
 				app.H2().Text("Table Closed"),
 				app.P().Style("color", "red").Text(t.Error),
+				renderReload(),
 				app.A().Href("#").OnClick(func(ctx app.Context, e app.Event) {
 					State.Error = ""
 					ctx.Navigate("/")
@@ -159,34 +210,116 @@ func (t *Table) Render() app.UI {
 		content = app.Div().Aria("busy", "true").Text("Redirecting to game...")
 	} else {
 		// Render Lobby
+		isCreator := len(t.State.Players) > 0 && t.State.Players[0].ID == State.Player.ID
+		teams := t.State.Mode().Info().Teams
 		var playersList []app.UI
 		for i, p := range t.State.Players {
 			name := p.Name
 			if i == 0 {
 				name += " (Creator)"
 			}
-			playersList = append(playersList, app.Li().Body(
+			if p.Bot != "" {
+				name += fmt.Sprintf(" (%s bot)", p.Bot)
+			}
+			li := app.Li()
+			if p.Disconnected {
+				name += " (offline)"
+				li = li.Style("opacity", "0.5")
+			}
+			var teamSelect app.UI = app.Text("")
+			if teams {
+				teamSelect = t.renderTeamSelect(p, isCreator || p.ID == State.Player.ID)
+			}
+			var removeBtn app.UI = app.Text("")
+			if isCreator && p.Bot != "" {
+				botID := p.ID
+				removeBtn = app.A().Href("#").Class("secondary").Style("margin-left", "8px").Title("Remove bot").
+					OnClick(func(ctx app.Context, e app.Event) {
+						e.PreventDefault()
+						State.SendRemoveBot(botID)
+					}).Text("✕")
+			}
+			playersList = append(playersList, li.Body(
 				app.Img().
 					Src(fmt.Sprintf("/web/images/symbol_%02d.png", p.Symbol)).
 					Style("width", "32px").Style("height", "32px").Style("vertical-align", "middle").Style("margin-right", "8px"),
 				app.Span().Text(name),
+				teamSelect,
+				removeBtn,
 			))
 		}
 
-		isCreator := len(t.State.Players) > 0 && t.State.Players[0].ID == State.Player.ID
-		canStart := len(t.State.Players) >= 1 // allow starting with 1 player
+		var addBot app.UI = app.Text("")
+		if isCreator && len(t.State.Players) < t.State.Settings.MaxPlayers {
+			var levelOptions []app.UI
+			for _, level := range game.BotLevels {
+				levelOptions = append(levelOptions, app.Option().
+					Value(string(level)).
+					Selected(level == t.botLevel).
+					Text(strings.ToUpper(string(level[:1]))+string(level[1:])))
+			}
+			addBot = app.Div().Style("display", "flex").Style("gap", "0.5rem").Style("align-items", "center").Body(
+				app.Select().
+					OnChange(t.onBotLevelChange).
+					Style("margin-bottom", "0").
+					Body(levelOptions...),
+				app.Button().
+					Class("secondary").
+					Text("Add Bot").
+					OnClick(t.onAddBot).
+					Style("margin-bottom", "0").
+					Style("width", "auto"),
+			)
+		}
+
+		var spectatorsInfo app.UI = app.Text("")
+		if len(t.State.Spectators) > 0 {
+			spectatorsInfo = app.P().Class("ins").Text(fmt.Sprintf("%d watching", len(t.State.Spectators)))
+		}
+
+		var visibility app.UI = app.Text("")
+		if !game.IsSoloTable(t.TableID) {
+			if isCreator {
+				visibility = app.Label().Body(
+					app.Input().
+						Type("checkbox").
+						Role("switch").
+						Checked(t.State.Public).
+						OnChange(t.onVisibilityChange),
+					app.Text("List this table in the open tables, so anyone can join"),
+				)
+			} else if t.State.Public {
+				visibility = app.P().Class("ins").Text("This table is listed in the open tables.")
+			}
+		}
+
+		if t.State.AutoStart {
+			visibility = app.P().Class("ins").Text("The game starts automatically once all players join.")
+		}
+
+		modeInfo := t.State.Mode().Info()
+		canStart := len(t.State.Players) >= modeInfo.MinPlayers // some modes allow starting with 1 player
+		startText := "Start Game"
+		if size := t.State.Settings.TournamentTableSize; size > 0 && len(t.State.Players) > size {
+			startText = "Start Tournament"
+		} else if t.State.Settings.MatchGames > 1 {
+			startText = fmt.Sprintf("Start Match (%d games)", t.State.Settings.MatchGames)
+		}
 
 		var footer app.UI
 		if isCreator {
 			var waitingMsg app.UI = app.Text("")
-			if len(t.State.Players) == 1 {
+			if !canStart {
+				waitingMsg = app.P().Class("ins").Style("text-align", "center").Text(
+					fmt.Sprintf("Waiting for more players... %s needs at least %d.", modeInfo.Name, modeInfo.MinPlayers))
+			} else if len(t.State.Players) == 1 {
 				waitingMsg = app.P().Class("ins").Style("text-align", "center").Text("Waiting for more players... or play solo!")
 			}
 			footer = app.Footer().Body(
 				waitingMsg,
 				app.Div().Style("display", "flex").Style("gap", "1rem").Style("justify-content", "center").Body(
 					app.Button().
-						Text("Start Game").
+						Text(startText).
 						Disabled(!canStart).
 						OnClick(t.onStart).
 						Style("flex", "1").
@@ -204,6 +337,9 @@ func (t *Table) Render() app.UI {
 				app.P().Text("Waiting for the creator to start the game..."),
 			)
 		}
+		if t.State.Tournament != nil {
+			footer = app.Footer().Body(renderBracket(t.State.Tournament, State.Player.ID))
+		}
 
 
 		var soloModal app.UI
@@ -216,7 +352,7 @@ func (t *Table) Render() app.UI {
 							Src(fmt.Sprintf("/web/images/symbol_%02d.png", t.randomSymbol)).
 							Style("width", "8em").
 							Style("height", "8em"),
-						app.P().Text("You are playing against the clock. Try to discard 10 cards as fast as you can!"),
+						app.P().Text(soloText()),
 					),
 					app.Footer().Body(
 						app.Button().Text("Ready").OnClick(t.onReady),
@@ -248,13 +384,18 @@ func (t *Table) Render() app.UI {
 							Style("width", "auto").
 							Style("padding", "0.5rem 1rem"),
 					),
+					visibility,
 				),
 			),
 			app.Article().Body(
-				app.Header().Text(fmt.Sprintf("Players (%d)", len(t.State.Players))),
+				app.Header().Text(fmt.Sprintf("Players (%d/%d)", len(t.State.Players), t.State.Settings.MaxPlayers)),
 				app.Ul().Body(playersList...),
+				addBot,
+				spectatorsInfo,
 				footer,
 			),
+			&SettingsPanel{Settings: t.State.Settings, Editable: isCreator},
+			&ChatPanel{},
 		)
 	}
 
@@ -84,6 +84,12 @@ func (t *TopBar) onLogout(ctx app.Context, e app.Event) {
 	ctx.Navigate("/")
 }
 
+func (t *TopBar) onDismissNotice(ctx app.Context, e app.Event) {
+	e.PreventDefault()
+	State.Notice = ""
+	State.Notify()
+}
+
 func (t *TopBar) onBannerClick(ctx app.Context, e app.Event) {
 	ctx.Navigate("/")
 }
@@ -108,13 +114,29 @@ func (t *TopBar) Render() app.UI {
 				),
 		),
 		app.Li().Body(
-			app.Span().Style("margin-right", "8px").Text(State.Player.Name),
-			app.Img().
-				Src(fmt.Sprintf("/web/images/symbol_%02d.png", State.Player.Symbol)).
-				Style("width", "32px").Style("height", "32px").Style("vertical-align", "middle"),
+			app.A().Href("/profile").Title("Your statistics").Style("text-decoration", "none").Body(
+				app.Span().Style("margin-right", "8px").Text(State.Player.Name),
+				app.Img().
+					Src(fmt.Sprintf("/web/images/symbol_%02d.png", State.Player.Symbol)).
+					Style("width", "32px").Style("height", "32px").Style("vertical-align", "middle"),
+			),
 		),
 	}
 
+	if State.Reconnecting {
+		notice := "Reconnecting..."
+		if State.RestartNotice != "" {
+			notice = State.RestartNotice
+		}
+		actions = append([]app.UI{app.Li().Aria("busy", "true").Text(notice)}, actions...)
+	}
+	if State.Notice != "" {
+		actions = append([]app.UI{app.Li().Body(
+			app.Mark().Text(State.Notice),
+			app.A().Href("#").Title("Dismiss").Style("margin-left", "8px").OnClick(t.onDismissNotice).Text("✕"),
+		)}, actions...)
+	}
+
 	if t.ShowLogout {
 		actions = append(actions, app.Li().Body(app.A().Href("#").OnClick(t.onLogout).Text("Logout")))
 	}
@@ -0,0 +1,203 @@
+package frontend
+
+import (
+	"cmp"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/janpfeifer/GoSpot/internal/game"
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+	"k8s.io/klog/v2"
+)
+
+// Watch is the spectator view of a table: it shows the target card and the leaderboard,
+// without taking a seat.
+type Watch struct {
+	app.Compo
+	TableID string
+	State   *game.Table
+	Error   string
+
+	onUpdate func()
+}
+
+func (w *Watch) OnAppUpdate(ctx app.Context) {
+	klog.Infof("Watch component: App update available, reloading...")
+	ctx.Reload()
+}
+
+func (w *Watch) OnMount(ctx app.Context) {
+	klog.Infof("Watch component: OnMount called")
+	w.State = State.Table
+	w.onUpdate = func() {
+		ctx.Dispatch(func(ctx app.Context) {
+			w.State = State.Table
+			w.Error = State.Error
+		})
+	}
+	State.Listeners["watch"] = w.onUpdate
+	State.SyncMusic()
+}
+
+func (w *Watch) OnDismount() {
+	klog.Infof("Watch component: OnDismount called")
+	delete(State.Listeners, "watch")
+}
+
+func (w *Watch) OnNav(ctx app.Context) {
+	klog.Infof("Watch component: OnNav called")
+	w.State = State.Table
+	// Check auth
+	if State.Player == nil || State.Player.ID == "" {
+		ctx.Navigate("/?return=" + url.QueryEscape(app.Window().URL().Path))
+		return
+	}
+
+	path := app.Window().URL().Path
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if len(parts) >= 2 && parts[0] == "watch" {
+		w.TableID = parts[1]
+	}
+	if w.TableID == "" {
+		w.Error = "No Table ID provided"
+		klog.Errorf("Watch component: Error: %s", w.Error)
+		return
+	}
+
+	// Players that were moved to spectators (joined mid-game) are already watching.
+	if State.Conn == nil || State.Table == nil || State.Table.ID != w.TableID || !State.IsSpectating() {
+		if err := State.ConnectWS(w.TableID, true); err != nil {
+			w.Error = fmt.Sprintf("Failed to connect to table: %v", err)
+			klog.Errorf("Watch component: Error connecting: %v", err)
+		}
+	}
+}
+
+// renderLeaderboard lists the players by score. In race modes, finished ones come first (by time).
+func (w *Watch) renderLeaderboard() app.UI {
+	info := w.State.Mode().Info()
+	players := slices.Clone(w.State.Players)
+	slices.SortStableFunc(players, func(a, b *game.Player) int {
+		if info.Race && a.Finished && b.Finished {
+			return cmp.Compare(a.TimeTaken, b.TimeTaken)
+		}
+		if info.HigherScoreWins {
+			return cmp.Compare(b.Score, a.Score)
+		}
+		return cmp.Compare(a.Score, b.Score)
+	})
+
+	var items []app.UI
+	for i, p := range players {
+		var text string
+		var crown app.UI = app.Text("")
+		if w.State.Started && p.Finished && info.Race {
+			text = fmt.Sprintf("%d. %s: %s", i+1, p.Name, game.FormatDuration(p.TimeTaken))
+		} else {
+			text = fmt.Sprintf("%d. %s (%d)", i+1, p.Name, p.Score)
+		}
+		if p.ID == w.State.WinnerID {
+			crown = app.Span().Class("system-font").Text("👑 ")
+		}
+		li := app.Li().Class("player-item-game")
+		if p.InPenalty {
+			li = applyPenaltyStyles(li)
+		}
+		if p.Disconnected {
+			text += " (offline)"
+			li = li.Style("opacity", "0.5")
+		}
+		items = append(items, li.Body(
+			app.Img().
+				Src(fmt.Sprintf("/web/images/symbol_%02d.png", p.Symbol)).
+				Style("width", "32px").Style("height", "32px"),
+			crown,
+			app.Span().Text(text),
+		))
+	}
+	return app.Ul().Class("player-list-game").Body(items...)
+}
+
+// renderReplayLink links to the replay of the game, once it's over.
+func (w *Watch) renderReplayLink() app.UI {
+	if !w.State.Finished || w.State.ReplayID == "" {
+		return app.Text("")
+	}
+	return app.A().Href("/replay/" + w.State.ReplayID).Text("Watch the replay")
+}
+
+func (w *Watch) Render() app.UI {
+	if State.Player == nil || State.Player.ID == "" {
+		return app.Main().Class("container").Body(
+			app.Div().Aria("busy", "true").Text("Redirecting to login..."),
+		)
+	}
+
+	if w.Error != "" {
+		return app.Main().Class("container").Body(
+			app.Article().Body(
+				app.H2().Text("Table Closed"),
+				app.P().Style("color", "red").Text(w.Error),
+				renderReload(),
+				app.A().Href("#").OnClick(func(ctx app.Context, e app.Event) {
+					State.Error = ""
+					ctx.Navigate("/")
+				}).Text("Return to Home"),
+			),
+		)
+	}
+
+	var content app.UI
+	if w.State == nil {
+		content = app.Div().Aria("busy", "true").Text("Connecting to table...")
+	} else {
+		var cardArea app.UI
+		noGlow := func(int) string { return "none" }
+		if w.State.Started {
+			// Show the cards on the table: the grid, or the target card and the public top cards.
+			var cards []app.UI
+			for _, card := range State.Grid {
+				cards = append(cards, app.Div().Body(renderCardSVG(card, 200, false, true, noGlow)))
+			}
+			for _, p := range w.State.Players {
+				if card, ok := State.TopCards[p.ID]; ok {
+					cards = append(cards, app.Div().Body(
+						app.P().Style("text-align", "center").Style("margin-bottom", "0").Text(p.Name),
+						renderCardSVG(card, 200, false, true, noGlow),
+					))
+				}
+			}
+			var target app.UI = app.Text("")
+			if len(State.TargetCard) > 0 {
+				target = renderCardSVG(State.TargetCard, 520, false, true, noGlow)
+			}
+			cardArea = app.Div().Body(
+				target,
+				app.Div().Class("card-grid").Body(cards...),
+			)
+		} else {
+			cardArea = app.P().Aria("busy", "true").Text("Waiting for the game to start...")
+		}
+		content = app.Div().Class("game-grid").Style("grid-template-columns", "auto 1fr").Body(
+			app.Div().Class("game-column").Class("players-column").Body(
+				app.H4().Text(fmt.Sprintf("Watching: %s", w.TableID)),
+				w.renderLeaderboard(),
+				app.P().Class("ins").Text(fmt.Sprintf("%d watching", len(w.State.Spectators))),
+				w.renderReplayLink(),
+				renderMatchScoreboard(w.State),
+				renderBracket(w.State.Tournament, ""),
+				&ChatPanel{Collapsible: true},
+			),
+			app.Div().Class("game-column").Class("card-column").Body(
+				cardArea,
+			),
+		)
+	}
+
+	return app.Main().Class("container").Class("game-page-main").Body(
+		&TopBar{},
+		content,
+	)
+}
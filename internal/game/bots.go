@@ -0,0 +1,13 @@
+package game
+
+// BotLevel is the skill level of a bot player, see Player.Bot.
+type BotLevel string
+
+const (
+	BotEasy   BotLevel = "easy"
+	BotMedium BotLevel = "medium"
+	BotHard   BotLevel = "hard"
+)
+
+// BotLevels lists the skill levels of bots, in the order they are offered to the creator of a table.
+var BotLevels = []BotLevel{BotEasy, BotMedium, BotHard}
@@ -0,0 +1,296 @@
+package game
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Encoding of the messages exchanged over the websocket, negotiated in the handshake: the client lists the ones it
+// supports in HelloMessage.Encodings, and the server answers with the one chosen in WelcomeMessage.Encoding.
+type Encoding string
+
+const (
+	// EncodingJSON sends every message as a JSON WsMessage in a text frame. It's always supported, and it's the
+	// one to use to debug the protocol.
+	EncodingJSON Encoding = "json"
+
+	// EncodingBinary sends the frequent messages (see binaryCodes) in a compact binary codec, in binary frames.
+	// The other messages are still sent as JSON.
+	EncodingBinary Encoding = "binary"
+)
+
+// Encodings supported, by preference.
+var Encodings = []Encoding{EncodingBinary, EncodingJSON}
+
+// NegotiateEncoding returns the first of the encodings requested by the client that the server supports, or
+// EncodingJSON if there is none.
+func NegotiateEncoding(requested, supported []Encoding) Encoding {
+	for _, enc := range requested {
+		if slices.Contains(supported, enc) {
+			return enc
+		}
+	}
+	return EncodingJSON
+}
+
+// binaryCodes are the first byte of the binary frames, identifying the type of the message that follows.
+var binaryCodes = map[MessageType]byte{
+	MsgTypeUpdate:     1,
+	MsgTypeClick:      2,
+	MsgTypePing:       3,
+	MsgTypePong:       4,
+	MsgTypeStateDelta: 5,
+}
+
+// EncodeMessage encodes the payload of a message of the given type: in the binary codec if enc is EncodingBinary
+// and the message type has one, in which case isBinary is set, or as a JSON WsMessage otherwise.
+func EncodeMessage(enc Encoding, msgType MessageType, payload any) (data []byte, isBinary bool, err error) {
+	if code, ok := binaryCodes[msgType]; ok && enc == EncodingBinary {
+		data, err = appendBinary([]byte{code}, payload)
+		return data, err == nil, err
+	}
+	msg, err := NewWsMessage(msgType, payload)
+	if err != nil {
+		return nil, false, err
+	}
+	data, err = json.Marshal(msg)
+	return data, false, err
+}
+
+// DecodeMessage decodes a message received in a binary frame (if isBinary is set), or in a JSON text frame.
+// The payload of binary messages is already decoded, and it's returned by WsMessage.Parse.
+func DecodeMessage(data []byte, isBinary bool) (WsMessage, error) {
+	var msg WsMessage
+	if !isBinary {
+		err := json.Unmarshal(data, &msg)
+		return msg, err
+	}
+	if len(data) == 0 {
+		return msg, errors.New("empty binary message")
+	}
+	for msgType, code := range binaryCodes {
+		if code == data[0] {
+			msg.Type = msgType
+		}
+	}
+	r := &binaryReader{data: data[1:]}
+	switch msg.Type {
+	case MsgTypeUpdate:
+		update := &UpdateMessage{
+			TargetCard: r.card(),
+			TopCard:    r.card(),
+			Round:      r.int(),
+		}
+		if n := r.len(); n > 0 {
+			update.ScoringIDs = make([]string, n)
+			for i := range update.ScoringIDs {
+				update.ScoringIDs[i] = r.string()
+			}
+		}
+		if n := r.len(); n > 0 {
+			update.TopCards = make(map[string][]int, n)
+			for range n {
+				id := r.string()
+				update.TopCards[id] = r.card()
+			}
+		}
+		if n := r.len(); n > 0 {
+			update.Grid = make([][]int, n)
+			for i := range update.Grid {
+				update.Grid[i] = r.card()
+			}
+		}
+		update.PileSize = r.int()
+		msg.decoded = update
+	case MsgTypeClick:
+		msg.decoded = &ClickMessage{Symbol: r.int(), Round: r.int()}
+	case MsgTypePing:
+		msg.decoded = &PingMessage{ServerTime: r.int64()}
+	case MsgTypePong:
+		msg.decoded = &PongMessage{ServerTime: r.int64(), ClientTime: r.int64()}
+	case MsgTypeStateDelta:
+		delta := &StateDeltaMessage{
+			TableID:      r.string(),
+			BaseRevision: r.int64(),
+			Revision:     r.int64(),
+			Fields:       r.fields(),
+		}
+		if n := r.len(); n > 0 {
+			delta.Players = make(map[string]map[string]json.RawMessage, n)
+			for range n {
+				id := r.string()
+				delta.Players[id] = r.fields()
+			}
+		}
+		// The length of PlayerIDs is shifted by one, so nil (unchanged) is told apart from empty.
+		if n := r.len(); n > 0 {
+			delta.PlayerIDs = make([]string, n-1)
+			for i := range delta.PlayerIDs {
+				delta.PlayerIDs[i] = r.string()
+			}
+		}
+		msg.decoded = delta
+	default:
+		return msg, fmt.Errorf("unknown binary message code %d", data[0])
+	}
+	if r.err != nil {
+		return WsMessage{}, fmt.Errorf("failed to decode binary %s message: %w", msg.Type, r.err)
+	}
+	return msg, nil
+}
+
+// appendBinary appends the binary encoding of the payload (without its message code) to data.
+// Integers are encoded as varints, and strings and slices are prefixed by their length.
+func appendBinary(data []byte, payload any) ([]byte, error) {
+	appendInt := binary.AppendVarint
+	appendLen := func(data []byte, n int) []byte { return binary.AppendUvarint(data, uint64(n)) }
+	appendString := func(data []byte, s string) []byte { return append(appendLen(data, len(s)), s...) }
+	appendCard := func(data []byte, card []int) []byte {
+		data = appendLen(data, len(card))
+		for _, symbol := range card {
+			data = appendLen(data, symbol)
+		}
+		return data
+	}
+
+	switch msg := payload.(type) {
+	case UpdateMessage:
+		data = appendCard(data, msg.TargetCard)
+		data = appendCard(data, msg.TopCard)
+		data = appendInt(data, int64(msg.Round))
+		data = appendLen(data, len(msg.ScoringIDs))
+		for _, id := range msg.ScoringIDs {
+			data = appendString(data, id)
+		}
+		data = appendLen(data, len(msg.TopCards))
+		for id, card := range msg.TopCards {
+			data = appendString(data, id)
+			data = appendCard(data, card)
+		}
+		data = appendLen(data, len(msg.Grid))
+		for _, card := range msg.Grid {
+			data = appendCard(data, card)
+		}
+		data = appendInt(data, int64(msg.PileSize))
+	case ClickMessage:
+		data = appendInt(data, int64(msg.Symbol))
+		data = appendInt(data, int64(msg.Round))
+	case PingMessage:
+		data = appendInt(data, msg.ServerTime)
+	case PongMessage:
+		data = appendInt(data, msg.ServerTime)
+		data = appendInt(data, msg.ClientTime)
+	case StateDeltaMessage:
+		// The values of the fields are kept in JSON, as they are in the full state.
+		appendFields := func(data []byte, fields map[string]json.RawMessage) []byte {
+			data = appendLen(data, len(fields))
+			for name, value := range fields {
+				data = appendString(data, name)
+				data = appendString(data, string(value))
+			}
+			return data
+		}
+		data = appendString(data, msg.TableID)
+		data = appendInt(data, msg.BaseRevision)
+		data = appendInt(data, msg.Revision)
+		data = appendFields(data, msg.Fields)
+		data = appendLen(data, len(msg.Players))
+		for id, fields := range msg.Players {
+			data = appendString(data, id)
+			data = appendFields(data, fields)
+		}
+		if msg.PlayerIDs == nil {
+			data = appendLen(data, 0)
+		} else {
+			data = appendLen(data, len(msg.PlayerIDs)+1)
+			for _, id := range msg.PlayerIDs {
+				data = appendString(data, id)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("no binary encoding for payload %T", payload)
+	}
+	return data, nil
+}
+
+// binaryReader decodes the values appended by appendBinary. After the first error, it returns zero values, and
+// the error is kept in err.
+type binaryReader struct {
+	data []byte
+	err  error
+}
+
+// maxBinaryLen limits the length of strings and slices, so corrupted messages don't allocate too much.
+const maxBinaryLen = 1 << 16
+
+func (r *binaryReader) int64() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.data)
+	if n <= 0 {
+		r.err = errors.New("invalid or truncated varint")
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *binaryReader) int() int { return int(r.int64()) }
+
+func (r *binaryReader) len() int {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 || v > maxBinaryLen {
+		r.err = errors.New("invalid or truncated length")
+		return 0
+	}
+	r.data = r.data[n:]
+	return int(v)
+}
+
+func (r *binaryReader) string() string {
+	n := r.len()
+	if r.err != nil {
+		return ""
+	}
+	if n > len(r.data) {
+		r.err = errors.New("truncated string")
+		return ""
+	}
+	s := string(r.data[:n])
+	r.data = r.data[n:]
+	return s
+}
+
+// fields returns nil if there are none, like the JSON encoding of the omitted fields of StateDeltaMessage.
+func (r *binaryReader) fields() map[string]json.RawMessage {
+	n := r.len()
+	if n == 0 {
+		return nil
+	}
+	fields := make(map[string]json.RawMessage, n)
+	for range n {
+		name := r.string()
+		fields[name] = json.RawMessage(r.string())
+	}
+	return fields
+}
+
+// card returns nil for empty cards, like the JSON encoding of nil slices.
+func (r *binaryReader) card() []int {
+	n := r.len()
+	if n == 0 {
+		return nil
+	}
+	card := make([]int, n)
+	for i := range card {
+		card[i] = r.len()
+	}
+	return card
+}
@@ -0,0 +1,105 @@
+package game
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestBinaryCodec(t *testing.T) {
+	messages := []struct {
+		msgType MessageType
+		payload any
+	}{
+		{MsgTypeUpdate, UpdateMessage{
+			TargetCard: []int{0, 1, 2, 3, 4, 5, 6, 7},
+			TopCard:    []int{0, 8, 15, 22, 29, 36, 43, 50},
+			Round:      12,
+			ScoringIDs: []string{"p1", "p2"},
+			TopCards:   map[string][]int{"p1": {1, 2, 3}, "p2": {4, 5, 6}},
+			Grid:       [][]int{{1, 2}, {3, 4}},
+			PileSize:   33,
+		}},
+		{MsgTypeUpdate, UpdateMessage{Round: 1}},
+		{MsgTypeClick, ClickMessage{Symbol: 56, Round: 300}},
+		{MsgTypePing, PingMessage{ServerTime: 1767225600123456789}},
+		{MsgTypePong, PongMessage{ServerTime: 1767225600123456789, ClientTime: -1}},
+		{MsgTypeStateDelta, StateDeltaMessage{
+			TableID:      "table",
+			BaseRevision: 41,
+			Revision:     42,
+			Fields:       map[string]json.RawMessage{"round": json.RawMessage(`13`), "target_card": json.RawMessage(`[1,2,3]`)},
+			Players:      map[string]map[string]json.RawMessage{"p1": {"in_penalty": json.RawMessage(`true`)}},
+			PlayerIDs:    []string{"p1", "p2"},
+		}},
+		{MsgTypeStateDelta, StateDeltaMessage{TableID: "table", BaseRevision: 1, Revision: 2, PlayerIDs: []string{}}},
+		{MsgTypeStateDelta, StateDeltaMessage{TableID: "table", BaseRevision: 1, Revision: 2}},
+	}
+	for _, m := range messages {
+		data, isBinary, err := EncodeMessage(EncodingBinary, m.msgType, m.payload)
+		if err != nil || !isBinary {
+			t.Fatalf("Failed to encode %s in binary (binary=%t): %v", m.msgType, isBinary, err)
+		}
+		jsonData, _, _ := EncodeMessage(EncodingJSON, m.msgType, m.payload)
+		if len(data) >= len(jsonData) {
+			t.Errorf("Expected the binary %s (%d bytes) to be smaller than the JSON (%d bytes)", m.msgType, len(data), len(jsonData))
+		}
+
+		msg, err := DecodeMessage(data, true)
+		if err != nil {
+			t.Fatalf("Failed to decode %s: %v", m.msgType, err)
+		}
+		decoded, err := msg.Parse()
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", m.msgType, err)
+		}
+		if msg.Type != m.msgType || !reflect.DeepEqual(reflect.ValueOf(decoded).Elem().Interface(), m.payload) {
+			t.Errorf("Expected %s %+v, got %s %+v", m.msgType, m.payload, msg.Type, decoded)
+		}
+
+		// Truncated messages fail to decode.
+		if _, err := DecodeMessage(data[:len(data)-1], true); err == nil {
+			t.Errorf("Expected an error decoding a truncated %s", m.msgType)
+		}
+	}
+
+	if _, err := DecodeMessage([]byte{255, 0}, true); err == nil {
+		t.Errorf("Expected an error decoding an unknown message code")
+	}
+}
+
+func TestEncodeMessageFallback(t *testing.T) {
+	// Messages without a binary codec, or with the JSON encoding, are sent as JSON.
+	for _, enc := range []Encoding{EncodingJSON, EncodingBinary} {
+		data, isBinary, err := EncodeMessage(enc, MsgTypeChat, ChatMessage{Text: "hi"})
+		if err != nil || isBinary {
+			t.Fatalf("Expected a JSON chat message with the %s encoding, got binary=%t, err=%v", enc, isBinary, err)
+		}
+		var wsMsg WsMessage
+		if err := json.Unmarshal(data, &wsMsg); err != nil || wsMsg.Type != MsgTypeChat {
+			t.Errorf("Expected a JSON chat message, got %q (%v)", data, err)
+		}
+		msg, err := DecodeMessage(data, false)
+		if err != nil {
+			t.Fatalf("Failed to decode JSON message: %v", err)
+		}
+		if chat, err := msg.Parse(); err != nil || chat.(*ChatMessage).Text != "hi" {
+			t.Errorf("Expected the chat message back, got %+v (%v)", chat, err)
+		}
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	if enc := NegotiateEncoding(Encodings, Encodings); enc != EncodingBinary {
+		t.Errorf("Expected binary encoding, got %s", enc)
+	}
+	if enc := NegotiateEncoding(Encodings, []Encoding{EncodingJSON}); enc != EncodingJSON {
+		t.Errorf("Expected JSON encoding when the server only supports it, got %s", enc)
+	}
+	if enc := NegotiateEncoding([]Encoding{"cbor"}, Encodings); enc != EncodingJSON {
+		t.Errorf("Expected JSON encoding for unknown encodings, got %s", enc)
+	}
+	if enc := NegotiateEncoding(nil, Encodings); enc != EncodingJSON {
+		t.Errorf("Expected JSON encoding for old clients, got %s", enc)
+	}
+}
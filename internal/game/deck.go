@@ -1,6 +1,7 @@
 package game
 
 import (
+	"fmt"
 	"math/rand"
 	"time"
 )
@@ -9,10 +10,22 @@ import (
 // Each card is a slice of integers representing symbol IDs.
 type Deck [][]int
 
-// GenerateDeck creates a mathematically perfect deck based on a prime order.
+// GenerateDeck creates a mathematically perfect deck (a finite projective plane) of the given order.
 // For standard gameplay, use order = 7 (yields 57 cards with 8 symbols each).
-func GenerateDeck(order int) Deck {
-	// A standard deck requires the order to be a prime number (2, 3, 5, 7, 11...)
+//
+// The order must be a prime power (2, 3, 4, 5, 7, 8, 9, 11, ...), otherwise an error is returned.
+// E.g.: order = 4 yields a kid-friendly deck of 21 cards with 5 symbols each.
+//
+// The symbols within each card are shuffled using r, so the same seeded generator yields the same deck.
+// If r is nil, the global math/rand generator is used.
+func GenerateDeck(order int, r *rand.Rand) (Deck, error) {
+	// Lines are built using the arithmetic of the finite field GF(order), which only
+	// exists if order is a prime power.
+	f, err := newField(order)
+	if err != nil {
+		return nil, fmt.Errorf("invalid deck order %d: %w", order, err)
+	}
+
 	// Total cards and symbols will equal: order^2 + order + 1
 	numCards := order*order + order + 1
 	deck := make([][]int, 0, numCards)
@@ -48,8 +61,8 @@ func GenerateDeck(order int) Deck {
 			card = make([]int, 0, order+1)
 			card = append(card, i+1)
 			for k := range order {
-				// Formula: (n + 1) + (n * k) + ((i * k + j) % n)
-				symbol := (order + 1) + (order * k) + ((i*k + j) % order)
+				// Formula: (n + 1) + (n * k) + (i * k + j), with the last term in GF(n).
+				symbol := (order + 1) + (order * k) + f.add[f.mul[i][k]][j]
 				card = append(card, symbol)
 			}
 			deck = append(deck, card)
@@ -57,26 +70,47 @@ func GenerateDeck(order int) Deck {
 	}
 
 	// Shuffle the symbols within each card.
+	intn := rand.Intn
+	if r != nil {
+		intn = r.Intn
+	}
 	for _, card := range deck {
 		for j := len(card) - 1; j >= 0; j-- {
-			k := rand.Intn(j + 1)
+			k := intn(j + 1)
 			card[j], card[k] = card[k], card[j]
 		}
 	}
 
-	return deck
+	return deck, nil
 }
 
 // GenerateStandardDeck creates a deck with order = 7.
-func GenerateStandardDeck() Deck {
-	return GenerateDeck(7)
+// See GenerateDeck for the use of r.
+func GenerateStandardDeck(r *rand.Rand) Deck {
+	deck, err := GenerateDeck(7, r)
+	if err != nil {
+		// Order 7 is prime, this never happens.
+		panic(err)
+	}
+	return deck
 }
 
-// Shuffle shuffles the deck using Fisher-Yates algorithm.
-func (d Deck) Shuffle() {
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+// Shuffle shuffles the deck using Fisher-Yates algorithm, drawing from r.
+// If r is nil, a generator seeded from the current time is used.
+func (d Deck) Shuffle(r *rand.Rand) {
+	if r == nil {
+		r = NewRand(time.Now().UnixNano())
+	}
 	for i := len(d) - 1; i > 0; i-- {
 		j := r.Intn(i + 1)
 		d[i], d[j] = d[j], d[i]
 	}
 }
+
+// NewRand returns a new random number generator for the given seed.
+//
+// Games are dealt from a generator created with the seed recorded in Table.Seed, so
+// the deck, the deal and the tie-breaks of any game can be reproduced exactly.
+func NewRand(seed int64) *rand.Rand {
+	return rand.New(rand.NewSource(seed))
+}
@@ -2,15 +2,27 @@ package game
 
 import (
 	"fmt"
+	"slices"
 	"testing"
 )
 
 func TestDeckMatchingSymbol(t *testing.T) {
-	orders := []int{2, 3, 5, 7, 11} // Prime orders
+	orders := []int{2, 3, 4, 5, 7, 8, 9, 11, 16} // Prime and prime-power orders
 
 	for _, order := range orders {
 		t.Run(fmt.Sprintf("%d", order), func(t *testing.T) {
-			deck := GenerateDeck(order)
+			deck, err := GenerateDeck(order, nil)
+			if err != nil {
+				t.Fatalf("Order %d: Failed to generate deck: %v", order, err)
+			}
+			if want := order*order + order + 1; len(deck) != want {
+				t.Fatalf("Order %d: Expected %d cards, got %d", order, want, len(deck))
+			}
+			for i, card := range deck {
+				if len(card) != order+1 {
+					t.Fatalf("Order %d: Expected %d symbols in card %d, got %v", order, order+1, i, card)
+				}
+			}
 
 			// Check that each card has exactly one matching symbol with each other card
 			for i := 0; i < len(deck); i++ {
@@ -26,6 +38,33 @@ func TestDeckMatchingSymbol(t *testing.T) {
 	}
 }
 
+func TestDeckInvalidOrder(t *testing.T) {
+	for _, order := range []int{-1, 0, 1, 6, 10, 12, 15} {
+		if _, err := GenerateDeck(order, nil); err == nil {
+			t.Errorf("Order %d: Expected an error, got none", order)
+		}
+	}
+}
+
+func TestDeckSeeded(t *testing.T) {
+	generate := func(seed int64) Deck {
+		r := NewRand(seed)
+		deck, err := GenerateDeck(7, r)
+		if err != nil {
+			t.Fatalf("Failed to generate deck: %v", err)
+		}
+		deck.Shuffle(r)
+		return deck
+	}
+	deck1, deck2 := generate(42), generate(42)
+	if !slices.EqualFunc(deck1, deck2, slices.Equal) {
+		t.Errorf("Expected the same seed to generate the same deck:\n%v\n%v", deck1, deck2)
+	}
+	if deck3 := generate(43); slices.EqualFunc(deck1, deck3, slices.Equal) {
+		t.Errorf("Expected different seeds to generate different decks")
+	}
+}
+
 func countMatches(card1, card2 []int) int {
 	matches := 0
 	for _, s1 := range card1 {
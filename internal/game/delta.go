@@ -0,0 +1,168 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// ErrRevisionGap is returned by Table.ApplyDelta when the delta doesn't apply to the revision of the table:
+// some state was missed, and the client must ask for a full resync, see MsgTypeResync.
+var ErrRevisionGap = errors.New("state delta doesn't apply to the current revision")
+
+// StateSnapshot is the JSON encoding of each of the fields of a table, as sent in a StateMessage.
+// The server keeps the last one broadcast, to send only what changed in the next one, see Diff.
+type StateSnapshot struct {
+	TableID   string
+	Revision  int64
+	Fields    map[string]json.RawMessage            // Table fields, except the players and the revision
+	Players   map[string]map[string]json.RawMessage // Fields of each player, by player ID
+	PlayerIDs []string                              // Players in order
+}
+
+// NewStateSnapshot encodes the state of the table.
+func NewStateSnapshot(t *Table) (*StateSnapshot, error) {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &StateSnapshot{TableID: t.ID, Revision: t.Revision}
+	if err := json.Unmarshal(data, &snapshot.Fields); err != nil {
+		return nil, err
+	}
+	var players []map[string]json.RawMessage
+	if err := json.Unmarshal(snapshot.Fields["players"], &players); err != nil {
+		return nil, fmt.Errorf("failed to decode players: %w", err)
+	}
+	delete(snapshot.Fields, "players")
+	delete(snapshot.Fields, "revision")
+	snapshot.Players = make(map[string]map[string]json.RawMessage, len(players))
+	snapshot.PlayerIDs = make([]string, len(players))
+	for i, p := range t.Players {
+		snapshot.PlayerIDs[i] = p.ID
+		snapshot.Players[p.ID] = players[i]
+	}
+	return snapshot, nil
+}
+
+// Diff returns the delta that takes a table from the state s to the state to.
+// Fields that are no longer present (omitted when empty) are set to null.
+func (s *StateSnapshot) Diff(to *StateSnapshot) *StateDeltaMessage {
+	delta := &StateDeltaMessage{
+		TableID:      to.TableID,
+		BaseRevision: s.Revision,
+		Revision:     to.Revision,
+		Fields:       diffFields(s.Fields, to.Fields),
+	}
+	for _, id := range to.PlayerIDs {
+		if fields := diffFields(s.Players[id], to.Players[id]); len(fields) > 0 {
+			if delta.Players == nil {
+				delta.Players = make(map[string]map[string]json.RawMessage)
+			}
+			delta.Players[id] = fields
+		}
+	}
+	if !slices.Equal(s.PlayerIDs, to.PlayerIDs) {
+		delta.PlayerIDs = append([]string{}, to.PlayerIDs...)
+	}
+	return delta
+}
+
+// diffFields returns the fields that changed from the encoding from to the encoding to.
+func diffFields(from, to map[string]json.RawMessage) map[string]json.RawMessage {
+	var changed map[string]json.RawMessage
+	set := func(key string, value json.RawMessage) {
+		if changed == nil {
+			changed = make(map[string]json.RawMessage)
+		}
+		changed[key] = value
+	}
+	for key, value := range to {
+		if old, ok := from[key]; !ok || string(old) != string(value) {
+			set(key, value)
+		}
+	}
+	for key := range from {
+		if _, ok := to[key]; !ok {
+			set(key, json.RawMessage("null"))
+		}
+	}
+	return changed
+}
+
+// ApplyDelta returns a copy of the table with the delta applied, or ErrRevisionGap if the delta doesn't apply
+// to the revision of the table (or it's for another table). The table itself is not modified.
+func (t *Table) ApplyDelta(delta *StateDeltaMessage) (*Table, error) {
+	if delta.TableID != t.ID {
+		return nil, fmt.Errorf("%w: delta for table %s, got table %s", ErrRevisionGap, delta.TableID, t.ID)
+	}
+	if delta.BaseRevision != t.Revision {
+		return nil, fmt.Errorf("%w: table at revision %d, delta from %d", ErrRevisionGap, t.Revision, delta.BaseRevision)
+	}
+	updated := *t
+	if err := applyFields(&updated, delta.Fields); err != nil {
+		return nil, err
+	}
+	players := make(map[string]*Player, len(t.Players))
+	for _, p := range t.Players {
+		players[p.ID] = p
+	}
+	for id, fields := range delta.Players {
+		var p Player
+		if old := players[id]; old != nil {
+			p = *old
+		}
+		if err := applyFields(&p, fields); err != nil {
+			return nil, fmt.Errorf("failed to apply delta of player %s: %w", id, err)
+		}
+		players[id] = &p
+	}
+	ids := delta.PlayerIDs
+	if ids == nil {
+		ids = make([]string, len(t.Players))
+		for i, p := range t.Players {
+			ids[i] = p.ID
+		}
+	}
+	updated.Players = make([]*Player, 0, len(ids))
+	for _, id := range ids {
+		p := players[id]
+		if p == nil {
+			return nil, fmt.Errorf("delta lists unknown player %s", id)
+		}
+		updated.Players = append(updated.Players, p)
+	}
+	updated.Revision = delta.Revision
+	return &updated, nil
+}
+
+// applyFields sets the fields of the struct pointed by target that are present in the JSON encoded fields,
+// by their JSON name. Fields are decoded from scratch, so values set to null are reset to their zero value.
+func applyFields(target any, fields map[string]json.RawMessage) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	v := reflect.ValueOf(target).Elem()
+	decoded := reflect.New(v.Type())
+	if err := json.Unmarshal(data, decoded.Interface()); err != nil {
+		return err
+	}
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" {
+			name = field.Name
+		}
+		if _, ok := fields[name]; ok && field.IsExported() && name != "-" {
+			v.Field(i).Set(decoded.Elem().Field(i))
+		}
+	}
+	return nil
+}
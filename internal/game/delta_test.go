@@ -0,0 +1,93 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStateDelta(t *testing.T) {
+	table := &Table{
+		ID:       "test-delta",
+		Name:     "test-delta",
+		Settings: DefaultTableSettings(),
+		Players: []*Player{
+			{ID: "p1", Name: "Alice", Symbol: 1},
+			{ID: "p2", Name: "R2-D2", Symbol: 2, Bot: BotEasy},
+		},
+		Match:    NewMatch(3),
+		Revision: 7,
+	}
+	client := cloneTable(t, table)
+	base, err := NewStateSnapshot(table)
+	if err != nil {
+		t.Fatalf("Failed to take snapshot: %v", err)
+	}
+
+	// Only the latency of one player changes.
+	table.Revision++
+	table.Players[0].Latency = 30 * time.Millisecond
+	snapshot, _ := NewStateSnapshot(table)
+	delta := base.Diff(snapshot)
+	if len(delta.Fields) != 0 || len(delta.Players) != 1 || len(delta.Players["p1"]) != 1 || delta.PlayerIDs != nil {
+		t.Errorf("Expected a delta with only the latency of p1, got %+v", delta)
+	}
+	client = testApplyDelta(t, client, delta, table)
+
+	// Players join and leave, and fields are removed.
+	base = snapshot
+	table.Revision++
+	table.Players = []*Player{table.Players[0], {ID: "p3", Name: "Bob", Symbol: 3}}
+	table.Match = nil
+	table.Started = true
+	snapshot, _ = NewStateSnapshot(table)
+	delta = base.Diff(snapshot)
+	if string(delta.Fields["match"]) != "null" || delta.PlayerIDs == nil {
+		t.Errorf("Expected the match to be removed, and the new order of players, got %+v", delta)
+	}
+	data, _ := json.Marshal(delta)
+	var received StateDeltaMessage
+	if err := json.Unmarshal(data, &received); err != nil {
+		t.Fatalf("Failed to decode delta: %v", err)
+	}
+	client = testApplyDelta(t, client, &received, table)
+
+	// Deltas from another revision, or for another table, are refused.
+	if _, err := client.ApplyDelta(delta); !errors.Is(err, ErrRevisionGap) {
+		t.Errorf("Expected a revision gap applying the delta twice, got %v", err)
+	}
+	delta.TableID, delta.BaseRevision = "other", client.Revision
+	if _, err := client.ApplyDelta(delta); !errors.Is(err, ErrRevisionGap) {
+		t.Errorf("Expected an error applying the delta of another table, got %v", err)
+	}
+}
+
+// cloneTable returns a copy of the table as received by a client in a StateMessage.
+func cloneTable(t *testing.T, table *Table) *Table {
+	t.Helper()
+	data, err := json.Marshal(table)
+	if err != nil {
+		t.Fatalf("Failed to encode table: %v", err)
+	}
+	var clone Table
+	if err := json.Unmarshal(data, &clone); err != nil {
+		t.Fatalf("Failed to decode table: %v", err)
+	}
+	return &clone
+}
+
+// testApplyDelta applies the delta to the client's table, and checks it matches the server's one.
+func testApplyDelta(t *testing.T, client *Table, delta *StateDeltaMessage, want *Table) *Table {
+	t.Helper()
+	updated, err := client.ApplyDelta(delta)
+	if err != nil {
+		t.Fatalf("Failed to apply delta: %v", err)
+	}
+	got, _ := json.Marshal(updated)
+	wantData, _ := json.Marshal(want)
+	if string(got) != string(wantData) {
+		t.Fatalf("Expected the table after the delta to be\n%s\ngot\n%s", wantData, got)
+	}
+	return updated
+}
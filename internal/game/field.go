@@ -0,0 +1,171 @@
+package game
+
+import "fmt"
+
+// field implements arithmetic over the finite field GF(p^k).
+//
+// Elements are represented as integers in [0, p^k): the base-p digits of an element are
+// the coefficients of a polynomial over GF(p) with degree < k. Addition is coefficient-wise
+// modulo p, and multiplication is polynomial multiplication modulo an irreducible polynomial
+// of degree k.
+//
+// For k == 1 this is the usual modular arithmetic on integers modulo p.
+type field struct {
+	order  int // Number of elements, p^k.
+	prime  int // Characteristic p.
+	degree int // Extension degree k.
+
+	// Precomputed tables: add[a][b] = a+b and mul[a][b] = a*b.
+	add, mul [][]int
+}
+
+// primePower returns p and k such that n == p^k, with p prime and k >= 1.
+// It returns ok=false if n is not a prime power.
+func primePower(n int) (p, k int, ok bool) {
+	if n < 2 {
+		return 0, 0, false
+	}
+	// The smallest divisor > 1 is necessarily prime.
+	p = n
+	for d := 2; d*d <= n; d++ {
+		if n%d == 0 {
+			p = d
+			break
+		}
+	}
+	for n%p == 0 {
+		n /= p
+		k++
+	}
+	if n != 1 {
+		return 0, 0, false
+	}
+	return p, k, true
+}
+
+// newField creates the finite field with the given order, which must be a prime power.
+func newField(order int) (*field, error) {
+	p, k, ok := primePower(order)
+	if !ok {
+		return nil, fmt.Errorf("there is no finite field of order %d, it must be a prime power", order)
+	}
+	f := &field{order: order, prime: p, degree: k}
+
+	f.add = make([][]int, order)
+	for a := range order {
+		f.add[a] = make([]int, order)
+		for b := range order {
+			f.add[a][b] = f.polyAdd(a, b)
+		}
+	}
+
+	if k == 1 {
+		f.mul = f.mulTable(nil)
+		return f, nil
+	}
+
+	// Search for a monic irreducible polynomial of degree k: it is encoded as the element
+	// with k+1 base-p digits, whose leading digit is 1. The quotient ring is a field if and
+	// only if it has no zero divisors.
+	for lower := range order {
+		modulus := make([]int, k+1)
+		f.digits(lower, modulus[:k])
+		modulus[k] = 1
+		mul := f.mulTable(modulus)
+		if hasNoZeroDivisors(mul) {
+			f.mul = mul
+			return f, nil
+		}
+	}
+	// Unreachable: there is always an irreducible polynomial of any degree over GF(p).
+	return nil, fmt.Errorf("failed to find an irreducible polynomial of degree %d over GF(%d)", k, p)
+}
+
+// digits decomposes the element a into its k base-p digits (polynomial coefficients),
+// least significant first.
+func (f *field) digits(a int, coeffs []int) {
+	for i := range coeffs {
+		coeffs[i] = a % f.prime
+		a /= f.prime
+	}
+}
+
+// fromDigits is the inverse of digits.
+func (f *field) fromDigits(coeffs []int) int {
+	a := 0
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		a = a*f.prime + coeffs[i]
+	}
+	return a
+}
+
+// polyAdd adds two elements coefficient-wise, modulo p.
+func (f *field) polyAdd(a, b int) int {
+	result, scale := 0, 1
+	for range f.degree {
+		result += ((a%f.prime + b%f.prime) % f.prime) * scale
+		a /= f.prime
+		b /= f.prime
+		scale *= f.prime
+	}
+	return result
+}
+
+// mulTable builds the multiplication table modulo the given monic polynomial
+// of degree k (coefficients least significant first).
+// If modulus is nil, k must be 1 and multiplication is simply modulo p.
+func (f *field) mulTable(modulus []int) [][]int {
+	table := make([][]int, f.order)
+	if modulus == nil {
+		for a := range f.order {
+			table[a] = make([]int, f.order)
+			for b := range f.order {
+				table[a][b] = (a * b) % f.prime
+			}
+		}
+		return table
+	}
+
+	k := f.degree
+	aCoeffs := make([]int, k)
+	bCoeffs := make([]int, k)
+	product := make([]int, 2*k-1)
+	for a := range f.order {
+		table[a] = make([]int, f.order)
+		f.digits(a, aCoeffs)
+		for b := range f.order {
+			f.digits(b, bCoeffs)
+			clear(product)
+			for i, ai := range aCoeffs {
+				for j, bj := range bCoeffs {
+					product[i+j] = (product[i+j] + ai*bj) % f.prime
+				}
+			}
+			// Reduce modulo the monic modulus, from the highest degree down.
+			for d := len(product) - 1; d >= k; d-- {
+				c := product[d]
+				if c == 0 {
+					continue
+				}
+				for i := range k + 1 {
+					idx := d - k + i
+					product[idx] = ((product[idx]-c*modulus[i])%f.prime + f.prime) % f.prime
+				}
+			}
+			table[a][b] = f.fromDigits(product[:k])
+		}
+	}
+	return table
+}
+
+// hasNoZeroDivisors returns whether the product of any two non-zero elements is non-zero.
+func hasNoZeroDivisors(mul [][]int) bool {
+	for a := 1; a < len(mul); a++ {
+		for b := 1; b < len(mul); b++ {
+			if mul[a][b] == 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -9,6 +9,8 @@ package game
 // This is useful during development.
 var Version = "v0.1.6"
 
-// BonusDiscards is the number of cards to discard when a player matches
-// a symbol in the card that also matches their own symbol.
-var BonusDiscards = 3
+// MaxChatLength is the maximum number of characters of a chat message, longer messages are truncated.
+const MaxChatLength = 200
+
+// ChatHistorySize is the number of chat messages kept by each table, and sent to players joining it.
+const ChatHistorySize = 50
@@ -0,0 +1,85 @@
+package game
+
+import (
+	"fmt"
+	"slices"
+)
+
+// HotPotatoDeals is the maximum number of deals played in a game of Hot Potato.
+const HotPotatoDeals = 5
+
+// hotPotatoMode implements Hot Potato: the game is played in several deals, where each player gets a
+// single card. Players race to match their top card with the top card of another player, and give them
+// all their cards. The last player holding cards loses the deal, and adds them to their Score.
+// After the last deal, the player with the lowest Score wins.
+type hotPotatoMode struct{}
+
+func (hotPotatoMode) Info() GameModeInfo {
+	return GameModeInfo{
+		ID:             ModeHotPotato,
+		Name:           "Hot Potato",
+		Description:    fmt.Sprintf("Everyone gets a single card. Match your card with another player's card to give them all your cards: the last one holding cards keeps them. After %d deals, the one with the fewest cards wins.", HotPotatoDeals),
+		MinPlayers:     2,
+		PublicTopCards: true,
+	}
+}
+
+func (m hotPotatoMode) Deal(table *Table, deck Deck) {
+	table.Pile = deck
+	m.dealNext(table)
+}
+
+// dealNext deals one card to each player from the pile, if there are enough cards and deals left.
+func (hotPotatoMode) dealNext(table *Table) {
+	if table.Deal >= HotPotatoDeals || len(table.Pile) < len(table.Players) {
+		return
+	}
+	table.Deal++
+	for _, p := range table.Players {
+		p.Hand = table.Pile[:1]
+		table.Pile = table.Pile[1:]
+	}
+}
+
+func (hotPotatoMode) ValidateClick(table *Table, player *Player, symbol int) RejectReason {
+	if len(player.Hand) == 0 {
+		return RejectNoCards
+	}
+	if !slices.Contains(player.Hand[0], symbol) || findPlayer(table, player, symbol) == nil {
+		return RejectWrongSymbol
+	}
+	return ""
+}
+
+func (m hotPotatoMode) ApplyWin(table *Table, player *Player, symbol int) []string {
+	// The cards given go on top of the receiver's pile.
+	receiver := findPlayer(table, player, symbol)
+	receiver.Hand = append(slices.Clone(player.Hand), receiver.Hand...)
+	player.Hand = nil
+
+	var holders []*Player
+	for _, p := range table.Players {
+		if len(p.Hand) > 0 {
+			holders = append(holders, p)
+		}
+	}
+	if len(holders) == 1 {
+		// End of the deal: the last one holding cards keeps them.
+		loser := holders[0]
+		loser.Score += len(loser.Hand)
+		loser.Hand = nil
+		m.dealNext(table)
+	}
+	return []string{player.ID}
+}
+
+// GameOver once no more cards could be dealt.
+func (hotPotatoMode) GameOver(table *Table) bool {
+	for _, p := range table.Players {
+		if len(p.Hand) > 0 {
+			return false
+		}
+	}
+	setBestScoreWinner(table, false)
+	return true
+}
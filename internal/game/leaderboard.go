@@ -0,0 +1,74 @@
+package game
+
+import (
+	"fmt"
+	"time"
+)
+
+// LeaderboardSize is the number of entries shown in each leaderboard.
+const LeaderboardSize = 20
+
+// LeaderboardPeriod selects the solo games considered in a leaderboard, by when they were played.
+type LeaderboardPeriod string
+
+const (
+	PeriodDaily   LeaderboardPeriod = "daily"
+	PeriodWeekly  LeaderboardPeriod = "weekly"
+	PeriodAllTime LeaderboardPeriod = "all_time"
+)
+
+// LeaderboardPeriods lists the periods of the leaderboards, in the order they are offered.
+var LeaderboardPeriods = []LeaderboardPeriod{PeriodDaily, PeriodWeekly, PeriodAllTime}
+
+// Since returns the start of the period that includes now, in UTC: days start at midnight and
+// weeks on Monday. It returns the zero time for PeriodAllTime.
+func (p LeaderboardPeriod) Since(now time.Time) time.Time {
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch p {
+	case PeriodDaily:
+		return today
+	case PeriodWeekly:
+		daysSinceMonday := (int(today.Weekday()) + 6) % 7
+		return today.AddDate(0, 0, -daysSinceMonday)
+	default:
+		return time.Time{}
+	}
+}
+
+// LeaderboardKey identifies a leaderboard: only solo games with the same rules are ranked together.
+type LeaderboardKey struct {
+	Mode      GameModeID `json:"mode"`
+	DeckOrder int        `json:"deck_order"`
+	HandSize  int        `json:"hand_size"` // Number of cards dealt to the player
+}
+
+func (k LeaderboardKey) String() string {
+	return fmt.Sprintf("%s/order=%d/hand=%d", k.Mode, k.DeckOrder, k.HandSize)
+}
+
+// LeaderboardEntry is a solo game in a leaderboard.
+type LeaderboardEntry struct {
+	LeaderboardKey
+	PlayerID string        `json:"player_id"`
+	Name     string        `json:"name"`
+	Symbol   int           `json:"symbol"`
+	Duration time.Duration `json:"duration"` // Measured by the server, from the start of the game to the last match
+	EndTime  time.Time     `json:"end_time"`
+	Seed     int64         `json:"seed"` // Seed of the game, see Table.Seed
+}
+
+// LeaderboardKeyFor returns the leaderboard in which a solo game with the settings and
+// handSize cards dealt is ranked. It returns false if the game is not ranked: only The Tower
+// and The Well with the default penalty and bonus rules are, so all times are comparable.
+func LeaderboardKeyFor(settings TableSettings, handSize int) (LeaderboardKey, bool) {
+	defaults := DefaultTableSettings()
+	if settings.Mode != ModeTower && settings.Mode != ModeWell {
+		return LeaderboardKey{}, false
+	}
+	if settings.PenaltyDuration != defaults.PenaltyDuration || settings.BonusDiscards != defaults.BonusDiscards ||
+		settings.PlayerSymbolBonus != defaults.PlayerSymbolBonus || handSize <= 0 {
+		return LeaderboardKey{}, false
+	}
+	return LeaderboardKey{Mode: settings.Mode, DeckOrder: settings.DeckOrder, HandSize: handSize}, true
+}
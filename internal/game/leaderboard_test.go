@@ -0,0 +1,45 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLeaderboardPeriodSince(t *testing.T) {
+	now := time.Date(2024, 5, 16, 15, 30, 0, 0, time.UTC) // A Thursday.
+	tests := map[LeaderboardPeriod]time.Time{
+		PeriodDaily:   time.Date(2024, 5, 16, 0, 0, 0, 0, time.UTC),
+		PeriodWeekly:  time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC),
+		PeriodAllTime: {},
+	}
+	for period, want := range tests {
+		if got := period.Since(now); !got.Equal(want) {
+			t.Errorf("%s.Since(%v) = %v, want %v", period, now, got, want)
+		}
+	}
+	sunday := time.Date(2024, 5, 19, 23, 0, 0, 0, time.UTC)
+	if got, want := PeriodWeekly.Since(sunday), time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Expected weeks to start on Monday, got %v for %v", got, sunday)
+	}
+}
+
+func TestLeaderboardKeyFor(t *testing.T) {
+	settings := DefaultTableSettings()
+	key, ok := LeaderboardKeyFor(settings, 10)
+	if !ok || key != (LeaderboardKey{Mode: ModeTower, DeckOrder: 7, HandSize: 10}) {
+		t.Errorf("Expected default settings to be ranked, got %v, %t", key, ok)
+	}
+	unranked := map[string]func(s *TableSettings){
+		"triplet":    func(s *TableSettings) { s.Mode = ModeTriplet },
+		"no penalty": func(s *TableSettings) { s.PenaltyDuration = 0 },
+		"big bonus":  func(s *TableSettings) { s.BonusDiscards = MaxBonusDiscards },
+		"no bonus":   func(s *TableSettings) { s.PlayerSymbolBonus = false },
+	}
+	for name, change := range unranked {
+		settings := DefaultTableSettings()
+		change(&settings)
+		if _, ok := LeaderboardKeyFor(settings, 10); ok {
+			t.Errorf("Expected %s to be unranked", name)
+		}
+	}
+}
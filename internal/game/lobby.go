@@ -0,0 +1,36 @@
+package game
+
+import "strings"
+
+// SoloTablePrefix is the prefix of the IDs of the tables created with "Create Solo Game".
+// Solo tables are never listed in the open tables.
+const SoloTablePrefix = "Solo-"
+
+// IsSoloTable returns whether the table ID is of a solo table, see SoloTablePrefix.
+func IsSoloTable(tableID string) bool {
+	return strings.HasPrefix(tableID, SoloTablePrefix)
+}
+
+// TableSummary describes a public table in the list of open tables.
+type TableSummary struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Mode       GameModeID `json:"mode"`
+	Players    int        `json:"players"`
+	MaxPlayers int        `json:"max_players"`
+	Spectators int        `json:"spectators"`
+	Started    bool       `json:"started"` // Players joining a started table can only watch
+}
+
+// Summary returns the description of the table for the list of open tables.
+func (t *Table) Summary() TableSummary {
+	return TableSummary{
+		ID:         t.ID,
+		Name:       t.Name,
+		Mode:       t.Mode().Info().ID,
+		Players:    len(t.Players),
+		MaxPlayers: t.Settings.MaxPlayers,
+		Spectators: len(t.Spectators),
+		Started:    t.Started,
+	}
+}
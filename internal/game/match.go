@@ -0,0 +1,120 @@
+package game
+
+import (
+	"cmp"
+	"slices"
+)
+
+// MatchPoints are the points won in each game of a match, by finish order: the players finishing
+// after the last one listed get none.
+var MatchPoints = []int{10, 6, 4, 3, 2, 1}
+
+// FinishPoints returns the points won by the player finishing at position (starting at 0) of a game of a match.
+func FinishPoints(position int) int {
+	if position < 0 || position >= len(MatchPoints) {
+		return 0
+	}
+	return MatchPoints[position]
+}
+
+// Match is a series of games played at the same table, with points accumulated by finish order.
+// It's over after Games games, or as soon as a player can't be caught anymore.
+type Match struct {
+	Games  int            `json:"games"`  // Number of games of the match, see TableSettings.MatchGames
+	Played int            `json:"played"` // Number of games finished so far
+	Points map[string]int `json:"points"` // Accumulated points, by player ID
+
+	// Results holds the finish order (player IDs) of each game played.
+	Results [][]string `json:"results"`
+
+	WinnerID string `json:"winner_id,omitempty"` // Set once the match is over
+}
+
+// NewMatch creates a match of the given number of games.
+func NewMatch(games int) *Match {
+	return &Match{Games: games, Points: make(map[string]int)}
+}
+
+// Over returns whether the match is over.
+func (m *Match) Over() bool {
+	return m.WinnerID != ""
+}
+
+// Record adds the points of a finished game, given the finish order of its players (see Table.FinishOrder),
+// and decides the winner of the match if it is over.
+func (m *Match) Record(order []*Player) {
+	ids := make([]string, len(order))
+	for i, p := range order {
+		ids[i] = p.ID
+		m.Points[p.ID] += FinishPoints(i)
+	}
+	m.Results = append(m.Results, ids)
+	m.Played++
+
+	standings := m.Standings()
+	if len(standings) == 0 {
+		return
+	}
+	if len(standings) > 1 {
+		// The most a player can gain on another in each remaining game.
+		maxSwing := FinishPoints(0) - FinishPoints(len(order)-1)
+		lead := m.Points[standings[0]] - m.Points[standings[1]]
+		if m.Played < m.Games && lead <= maxSwing*(m.Games-m.Played) {
+			return
+		}
+	}
+	m.WinnerID = standings[0]
+}
+
+// Standings returns the IDs of the players of the match, by accumulated points. Ties are broken by the
+// finish order of the last game.
+func (m *Match) Standings() []string {
+	ids := make([]string, 0, len(m.Points))
+	for id := range m.Points {
+		ids = append(ids, id)
+	}
+	lastPosition := func(id string) int {
+		if len(m.Results) == 0 {
+			return 0
+		}
+		last := m.Results[len(m.Results)-1]
+		if i := slices.Index(last, id); i >= 0 {
+			return i
+		}
+		return len(last)
+	}
+	slices.SortFunc(ids, func(a, b string) int {
+		return cmp.Or(
+			cmp.Compare(m.Points[b], m.Points[a]),
+			cmp.Compare(lastPosition(a), lastPosition(b)),
+			cmp.Compare(a, b))
+	})
+	return ids
+}
+
+// FinishOrder returns the players of the finished game of the table, from first to last: the winner (or the
+// players of the winning team) first, then, in race modes, the players that finished by their time, and then
+// the others by score.
+func (t *Table) FinishOrder() []*Player {
+	info := t.Mode().Info()
+	players := slices.Clone(t.Players)
+	slices.SortStableFunc(players, func(a, b *Player) int {
+		if aWon, bWon := t.Won(a), t.Won(b); aWon != bWon {
+			if aWon {
+				return -1
+			}
+			return 1
+		}
+		if info.Race {
+			// Players finish when they are out of cards, or when the game is over: TimeTaken tells them apart.
+			if c := cmp.Compare(a.TimeTaken, b.TimeTaken); c != 0 {
+				return c
+			}
+		}
+		if info.HigherScoreWins {
+			return cmp.Compare(b.Score, a.Score)
+		}
+		return cmp.Compare(a.Score, b.Score)
+	})
+	return players
+}
@@ -0,0 +1,50 @@
+package game
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	alice, bob, carol := &Player{ID: "alice"}, &Player{ID: "bob"}, &Player{ID: "carol"}
+
+	// Best of 3 between two players: winning the first two games clinches the match.
+	m := NewMatch(3)
+	m.Record([]*Player{alice, bob})
+	if m.Over() || m.Points["alice"] != FinishPoints(0) || m.Points["bob"] != FinishPoints(1) {
+		t.Fatalf("After the first game, expected the match to go on with points %v, got %+v", MatchPoints[:2], m)
+	}
+	m.Record([]*Player{alice, bob})
+	if !m.Over() || m.WinnerID != "alice" || m.Played != 2 {
+		t.Errorf("Expected alice to clinch the match after 2 games, got %+v", m)
+	}
+
+	// Ties are broken by the finish order of the last game.
+	m = NewMatch(2)
+	m.Record([]*Player{alice, bob, carol})
+	m.Record([]*Player{bob, alice, carol})
+	if got := fmt.Sprint(m.Standings()); got != "[bob alice carol]" {
+		t.Errorf("Expected standings [bob alice carol], got %s", got)
+	}
+	if m.WinnerID != "bob" {
+		t.Errorf("Expected bob to win the match after the last game, got %q", m.WinnerID)
+	}
+}
+
+func TestFinishOrder(t *testing.T) {
+	table := &Table{Settings: DefaultTableSettings()}
+	table.Players = []*Player{
+		{ID: "last", TimeTaken: 90, Score: 3},
+		{ID: "second", TimeTaken: 60},
+		{ID: "third", TimeTaken: 90, Score: 1},
+		{ID: "first", TimeTaken: 30},
+	}
+	table.WinnerID = "first"
+	var ids []string
+	for _, p := range table.FinishOrder() {
+		ids = append(ids, p.ID)
+	}
+	if got := fmt.Sprint(ids); got != "[first second third last]" {
+		t.Errorf("Expected players ordered by time, and then cards left, got %s", got)
+	}
+}
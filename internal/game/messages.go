@@ -2,48 +2,101 @@ package game
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 )
 
 // Message type for WebSocket communication between client and server.
 type MessageType string
 
 const (
-	MsgTypeJoin   MessageType = "join"   // Client wants to join a table
-	MsgTypeState  MessageType = "state"  // Server sends full table state
-	MsgTypeStart  MessageType = "start"  // Client wants to start the game
-	MsgTypeCancel MessageType = "cancel" // Client (creator) wants to cancel/destroy the table
-	MsgTypePing   MessageType = "ping"   // Server pings client to measure RTT
-	MsgTypePong   MessageType = "pong"   // Client responds to ping
-	MsgTypeUpdate MessageType = "update" // Server sends game update (top card, target card)
-	MsgTypeClick  MessageType = "click"  // Client clicks a symbol
-	MsgTypeError  MessageType = "error"  // Server sends an error message
-	MsgTypeChat   MessageType = "chat"   // (Optional) simple chat
+	MsgTypeJoin        MessageType = "join"         // Client wants to join a table
+	MsgTypeState       MessageType = "state"        // Server sends full table state
+	MsgTypeStart       MessageType = "start"        // Client wants to start the game
+	MsgTypeCancel      MessageType = "cancel"       // Client (creator) wants to cancel/destroy the table
+	MsgTypePing        MessageType = "ping"         // Server pings client to measure RTT
+	MsgTypePong        MessageType = "pong"         // Client responds to ping
+	MsgTypeUpdate      MessageType = "update"       // Server sends game update (top card, target card)
+	MsgTypeClick       MessageType = "click"        // Client clicks a symbol
+	MsgTypeReject      MessageType = "reject"       // Server rejects a click
+	MsgTypeError       MessageType = "error"        // Server sends an error message
+	MsgTypeChat        MessageType = "chat"         // Chat message, from a client to the server, and rebroadcast to the table
+	MsgTypeSettings    MessageType = "settings"     // Client (creator) changes the table settings
+	MsgTypeAddBot      MessageType = "add_bot"      // Client (creator) adds a bot player to the table
+	MsgTypeRemoveBot   MessageType = "remove_bot"   // Client (creator) removes a bot player from the table
+	MsgTypeVisibility  MessageType = "visibility"   // Client (creator) makes the table public or private
+	MsgTypeQueue       MessageType = "queue"        // Client wants to join the quick match queue, instead of a table
+	MsgTypeQueueStatus MessageType = "queue_status" // Server sends the position in the queue, or the table matched
+	MsgTypeTeam        MessageType = "team"         // Client changes the team of a player, in team modes
+	MsgTypeStateDelta  MessageType = "state_delta"  // Server sends the changes of the table state since the last one
+	MsgTypeResync      MessageType = "resync"       // Client missed a state delta, and asks for the full state
+	MsgTypeHello       MessageType = "hello"        // Client starts the handshake, before joining a table or the queue
+	MsgTypeWelcome     MessageType = "welcome"      // Server answers the handshake with the negotiated protocol
+	MsgTypeRestart     MessageType = "restart"      // Server is restarting: clients reconnect to resume their game
+	MsgTypeNotice      MessageType = "notice"       // Server relays a notice of the operators to every client (e.g.: maintenance)
 )
 
+// OptionalMessageTypes can be ignored by receivers that don't know them (e.g.: older clients), without breaking
+// the game. See WsMessage.Optional.
+var OptionalMessageTypes = map[MessageType]bool{
+	MsgTypeChat:    true,
+	MsgTypePing:    true,
+	MsgTypeReject:  true,
+	MsgTypeTeam:    true,
+	MsgTypeRestart: true,
+	MsgTypeNotice:  true,
+}
+
+// ErrUnknownMessageType is returned by WsMessage.Parse for message types it doesn't know.
+var ErrUnknownMessageType = errors.New("unknown message type")
+
+// Known returns whether the message type is one of the MsgType constants, that WsMessage.Parse can parse.
+func (t MessageType) Known() bool {
+	_, err := (&WsMessage{Type: t}).Parse()
+	return !errors.Is(err, ErrUnknownMessageType)
+}
+
 // WsMessage represents a WebSocket message.
 type WsMessage struct {
 	Type    MessageType     `json:"type"`
 	Payload json.RawMessage `json:"payload,omitempty"`
+
+	// Optional is set for the OptionalMessageTypes: receivers that don't know the type can ignore the message,
+	// see Ignorable. Unknown messages that are not optional mean the peer talks an incompatible protocol.
+	Optional bool `json:"optional,omitempty"`
+
+	// decoded holds the payload of messages received in the binary encoding, see DecodeMessage.
+	decoded any
 }
 
 // NewWsMessage creates a new WsMessage with a marshaled payload.
 func NewWsMessage(msgType MessageType, payload interface{}) (WsMessage, error) {
 	if payload == nil {
-		return WsMessage{Type: msgType}, nil
+		return WsMessage{Type: msgType, Optional: OptionalMessageTypes[msgType]}, nil
 	}
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
 		return WsMessage{}, fmt.Errorf("failed to marshal payload: %w", err)
 	}
 	return WsMessage{
-		Type:    msgType,
-		Payload: payloadBytes,
+		Type:     msgType,
+		Payload:  payloadBytes,
+		Optional: OptionalMessageTypes[msgType],
 	}, nil
 }
 
+// Ignorable returns whether the error returned by Parse is because the message type is unknown, but the message
+// is optional, so it can be safely ignored.
+func (m *WsMessage) Ignorable(err error) bool {
+	return m.Optional && errors.Is(err, ErrUnknownMessageType)
+}
+
 // Parse unmarshals the message payload into one of the message types (JoinMessage, StateMessage, etc.)
 func (m *WsMessage) Parse() (any, error) {
+	if m.decoded != nil {
+		return m.decoded, nil
+	}
 	var target any
 	switch m.Type {
 	case MsgTypeJoin:
@@ -62,10 +115,40 @@ func (m *WsMessage) Parse() (any, error) {
 		target = &UpdateMessage{}
 	case MsgTypeClick:
 		target = &ClickMessage{}
+	case MsgTypeReject:
+		target = &RejectMessage{}
 	case MsgTypeError:
 		target = &ErrorMessage{}
+	case MsgTypeChat:
+		target = &ChatMessage{}
+	case MsgTypeSettings:
+		target = &SettingsMessage{}
+	case MsgTypeAddBot:
+		target = &AddBotMessage{}
+	case MsgTypeRemoveBot:
+		target = &RemoveBotMessage{}
+	case MsgTypeVisibility:
+		target = &VisibilityMessage{}
+	case MsgTypeQueue:
+		target = &QueueMessage{}
+	case MsgTypeQueueStatus:
+		target = &QueueStatusMessage{}
+	case MsgTypeTeam:
+		target = &TeamMessage{}
+	case MsgTypeStateDelta:
+		target = &StateDeltaMessage{}
+	case MsgTypeResync:
+		target = &ResyncMessage{}
+	case MsgTypeHello:
+		target = &HelloMessage{}
+	case MsgTypeWelcome:
+		target = &WelcomeMessage{}
+	case MsgTypeRestart:
+		target = &RestartMessage{}
+	case MsgTypeNotice:
+		target = &NoticeMessage{}
 	default:
-		return nil, fmt.Errorf("unknown message type: %s", m.Type)
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMessageType, m.Type)
 	}
 
 	if len(m.Payload) == 0 {
@@ -80,6 +163,33 @@ func (m *WsMessage) Parse() (any, error) {
 type JoinMessage struct {
 	TableID string `json:"table_id"`
 	Player  Player `json:"player"`
+
+	// Spectator joins the table only to watch the game, without taking a seat.
+	// Players joining a game that has already started are always spectators.
+	Spectator bool `json:"spectator,omitempty"`
+}
+
+// HelloMessage is the payload for MsgTypeHello, the first message of the clients that negotiate the protocol.
+// It's followed by the JoinMessage or QueueMessage. Clients that don't send it are of protocol version 0, and
+// use only EncodingJSON and no optional Features.
+type HelloMessage struct {
+	ProtocolVersion int        `json:"protocol_version"`
+	Version         string     `json:"version"`             // Version of the client, see game.Version
+	Encodings       []Encoding `json:"encodings,omitempty"` // Supported by the client, by preference
+	Features        []Feature  `json:"features,omitempty"`  // Supported by the client
+}
+
+// WelcomeMessage is the payload for MsgTypeWelcome, the server's answer to the HelloMessage, always sent as JSON.
+// Clients must not send binary messages before receiving it.
+//
+// If Error is set, the client is incompatible with the server: it should ask the user to reload the page, and
+// the server closes the connection.
+type WelcomeMessage struct {
+	ProtocolVersion int       `json:"protocol_version"`
+	Version         string    `json:"version"`            // Version of the server, see game.Version
+	Encoding        Encoding  `json:"encoding"`           // Chosen among HelloMessage.Encodings
+	Features        []Feature `json:"features,omitempty"` // Requested by the client and supported by the server
+	Error           string    `json:"error,omitempty"`
 }
 
 // StartMessage: empty.
@@ -88,11 +198,79 @@ type StartMessage struct{}
 // CancelMessage: empty.
 type CancelMessage struct{}
 
+// SettingsMessage is the payload for MsgTypeSettings.
+// It's only accepted from the creator of the table, before the game starts.
+type SettingsMessage struct {
+	Settings TableSettings `json:"settings"`
+}
+
+// AddBotMessage is the payload for MsgTypeAddBot.
+// It's only accepted from the creator of the table, before the game starts.
+type AddBotMessage struct {
+	Level BotLevel `json:"level"`
+}
+
+// RemoveBotMessage is the payload for MsgTypeRemoveBot.
+// It's only accepted from the creator of the table, before the game starts.
+type RemoveBotMessage struct {
+	PlayerID string `json:"player_id"`
+}
+
+// TeamMessage is the payload for MsgTypeTeam.
+// Players can change their own team, and the creator of the table the team of anyone, before the game starts.
+type TeamMessage struct {
+	PlayerID string `json:"player_id"` // Empty for the sender
+	Team     int    `json:"team"`      // 1 to MaxTeams, or 0 to leave the team unassigned
+}
+
+// VisibilityMessage is the payload for MsgTypeVisibility.
+// It's only accepted from the creator of the table. Solo tables can't be made public.
+type VisibilityMessage struct {
+	Public bool `json:"public"`
+}
+
+// QueueMessage is the payload for MsgTypeQueue.
+// It's sent as the first message of a connection, instead of a JoinMessage, to wait in the quick match queue.
+// Closing the connection leaves the queue.
+type QueueMessage struct {
+	Player Player `json:"player"`
+}
+
+// QueueStatusMessage is the payload for MsgTypeQueueStatus, sent periodically to the players in the quick match queue.
+type QueueStatusMessage struct {
+	Position   int           `json:"position"`    // Position in the queue, starting at 1
+	Waiting    int           `json:"waiting"`     // Number of players in the queue
+	TargetSize int           `json:"target_size"` // Number of players of a full match
+	Waited     time.Duration `json:"waited"`      // How long the player has been waiting
+	Timeout    time.Duration `json:"timeout"`     // After this long, smaller matches are made
+
+	// TableID is set once the player is matched: they should join the table, where the game starts
+	// automatically. The server closes the queue connection after sending it.
+	TableID string `json:"table_id,omitempty"`
+}
+
 // StateMessage is the payload for MsgTypeState
 type StateMessage struct {
 	Table Table `json:"table"`
 }
 
+// StateDeltaMessage is the payload for MsgTypeStateDelta: the table fields and player fields that changed
+// from revision BaseRevision to Revision, by their JSON names. See Table.ApplyDelta.
+type StateDeltaMessage struct {
+	TableID      string `json:"table_id"`
+	BaseRevision int64  `json:"base_revision"`
+	Revision     int64  `json:"revision"`
+
+	Fields  map[string]json.RawMessage            `json:"fields,omitempty"`  // Changed table fields, except the players
+	Players map[string]map[string]json.RawMessage `json:"players,omitempty"` // Changed fields by player ID, all of them for new players
+
+	// PlayerIDs lists the players in order, if they changed (someone joined or left), or it's nil otherwise.
+	PlayerIDs []string `json:"player_ids"`
+}
+
+// ResyncMessage: empty. The server answers with a full StateMessage.
+type ResyncMessage struct{}
+
 // UpdateMessage is the payload for MsgTypeUpdate
 type UpdateMessage struct {
 	TargetCard []int `json:"target_card"` // Current card on the table
@@ -101,13 +279,48 @@ type UpdateMessage struct {
 
 	// ScoringIDs holds the IDs of the players that scored:
 	// The first one will always be the one that scored because they clicked on a matching symbols.
-	// The others are players whose "player symbol" matches the one clicked, they get the game.BonusDiscards.
+	// The others are players whose "player symbol" matches the one clicked, they get the TableSettings.BonusDiscards.
 	ScoringIDs []string `json:"scoring_ids"`
+
+	// TopCards holds the top card of every player with cards, by player ID, for the game modes
+	// where they are public (see GameModeInfo.PublicTopCards).
+	TopCards map[string][]int `json:"top_cards,omitempty"`
+
+	// Grid holds the cards face up on the table, for the game modes that use it (e.g.: Triplet).
+	Grid [][]int `json:"grid,omitempty"`
+
+	// PileSize is the number of cards left in the draw pile, for the game modes that use it.
+	PileSize int `json:"pile_size,omitempty"`
 }
 
-// ClickMessage is the payload for MsgTypeClick
+// ClickMessage is the payload for MsgTypeClick.
+//
+// What a symbol matches depends on the game mode, see GameMode.ValidateClick.
 type ClickMessage struct {
-	Symbol int `json:"symbol"` // The symbol ID that was clicked
+	Symbol int `json:"symbol"`          // The symbol ID that was clicked
+	Round  int `json:"round,omitempty"` // Round the client was seeing when it clicked, 0 if unknown
+}
+
+// RejectReason tells why a click was rejected by the server.
+type RejectReason string
+
+const (
+	RejectNotStarted  RejectReason = "not_started"  // The game has not started (or was restarted)
+	RejectNoCards     RejectReason = "no_cards"     // The player has already discarded all their cards
+	RejectInPenalty   RejectReason = "in_penalty"   // The player is serving a penalty for a previous wrong click
+	RejectStaleRound  RejectReason = "stale_round"  // The click was made on a round that is already over: no penalty
+	RejectWrongSymbol RejectReason = "wrong_symbol" // The symbol doesn't match: the player gets a penalty
+	RejectSpectator   RejectReason = "spectator"    // Spectators can't play
+	RejectGameOver    RejectReason = "game_over"    // The game is over
+)
+
+// RejectMessage is the payload for MsgTypeReject, sent only to the player whose click was rejected.
+type RejectMessage struct {
+	Symbol int          `json:"symbol"` // The symbol ID that was clicked
+	Reason RejectReason `json:"reason"`
+
+	// PenaltyRemaining is how long the player remains in penalty, or 0 if they are not in penalty.
+	PenaltyRemaining time.Duration `json:"penalty_remaining"`
 }
 
 // PingMessage is the payload for MsgTypePing
@@ -125,3 +338,31 @@ type PongMessage struct {
 type ErrorMessage struct {
 	Message string `json:"message"`
 }
+
+// RestartMessage is the payload for MsgTypeRestart, sent to every client before the server shuts down.
+// The tables are saved, and restored by the next instance: the connection is then closed with
+// websocket.StatusServiceRestart, and clients reconnect to resume their game.
+type RestartMessage struct {
+	Message string `json:"message"`
+}
+
+// NoticeMessage is the payload for MsgTypeNotice, a notice of the operators of the server (e.g.: an upcoming
+// maintenance), shown to the players until they dismiss it.
+type NoticeMessage struct {
+	Message string `json:"message"`
+}
+
+// ChatMessage is the payload for MsgTypeChat.
+//
+// Clients only fill Text, the server fills in the sender and timestamp before
+// rebroadcasting it to the table.
+type ChatMessage struct {
+	SenderID     string    `json:"sender_id,omitempty"`
+	SenderName   string    `json:"sender_name,omitempty"`
+	SenderSymbol int       `json:"sender_symbol,omitempty"`
+	Text         string    `json:"text"`
+	Timestamp    time.Time `json:"timestamp"`
+
+	// System is set for notices from the server (e.g.: "too many messages"), that have no sender.
+	System bool `json:"system,omitempty"`
+}
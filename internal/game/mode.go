@@ -0,0 +1,167 @@
+package game
+
+import (
+	"cmp"
+	"math/rand"
+	"slices"
+)
+
+// GameModeID identifies a game mode, see GameModes.
+type GameModeID string
+
+const (
+	ModeTower        GameModeID = "tower"
+	ModeWell         GameModeID = "well"
+	ModeHotPotato    GameModeID = "hot_potato"
+	ModePoisonedGift GameModeID = "poisoned_gift"
+	ModeTriplet      GameModeID = "triplet"
+	ModeTeamTower    GameModeID = "team_tower"
+)
+
+// GameModeInfo describes a game mode to the players.
+type GameModeInfo struct {
+	ID          GameModeID
+	Name        string
+	Description string
+
+	// MinPlayers needed to start a game.
+	MinPlayers int
+
+	// Race is set for modes where players race to discard their cards, and are Finished as soon as they do.
+	Race bool
+
+	// HigherScoreWins is set for modes where Player.Score counts the cards won, as opposed to
+	// the cards left or received.
+	HigherScoreWins bool
+
+	// PublicTopCards is set for modes where players play with the top cards of the other players,
+	// so they are sent to everyone.
+	PublicTopCards bool
+
+	// Teams is set for modes played by teams, see Player.Team: the game is won by a team, see Table.WinnerTeam.
+	Teams bool
+}
+
+// GameMode implements the rules of one of the variants of the game.
+//
+// The server calls the methods with its lock held, and takes care of everything that is common
+// to all modes: rejecting clicks before the game starts, during penalties or for stale rounds,
+// latency compensation, and advancing the Round.
+type GameMode interface {
+	Info() GameModeInfo
+
+	// Deal the shuffled deck at the start of the game: to the players' Hand, and to the table's
+	// TargetCard, Pile and Grid, as the mode requires.
+	// Players' Score, Finished and Hand, and the table's TargetCard, Pile, Grid and Deal are reset before.
+	Deal(table *Table, deck Deck)
+
+	// ValidateClick returns why the player's click on symbol is not a match, or "" if it is.
+	// RejectWrongSymbol is penalized by the server.
+	ValidateClick(table *Table, player *Player, symbol int) RejectReason
+
+	// ApplyWin applies the winning click (validated with ValidateClick) of the round,
+	// and returns the IDs of the players that scored: see UpdateMessage.ScoringIDs.
+	// It marks the players that are done as Finished, and may set the table's WinnerID.
+	ApplyWin(table *Table, player *Player, symbol int) (scoringIDs []string)
+
+	// GameOver returns whether the game is over, after a win is applied.
+	// If so, it sets the table's WinnerID, if not set yet.
+	GameOver(table *Table) bool
+}
+
+// GameModes lists the available game modes, in the order they are offered to the creator of a table.
+var GameModes = []GameMode{
+	towerMode{
+		info: GameModeInfo{
+			ID:          ModeTower,
+			Name:        "The Tower",
+			Description: "Match the top card of your pile with the card in the centre to discard it, matching your own symbol discards extra cards. The first to discard all their cards wins.",
+			MinPlayers:  1,
+			Race:        true,
+		},
+		bonus: true,
+	},
+	towerMode{
+		info: GameModeInfo{
+			ID:          ModeWell,
+			Name:        "The Well",
+			Description: "Match the top card of your pile with the card in the centre pile, and place it on top of it. The first to get rid of all their cards wins.",
+			MinPlayers:  1,
+			Race:        true,
+		},
+	},
+	towerMode{
+		info: GameModeInfo{
+			ID:          ModeTeamTower,
+			Name:        "Team Tower",
+			Description: "The Tower, played in teams: matching a teammate's symbol discards extra cards from their pile. The first team whose members all discard their cards wins.",
+			MinPlayers:  2,
+			Race:        true,
+			Teams:       true,
+		},
+		bonus: true,
+		teams: true,
+	},
+	hotPotatoMode{},
+	poisonedGiftMode{},
+	tripletMode{},
+}
+
+// LookupGameMode returns the game mode with the given ID, or nil if there is none.
+func LookupGameMode(id GameModeID) GameMode {
+	for _, mode := range GameModes {
+		if mode.Info().ID == id {
+			return mode
+		}
+	}
+	return nil
+}
+
+// Mode returns the game mode of the table, selected in its settings.
+// It defaults to The Tower, if none is selected.
+func (t *Table) Mode() GameMode {
+	if mode := LookupGameMode(t.Settings.Mode); mode != nil {
+		return mode
+	}
+	return GameModes[0]
+}
+
+// findPlayer returns the first player, other than exclude, with cards whose top card has the symbol.
+func findPlayer(table *Table, exclude *Player, symbol int) *Player {
+	for _, p := range table.Players {
+		if p != exclude && len(p.Hand) > 0 && slices.Contains(p.Hand[0], symbol) {
+			return p
+		}
+	}
+	return nil
+}
+
+// setBestScoreWinner sets the table's WinnerID to the player with the best score (the lowest, or the
+// highest if higherWins). Ties are broken randomly, using the table's generator if set.
+func setBestScoreWinner(table *Table, higherWins bool) {
+	if table.WinnerID != "" || len(table.Players) == 0 {
+		return
+	}
+	better := func(a, b *Player) int {
+		if higherWins {
+			return cmp.Compare(b.Score, a.Score)
+		}
+		return cmp.Compare(a.Score, b.Score)
+	}
+	best := slices.MinFunc(table.Players, better)
+	var tied []*Player
+	for _, p := range table.Players {
+		if better(p, best) == 0 {
+			tied = append(tied, p)
+		}
+	}
+	table.WinnerID = tied[table.intn(len(tied))].ID
+}
+
+// intn returns a random number in [0, n), using the table's generator if set, so games can be replayed.
+func (t *Table) intn(n int) int {
+	if t.Rand != nil {
+		return t.Rand.Intn(n)
+	}
+	return rand.Intn(n)
+}
@@ -0,0 +1,275 @@
+package game
+
+import (
+	"slices"
+	"testing"
+)
+
+// newModeTable creates a table with numPlayers players and deals a seeded deck with the given mode.
+func newModeTable(t *testing.T, modeID GameModeID, numPlayers int) (*Table, GameMode) {
+	t.Helper()
+	table := &Table{
+		ID:       "test-" + string(modeID),
+		Settings: DefaultTableSettings(),
+		Rand:     NewRand(42),
+	}
+	table.Settings.Mode = modeID
+	for i := range numPlayers {
+		table.Players = append(table.Players, &Player{ID: string(rune('a' + i)), Name: string(rune('A' + i)), Symbol: i})
+	}
+	mode := table.Mode()
+	if mode.Info().ID != modeID {
+		t.Fatalf("Expected mode %q, got %q", modeID, mode.Info().ID)
+	}
+	deck, err := GenerateDeck(table.Settings.DeckOrder, table.Rand)
+	if err != nil {
+		t.Fatalf("Failed to generate deck: %v", err)
+	}
+	deck.Shuffle(table.Rand)
+	mode.Deal(table, deck)
+	return table, mode
+}
+
+// playUntilOver plays the game, each round won by the first player (in a rotating order) that
+// finds a match, and returns the number of rounds played.
+// It fails if no player can find a match before the game is over.
+func playUntilOver(t *testing.T, table *Table, mode GameMode) int {
+	t.Helper()
+	numSymbols := table.Settings.DeckSize()
+	for round := 0; round < 1000; round++ {
+		var won bool
+	search:
+		for i := range table.Players {
+			player := table.Players[(round+i)%len(table.Players)]
+			for symbol := range numSymbols {
+				if mode.ValidateClick(table, player, symbol) == "" {
+					mode.ApplyWin(table, player, symbol)
+					won = true
+					break search
+				}
+			}
+		}
+		if !won {
+			t.Fatalf("No player can find a match after %d rounds", round)
+		}
+		if mode.GameOver(table) {
+			if table.WinnerID == "" {
+				t.Fatalf("Game over without a winner")
+			}
+			return round + 1
+		}
+	}
+	t.Fatalf("Game didn't finish")
+	return 0
+}
+
+// countCards returns the number of cards on the table and in the players' hands.
+func countCards(table *Table) int {
+	count := len(table.Pile) + len(table.Grid)
+	if len(table.TargetCard) > 0 {
+		count++
+	}
+	for _, p := range table.Players {
+		count += len(p.Hand)
+	}
+	return count
+}
+
+func TestGameModesRegistry(t *testing.T) {
+	seen := make(map[GameModeID]bool)
+	for _, mode := range GameModes {
+		info := mode.Info()
+		if seen[info.ID] {
+			t.Errorf("Duplicate game mode %q", info.ID)
+		}
+		seen[info.ID] = true
+		if LookupGameMode(info.ID) != mode {
+			t.Errorf("LookupGameMode(%q) returned a different mode", info.ID)
+		}
+	}
+	if LookupGameMode("bogus") != nil {
+		t.Errorf("Expected no mode for an unknown ID")
+	}
+	if (&Table{}).Mode().Info().ID != ModeTower {
+		t.Errorf("Expected The Tower to be the default mode")
+	}
+}
+
+func TestTowerModes(t *testing.T) {
+	for _, modeID := range []GameModeID{ModeTower, ModeWell} {
+		t.Run(string(modeID), func(t *testing.T) {
+			table, mode := newModeTable(t, modeID, 3)
+			for _, p := range table.Players {
+				if len(p.Hand) != 18 || p.Score != 18 {
+					t.Fatalf("Expected %s to be dealt 18 cards, got %d (score %d)", p.Name, len(p.Hand), p.Score)
+				}
+			}
+
+			// Match one's own symbol: The Tower gives bonus discards, The Well doesn't.
+			player := table.Players[0]
+			symbol := commonSymbol(table.TargetCard, player.Hand[0])
+			player.Symbol = symbol
+			mode.ApplyWin(table, player, symbol)
+			want := 17
+			if modeID == ModeTower {
+				want = 18 - table.Settings.BonusDiscards
+			}
+			if player.Score != want {
+				t.Errorf("Expected %d cards left after matching own symbol, got %d", want, player.Score)
+			}
+
+			playUntilOver(t, table, mode)
+			for _, p := range table.Players {
+				if !p.Finished || len(p.Hand) != 0 {
+					t.Errorf("Expected %s to have finished with no cards", p.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestTeamTowerMode(t *testing.T) {
+	table, mode := newModeTable(t, ModeTeamTower, 4)
+	for i, p := range table.Players {
+		p.Team = 1 + i%2
+	}
+	a, b, c := table.Players[0], table.Players[1], table.Players[2]
+
+	// Matching a teammate's symbol gives them the bonus, but not to opponents with the same symbol.
+	symbol := commonSymbol(table.TargetCard, a.Hand[0])
+	a.Symbol = (symbol + 1) % table.Settings.DeckSize()
+	b.Symbol, c.Symbol = symbol, symbol
+	bCards, cCards := len(b.Hand), len(c.Hand)
+	scoringIDs := mode.ApplyWin(table, a, symbol)
+	if len(c.Hand) != cCards-table.Settings.BonusDiscards {
+		t.Errorf("Expected teammate %s to discard %d bonus cards, got %d", c.Name, table.Settings.BonusDiscards, cCards-len(c.Hand))
+	}
+	if len(b.Hand) != bCards {
+		t.Errorf("Expected opponent %s to keep their cards, got %d discarded", b.Name, bCards-len(b.Hand))
+	}
+	if !slices.Equal(scoringIDs, []string{a.ID, c.ID}) {
+		t.Errorf("Expected scoring players [a c], got %v", scoringIDs)
+	}
+
+	playUntilOver(t, table, mode)
+	if table.WinnerTeam == 0 || !table.TeamFinished(table.WinnerTeam) {
+		t.Fatalf("Expected a winning team with all its cards discarded, got team %d", table.WinnerTeam)
+	}
+	for _, p := range table.Players {
+		if table.Won(p) != (p.Team == table.WinnerTeam) {
+			t.Errorf("Expected %s (team %d) won=%t", p.Name, p.Team, p.Team == table.WinnerTeam)
+		}
+	}
+	order := table.FinishOrder()
+	if !table.Won(order[0]) || !table.Won(order[1]) || table.Won(order[2]) {
+		t.Errorf("Expected the winning team first in the finish order, got %v", order)
+	}
+}
+
+func TestBalanceTeams(t *testing.T) {
+	table := &Table{}
+	for i, team := range []int{1, 1, 0, 0, 0, 3} {
+		table.Players = append(table.Players, &Player{ID: string(rune('a' + i)), Team: team})
+	}
+	table.BalanceTeams()
+	var teams []int
+	for _, p := range table.Players {
+		teams = append(teams, p.Team)
+	}
+	if want := []int{1, 1, 2, 2, 3, 3}; !slices.Equal(teams, want) {
+		t.Errorf("Expected teams %v, got %v", want, teams)
+	}
+	if err := table.CheckTeams(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	for _, p := range table.Players {
+		p.Team = 2
+	}
+	if err := table.CheckTeams(); err == nil {
+		t.Errorf("Expected an error with a single team")
+	}
+}
+
+func TestHotPotatoMode(t *testing.T) {
+	table, mode := newModeTable(t, ModeHotPotato, 3)
+	for _, p := range table.Players {
+		if len(p.Hand) != 1 {
+			t.Fatalf("Expected %s to be dealt a single card, got %d", p.Name, len(p.Hand))
+		}
+	}
+	if table.Deal != 1 {
+		t.Fatalf("Expected first deal, got %d", table.Deal)
+	}
+	playUntilOver(t, table, mode)
+	if table.Deal != HotPotatoDeals {
+		t.Errorf("Expected %d deals, got %d", HotPotatoDeals, table.Deal)
+	}
+	// Every deal, the loser keeps all the cards dealt.
+	total := 0
+	for _, p := range table.Players {
+		total += p.Score
+	}
+	if want := HotPotatoDeals * len(table.Players); total != want {
+		t.Errorf("Expected a total score of %d, got %d", want, total)
+	}
+}
+
+func TestPoisonedGiftMode(t *testing.T) {
+	table, mode := newModeTable(t, ModePoisonedGift, 4)
+	total := countCards(table)
+	if total != table.Settings.DeckSize() {
+		t.Fatalf("Expected all %d cards to be dealt, got %d", table.Settings.DeckSize(), total)
+	}
+	rounds := playUntilOver(t, table, mode)
+	if want := table.Settings.DeckSize() - len(table.Players); rounds != want {
+		t.Errorf("Expected %d rounds, one per card of the pile, got %d", want, rounds)
+	}
+	if countCards(table) != total {
+		t.Errorf("Expected all %d cards in the players' hands, got %d", total, countCards(table))
+	}
+	// The winner must have the fewest cards.
+	winner := table.Players[slices.IndexFunc(table.Players, func(p *Player) bool { return p.ID == table.WinnerID })]
+	for _, p := range table.Players {
+		if p.Score < winner.Score {
+			t.Errorf("Winner %s has %d cards, but %s has only %d", winner.Name, winner.Score, p.Name, p.Score)
+		}
+	}
+}
+
+func TestTripletMode(t *testing.T) {
+	table, mode := newModeTable(t, ModeTriplet, 2)
+	if len(table.Grid) < TripletGridSize {
+		t.Fatalf("Expected a grid of at least %d cards, got %d", TripletGridSize, len(table.Grid))
+	}
+	if !hasTriplet(table.Grid) {
+		t.Fatalf("Expected the grid to have a triplet")
+	}
+	total := countCards(table)
+
+	// A symbol shared by only two cards is not a triplet.
+	symbol := commonSymbol(table.Grid[0], table.Grid[1])
+	if len(tripletCards(table.Grid, symbol)) == 2 && mode.ValidateClick(table, table.Players[0], symbol) != RejectWrongSymbol {
+		t.Errorf("Expected a symbol shared by 2 cards to be rejected")
+	}
+
+	playUntilOver(t, table, mode)
+	if countCards(table) != total {
+		t.Errorf("Expected %d cards in total, got %d", total, countCards(table))
+	}
+	for _, p := range table.Players {
+		if p.Score%3 != 0 || p.Score != len(p.Hand) {
+			t.Errorf("Expected %s to have collected triplets, got %d cards (score %d)", p.Name, len(p.Hand), p.Score)
+		}
+	}
+}
+
+// commonSymbol returns the symbol shared by the two cards, or -1.
+func commonSymbol(a, b []int) int {
+	for _, s := range a {
+		if slices.Contains(b, s) {
+			return s
+		}
+	}
+	return -1
+}
@@ -29,3 +29,11 @@ var tableNames = []string{
 func RandomTableName() string {
 	return tableNames[rand.Intn(len(tableNames))]
 }
+
+// BotNames are the names given to bot players.
+var BotNames = []string{
+	"R2-D2", "C-3PO", "HAL 9000", "WALL-E", "Bender",
+	"Data", "Marvin", "Johnny 5", "Robby", "Baymax",
+	"K-2SO", "BB-8", "Optimus", "Sonny", "Rosie",
+	"Bishop", "Gort", "Dolores", "Astro Boy", "Chappie",
+}
@@ -0,0 +1,59 @@
+package game
+
+import "slices"
+
+// poisonedGiftMode implements Poisoned Gift: each player is dealt a single card, and the rest of the
+// deck is the draw pile, with its top card as the target card. Players race to match the target card
+// with the top card of another player, and give it to them. When the pile is exhausted, the player
+// with the fewest cards wins.
+type poisonedGiftMode struct{}
+
+func (poisonedGiftMode) Info() GameModeInfo {
+	return GameModeInfo{
+		ID:             ModePoisonedGift,
+		Name:           "Poisoned Gift",
+		Description:    "Everyone gets a single card. Match the card in the centre with another player's card to give it to them. When the centre pile is exhausted, the one with the fewest cards wins.",
+		MinPlayers:     2,
+		PublicTopCards: true,
+	}
+}
+
+func (poisonedGiftMode) Deal(table *Table, deck Deck) {
+	for i, p := range table.Players {
+		p.Hand = deck[i : i+1]
+		p.Score = 1
+	}
+	deck = deck[len(table.Players):]
+	table.TargetCard = deck[0]
+	table.Pile = deck[1:]
+}
+
+// ValidateClick accepts a symbol of the target card that matches the top card of another player.
+// Players always have cards in Poisoned Gift.
+func (poisonedGiftMode) ValidateClick(table *Table, player *Player, symbol int) RejectReason {
+	if !slices.Contains(table.TargetCard, symbol) || findPlayer(table, player, symbol) == nil {
+		return RejectWrongSymbol
+	}
+	return ""
+}
+
+func (poisonedGiftMode) ApplyWin(table *Table, player *Player, symbol int) []string {
+	receiver := findPlayer(table, player, symbol)
+	receiver.Hand = append([][]int{table.TargetCard}, receiver.Hand...)
+	receiver.Score = len(receiver.Hand)
+	table.TargetCard = nil
+	if len(table.Pile) > 0 {
+		table.TargetCard = table.Pile[0]
+		table.Pile = table.Pile[1:]
+	}
+	return []string{player.ID}
+}
+
+// GameOver once the draw pile is exhausted.
+func (poisonedGiftMode) GameOver(table *Table) bool {
+	if len(table.TargetCard) > 0 {
+		return false
+	}
+	setBestScoreWinner(table, false)
+	return true
+}
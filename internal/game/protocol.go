@@ -0,0 +1,52 @@
+package game
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ProtocolVersion of the websocket messages, sent in the HelloMessage/WelcomeMessage handshake.
+// Bump it when a change breaks clients or servers of the previous version: that is, when a message
+// changes meaning, or a new message can't be ignored by receivers that don't know it (see WsMessage.Optional).
+const ProtocolVersion = 1
+
+// MinProtocolVersion is the oldest version of the clients the server still talks to. Older clients are
+// asked to reload the page, to get the current version of the client.
+// Clients that connect without a HelloMessage are of version 0.
+const MinProtocolVersion = 0
+
+// Feature is an optional capability of the protocol, negotiated in the handshake.
+type Feature string
+
+const (
+	// FeatureDeltas is set by clients that apply StateDeltaMessage: they are sent the full state once, and then
+	// only what changes. Otherwise, every change is sent as a full StateMessage.
+	FeatureDeltas Feature = "deltas"
+)
+
+// Features supported by this version of the protocol.
+var Features = []Feature{FeatureDeltas}
+
+// NegotiateFeatures returns the features requested that are also supported.
+func NegotiateFeatures(requested, supported []Feature) []Feature {
+	var features []Feature
+	for _, f := range requested {
+		if slices.Contains(supported, f) && !slices.Contains(features, f) {
+			features = append(features, f)
+		}
+	}
+	return features
+}
+
+// CheckProtocolVersion returns an error if a client of the given protocol version can't talk to this server.
+func CheckProtocolVersion(clientVersion int) error {
+	if clientVersion < MinProtocolVersion {
+		return fmt.Errorf("this version of the game is no longer supported (protocol %d, the server requires at least %d), please reload the page",
+			clientVersion, MinProtocolVersion)
+	}
+	if clientVersion > ProtocolVersion {
+		return fmt.Errorf("the server is older than this version of the game (protocol %d, the server supports up to %d), please reload the page",
+			clientVersion, ProtocolVersion)
+	}
+	return nil
+}
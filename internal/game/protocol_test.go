@@ -0,0 +1,60 @@
+package game
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestProtocolNegotiation(t *testing.T) {
+	for _, version := range []int{MinProtocolVersion, ProtocolVersion} {
+		if err := CheckProtocolVersion(version); err != nil {
+			t.Errorf("Expected protocol version %d to be supported, got %v", version, err)
+		}
+	}
+	for _, version := range []int{MinProtocolVersion - 1, ProtocolVersion + 1} {
+		if err := CheckProtocolVersion(version); err == nil {
+			t.Errorf("Expected protocol version %d to be refused", version)
+		}
+	}
+
+	got := NegotiateFeatures([]Feature{"from_the_future", FeatureDeltas, FeatureDeltas}, Features)
+	if !slices.Equal(got, []Feature{FeatureDeltas}) {
+		t.Errorf("Expected only the deltas feature, got %v", got)
+	}
+	if got := NegotiateFeatures([]Feature{FeatureDeltas}, nil); len(got) != 0 {
+		t.Errorf("Expected no features, got %v", got)
+	}
+}
+
+func TestUnknownMessages(t *testing.T) {
+	chat, _ := NewWsMessage(MsgTypeChat, ChatMessage{Text: "hi"})
+	if !chat.Optional {
+		t.Errorf("Expected chat messages to be optional")
+	}
+	if start, _ := NewWsMessage(MsgTypeStart, nil); start.Optional {
+		t.Errorf("Expected start messages to be required")
+	}
+
+	// Messages of a newer version of the protocol.
+	for _, optional := range []bool{true, false} {
+		data, _ := json.Marshal(map[string]any{"type": "from_the_future", "payload": map[string]int{"x": 1}, "optional": optional})
+		var msg WsMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("Failed to decode message: %v", err)
+		}
+		_, err := msg.Parse()
+		if err == nil {
+			t.Fatalf("Expected an error parsing an unknown message type")
+		}
+		if msg.Ignorable(err) != optional {
+			t.Errorf("Expected Ignorable()=%t for optional=%t", optional, optional)
+		}
+		if msg.Type.Known() {
+			t.Errorf("Expected message type %q to be unknown", msg.Type)
+		}
+	}
+	if !MsgTypeNotice.Known() {
+		t.Errorf("Expected message type %q to be known", MsgTypeNotice)
+	}
+}
@@ -0,0 +1,141 @@
+package game
+
+import (
+	"slices"
+	"time"
+)
+
+// EventType is the type of a GameEvent.
+type EventType string
+
+const (
+	EventDeal     EventType = "deal"     // The cards were dealt, the game started
+	EventClick    EventType = "click"    // A valid click, waiting for the latency compensation delay
+	EventReject   EventType = "reject"   // An invalid click, rejected by the server
+	EventRoundWin EventType = "round"    // A click won the round
+	EventBonus    EventType = "bonus"    // A player discarded extra cards because their symbol was matched
+	EventFinish   EventType = "finish"   // A player finished: they discarded all their cards, or the game is over
+	EventGameOver EventType = "gameover" // The game is over
+)
+
+// GameEvent is an entry of the log of a game, see Replay.
+type GameEvent struct {
+	Type EventType `json:"type"`
+
+	// Time when the server received (or generated) the event, since the start of the game.
+	Time time.Duration `json:"time"`
+
+	PlayerID string `json:"player_id,omitempty"`
+	Symbol   int    `json:"symbol"`
+	Round    int    `json:"round"` // Round of the table when the event happened
+
+	// Clicks only.
+	ClickRound int           `json:"click_round,omitempty"` // Round the player was seeing when they clicked
+	Latency    time.Duration `json:"latency,omitempty"`     // Estimated one-way latency of the player
+	Delay      time.Duration `json:"delay,omitempty"`       // Latency compensation applied to a valid click
+	Reason     RejectReason  `json:"reason,omitempty"`      // Why an invalid click was rejected
+
+	// Discards is the number of extra cards discarded, for EventBonus.
+	Discards int `json:"discards,omitempty"`
+
+	// Snapshot of the table after EventDeal and EventRoundWin, so replays can show the cards
+	// without re-running the rules of the game mode.
+	Snapshot *ReplaySnapshot `json:"snapshot,omitempty"`
+}
+
+// ReplaySnapshot is the state of the cards on the table at some point of a game.
+type ReplaySnapshot struct {
+	TargetCard []int            `json:"target_card,omitempty"`
+	TopCards   map[string][]int `json:"top_cards,omitempty"` // Top card of each player with cards, by player ID
+	Grid       [][]int          `json:"grid,omitempty"`
+	Scores     map[string]int   `json:"scores"` // Score of each player, by player ID
+}
+
+// ReplayPlayer describes a player of a replayed game.
+type ReplayPlayer struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Symbol int      `json:"symbol"`
+	Bot    BotLevel `json:"bot,omitempty"`
+}
+
+// Replay is the log of every event of a game, from the deal to the end.
+type Replay struct {
+	ID        string         `json:"id"`
+	TableID   string         `json:"table_id"`
+	Settings  TableSettings  `json:"settings"`
+	Seed      int64          `json:"seed"` // See Table.Seed
+	StartTime time.Time      `json:"start_time"`
+	Players   []ReplayPlayer `json:"players"`
+	WinnerID  string         `json:"winner_id,omitempty"`
+	Events    []GameEvent    `json:"events"`
+}
+
+// NewReplayID returns a new random replay ID.
+func NewReplayID() string {
+	return newRandomID()
+}
+
+// NewReplay starts the log of the game that was just dealt on the table, with its EventDeal.
+func NewReplay(table *Table) *Replay {
+	r := &Replay{
+		ID:        NewReplayID(),
+		TableID:   table.ID,
+		Settings:  table.Settings,
+		Seed:      table.Seed,
+		StartTime: table.StartTime,
+	}
+	for _, p := range table.Players {
+		r.Players = append(r.Players, ReplayPlayer{ID: p.ID, Name: p.Name, Symbol: p.Symbol, Bot: p.Bot})
+	}
+	r.Log(table, GameEvent{Type: EventDeal, Snapshot: NewReplaySnapshot(table)}, table.StartTime)
+	return r
+}
+
+// Log appends the event, that happened at time now, to the replay. It fills in its Time and Round.
+func (r *Replay) Log(table *Table, event GameEvent, now time.Time) {
+	event.Time = now.Sub(r.StartTime)
+	event.Round = table.Round
+	r.Events = append(r.Events, event)
+}
+
+// NewReplaySnapshot returns the current state of the cards on the table.
+func NewReplaySnapshot(table *Table) *ReplaySnapshot {
+	snapshot := &ReplaySnapshot{
+		TargetCard: slices.Clone(table.TargetCard),
+		Grid:       slices.Clone(table.Grid),
+		TopCards:   make(map[string][]int),
+		Scores:     make(map[string]int),
+	}
+	for _, p := range table.Players {
+		if len(p.Hand) > 0 {
+			snapshot.TopCards[p.ID] = p.Hand[0]
+		}
+		snapshot.Scores[p.ID] = p.Score
+	}
+	return snapshot
+}
+
+// Steps splits the events of the replay by the snapshot they follow: each step starts with
+// EventDeal or EventRoundWin (followed by its EventBonus and EventFinish), and includes the
+// clicks racing for the next round.
+func (r *Replay) Steps() [][]GameEvent {
+	var steps [][]GameEvent
+	for _, e := range r.Events {
+		if e.Snapshot != nil || len(steps) == 0 {
+			steps = append(steps, nil)
+		}
+		steps[len(steps)-1] = append(steps[len(steps)-1], e)
+	}
+	return steps
+}
+
+// Player returns the player of the replay with the given ID, or a player named after the ID if there is none.
+func (r *Replay) Player(id string) ReplayPlayer {
+	for _, p := range r.Players {
+		if p.ID == id {
+			return p
+		}
+	}
+	return ReplayPlayer{ID: id, Name: id}
+}
@@ -0,0 +1,147 @@
+package game
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// NumSymbolImages is the number of symbol images available (web/images/symbol_XX.png).
+// It limits the orders of the decks that can be played.
+const NumSymbolImages = 72
+
+// DeckOrders lists the deck orders that can be chosen for a table: prime powers whose
+// decks don't use more symbols than NumSymbolImages.
+var DeckOrders = []int{2, 3, 4, 5, 7}
+
+// SoloCardsPerPlayer is the number of cards dealt in a solo game, if TableSettings.CardsPerPlayer is 0.
+const SoloCardsPerPlayer = 10
+
+// Limits of the table settings.
+const (
+	MaxTablePlayers    = 20
+	MaxBonusDiscards   = 10
+	MaxPenaltyDuration = 10 * time.Second
+	MaxMatchGames      = 9
+)
+
+// TableSettings holds the rules of a table, edited by its creator in the lobby.
+type TableSettings struct {
+	// Mode is the variant of the game played, see GameModes.
+	Mode GameModeID `json:"mode"`
+
+	// DeckOrder n defines the deck: n^2+n+1 cards and symbols, with n+1 symbols per card.
+	// It must be one of DeckOrders.
+	DeckOrder int `json:"deck_order"`
+
+	// CardsPerPlayer is the number of cards dealt to each player. If 0, the deck is split
+	// evenly among the players, or SoloCardsPerPlayer are dealt in a solo game.
+	// It is capped so that every player gets the same number of cards.
+	// It only applies to The Tower and The Well modes, the other modes deal single cards or none.
+	CardsPerPlayer int `json:"cards_per_player"`
+
+	// BonusDiscards is the number of cards discarded when the symbol matched is a player's symbol.
+	// It only applies to The Tower mode.
+	BonusDiscards int `json:"bonus_discards"`
+
+	// PenaltyDuration is how long the clicks of a player are rejected after they click a
+	// symbol that doesn't match. If 0, wrong clicks are not penalized.
+	PenaltyDuration time.Duration `json:"penalty_duration"`
+
+	// PlayerSymbolBonus enables the bonus discards for matching a player's symbol.
+	// If disabled, every match discards exactly one card.
+	PlayerSymbolBonus bool `json:"player_symbol_bonus"`
+
+	// MaxPlayers is the maximum number of seats at the table, further players join as spectators.
+	MaxPlayers int `json:"max_players"`
+
+	// MatchGames is the number of games of a match at the table, see Match. If 1, games are played one at a time.
+	MatchGames int `json:"match_games"`
+
+	// TournamentTableSize, if not 0, makes the table the lobby of a tournament: when the game starts,
+	// the players are seeded across tables of at most this many players, see Tournament.
+	// TournamentAdvance players (at most all but one) of each table advance to the next round.
+	TournamentTableSize int `json:"tournament_table_size"`
+	TournamentAdvance   int `json:"tournament_advance"`
+}
+
+// DefaultTableSettings returns the settings of newly created tables.
+func DefaultTableSettings() TableSettings {
+	return TableSettings{
+		Mode:              ModeTower,
+		DeckOrder:         7,
+		CardsPerPlayer:    0,
+		BonusDiscards:     3,
+		PenaltyDuration:   2 * time.Second,
+		PlayerSymbolBonus: true,
+		MaxPlayers:        10,
+		MatchGames:        1,
+		TournamentAdvance: 1,
+	}
+}
+
+// Validate returns an error describing the first invalid setting, or nil if they are all valid.
+func (s TableSettings) Validate() error {
+	if LookupGameMode(s.Mode) == nil {
+		return fmt.Errorf("invalid game mode %q", s.Mode)
+	}
+	if !slices.Contains(DeckOrders, s.DeckOrder) {
+		return fmt.Errorf("invalid deck order %d, it must be one of %v", s.DeckOrder, DeckOrders)
+	}
+	if s.CardsPerPlayer < 0 || s.CardsPerPlayer > s.DeckSize()-1 {
+		return fmt.Errorf("invalid number of cards per player %d, it must be between 0 (automatic) and %d",
+			s.CardsPerPlayer, s.DeckSize()-1)
+	}
+	if s.BonusDiscards < 1 || s.BonusDiscards > MaxBonusDiscards {
+		return fmt.Errorf("invalid number of bonus discards %d, it must be between 1 and %d", s.BonusDiscards, MaxBonusDiscards)
+	}
+	if s.PenaltyDuration < 0 || s.PenaltyDuration > MaxPenaltyDuration {
+		return fmt.Errorf("invalid penalty duration %v, it must be between 0 and %v", s.PenaltyDuration, MaxPenaltyDuration)
+	}
+	if s.MaxPlayers < 1 || s.MaxPlayers > MaxTablePlayers {
+		return fmt.Errorf("invalid maximum number of players %d, it must be between 1 and %d", s.MaxPlayers, MaxTablePlayers)
+	}
+	if s.MatchGames < 1 || s.MatchGames > MaxMatchGames {
+		return fmt.Errorf("invalid number of games per match %d, it must be between 1 and %d", s.MatchGames, MaxMatchGames)
+	}
+	if s.TournamentTableSize != 0 && (s.TournamentTableSize < 2 || s.TournamentTableSize > MaxTablePlayers) {
+		return fmt.Errorf("invalid tournament table size %d, it must be 0 (no tournament) or between 2 and %d",
+			s.TournamentTableSize, MaxTablePlayers)
+	}
+	if s.TournamentTableSize != 0 && (s.TournamentAdvance < 1 || s.TournamentAdvance >= s.TournamentTableSize) {
+		return fmt.Errorf("invalid number of players advancing %d, it must be between 1 and %d",
+			s.TournamentAdvance, s.TournamentTableSize-1)
+	}
+	return nil
+}
+
+// DeckSize returns the number of cards (and of symbols) of the deck.
+func (s TableSettings) DeckSize() int {
+	return s.DeckOrder*s.DeckOrder + s.DeckOrder + 1
+}
+
+// HandSize returns the number of cards dealt to each of numPlayers players, when
+// there are available cards to deal (the deck minus the first target card).
+func (s TableSettings) HandSize(numPlayers, available int) int {
+	if numPlayers <= 0 {
+		return 0
+	}
+	fairShare := available / numPlayers
+	switch {
+	case s.CardsPerPlayer > 0:
+		return min(s.CardsPerPlayer, fairShare)
+	case numPlayers == 1:
+		return min(SoloCardsPerPlayer, fairShare)
+	default:
+		return fairShare
+	}
+}
+
+// Discards returns how many cards a player discards when the symbol matched is their own
+// player symbol (isPlayerSymbol), or 1 otherwise.
+func (s TableSettings) Discards(isPlayerSymbol bool) int {
+	if isPlayerSymbol && s.PlayerSymbolBonus {
+		return s.BonusDiscards
+	}
+	return 1
+}
@@ -0,0 +1,62 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTableSettingsValidate(t *testing.T) {
+	if err := DefaultTableSettings().Validate(); err != nil {
+		t.Fatalf("Default settings are invalid: %v", err)
+	}
+	for _, order := range DeckOrders {
+		if size := (TableSettings{DeckOrder: order}).DeckSize(); size > NumSymbolImages {
+			t.Errorf("Deck order %d needs %d symbols, but there are only %d images", order, size, NumSymbolImages)
+		}
+	}
+
+	invalid := map[string]func(s *TableSettings){
+		"deck order 6":      func(s *TableSettings) { s.DeckOrder = 6 },
+		"deck order 8":      func(s *TableSettings) { s.DeckOrder = 8 },
+		"negative cards":    func(s *TableSettings) { s.CardsPerPlayer = -1 },
+		"too many cards":    func(s *TableSettings) { s.DeckOrder, s.CardsPerPlayer = 2, 7 },
+		"no bonus discards": func(s *TableSettings) { s.BonusDiscards = 0 },
+		"long penalty":      func(s *TableSettings) { s.PenaltyDuration = time.Minute },
+		"no players":        func(s *TableSettings) { s.MaxPlayers = 0 },
+		"too many players":  func(s *TableSettings) { s.MaxPlayers = MaxTablePlayers + 1 },
+		"no match games":    func(s *TableSettings) { s.MatchGames = 0 },
+		"too many games":    func(s *TableSettings) { s.MatchGames = MaxMatchGames + 1 },
+		"tournament of 1":   func(s *TableSettings) { s.TournamentTableSize = 1 },
+		"all advance":       func(s *TableSettings) { s.TournamentTableSize, s.TournamentAdvance = 4, 4 },
+	}
+	for name, change := range invalid {
+		settings := DefaultTableSettings()
+		change(&settings)
+		if err := settings.Validate(); err == nil {
+			t.Errorf("Expected settings with %s to be invalid", name)
+		}
+	}
+}
+
+func TestTableSettingsHandSize(t *testing.T) {
+	settings := DefaultTableSettings()
+	available := settings.DeckSize() - 1
+	testCases := []struct {
+		cardsPerPlayer, numPlayers, want int
+	}{
+		{0, 1, SoloCardsPerPlayer},
+		{0, 2, 28},
+		{0, 3, 18},
+		{5, 1, 5},
+		{5, 4, 5},
+		{20, 4, 14}, // Capped to the fair share.
+		{0, 0, 0},
+	}
+	for _, tc := range testCases {
+		settings.CardsPerPlayer = tc.cardsPerPlayer
+		if got := settings.HandSize(tc.numPlayers, available); got != tc.want {
+			t.Errorf("HandSize(%d players) with %d cards per player: got %d, want %d",
+				tc.numPlayers, tc.cardsPerPlayer, got, tc.want)
+		}
+	}
+}
@@ -2,20 +2,36 @@ package game
 
 import (
 	"fmt"
+	"math/rand"
 	"strings"
 	"time"
 )
 
 // Player represents a user in the lobby or game.
 type Player struct {
-	ID        string        `json:"id"`
-	Name      string        `json:"name"`
-	Symbol    int           `json:"symbol"`     // Symbol ID chosen by the player
-	Score     int           `json:"score"`      // Number of cards
-	InPenalty bool          `json:"in_penalty"` // True if player clicked wrong symbol
-	Latency   time.Duration `json:"latency"`    // Measured round-trip time / 2 (one-way estimate)
-	Hand      [][]int       `json:"-"`          // Cards in player's hand (not sent in full state)
-	TimeTaken string        `json:"time_taken"` // Time taken to finish the game ("MM:SS"), or empty if not finished
+	ID           string        `json:"id"`
+	Name         string        `json:"name"`
+	Symbol       int           `json:"symbol"`        // Symbol ID chosen by the player
+	Score        int           `json:"score"`         // Number of cards
+	InPenalty    bool          `json:"in_penalty"`    // True if player clicked wrong symbol
+	Disconnected bool          `json:"disconnected"`  // True if player lost connection, their seat is kept for a while
+	Latency      time.Duration `json:"latency"`       // Measured round-trip time / 2 (one-way estimate)
+	Hand         [][]int       `json:"-"`             // Cards in player's hand (not sent in full state)
+	TimeTaken    time.Duration `json:"time_taken"`    // Time taken to finish the game, measured by the server, or 0 if not finished
+	Finished     bool          `json:"finished"`      // True if player is done playing: they discarded all their cards, or the game is over
+	Bot          BotLevel      `json:"bot,omitempty"` // Skill level of bot players (played by the server), empty for humans
+	Team         int           `json:"team"`          // Team of the player (1 to MaxTeams) in team modes, 0 if not assigned yet
+
+	PenaltyUntil    time.Time   `json:"-"` // Server tracking of when the current penalty ends
+	PenaltyTimer    *time.Timer `json:"-"` // Server timer to clear InPenalty
+	RecentChats     []time.Time `json:"-"` // Server tracking of the times of recent chat messages, for rate limiting
+	DisconnectTimer *time.Timer `json:"-"` // Server timer to release the seat of a disconnected player
+	BotTimer        *time.Timer `json:"-"` // Server timer for the next click of a bot player
+
+	// Server tracking of the current game, recorded in the player's PlayerStats when it's over.
+	Matches       int `json:"-"` // Rounds won
+	BonusDiscards int `json:"-"` // Extra cards discarded by matching a player's symbol
+	CardsDealt    int `json:"-"` // Cards dealt at the start of the game
 }
 
 // PendingClick represents a client click that is currently delayed waiting to be processed.
@@ -31,20 +47,62 @@ type Table struct {
 	ID           string        `json:"id"`
 	Name         string        `json:"name"`
 	Players      []*Player     `json:"players"`     // Players currently at the table
+	Spectators   []*Player     `json:"spectators"`  // Spectators watching the table, they have no cards
 	Started      bool          `json:"started"`     // True if game has started
 	StartTime    time.Time     `json:"start_time"`  // When the game started
 	TargetCard   []int         `json:"target_card"` // Current card on the table
 	Round        int           `json:"round"`       // Current round number
+	Revision     int64         `json:"revision"`    // Incremented every time the state is sent, see StateDeltaMessage
 	PendingClick *PendingClick `json:"-"`           // Server tracking of pending click
 	ClickTimer   *time.Timer   `json:"-"`           // Server timer to process the click
-	WinnerID     string        `json:"winner_id"`   // ID of the winner (the first player to discard all cards)
+	WinnerID     string        `json:"winner_id"`   // ID of the winner (e.g.: the first player to discard all cards), see GameMode
+	WinnerTeam   int           `json:"winner_team"` // Team that won, in team modes, see GameModeInfo.Teams
+	Finished     bool          `json:"finished"`    // True if the game is over
+	Public       bool          `json:"public"`      // True if the table is listed in the open tables, see TableSummary
+	AutoStart    bool          `json:"auto_start"`  // True for tables created by the server (quick matches, tournaments): the game starts once all join
+
+	// Cards laid out by some game modes, see GameMode.
+	Pile [][]int `json:"-"`    // Draw pile, face down
+	Grid [][]int `json:"-"`    // Cards face up on the table, sent in the UpdateMessage
+	Deal int     `json:"deal"` // Number of deals so far, for modes that deal cards more than once
+
+	// Settings are the rules of the table, edited by the creator before the game starts.
+	Settings TableSettings `json:"settings"`
+
+	// Seed used to generate, shuffle and deal the deck of the current game, and to break ties.
+	// Replaying a game with the same seed and the same clicks reproduces it exactly.
+	Seed int64      `json:"seed"`
+	Rand *rand.Rand `json:"-"` // Server generator seeded with Seed.
+
+	Chat []ChatMessage `json:"-"` // Last (up to ChatHistorySize) chat messages, sent separately to joining players
+
+	// Replay logs the events of the current game, and is saved when it's over.
+	Replay   *Replay `json:"-"`
+	ReplayID string  `json:"replay_id,omitempty"` // ID of the Replay of the current (or last) game
+
+	// Match accumulates the points of the games of the table, if TableSettings.MatchGames > 1.
+	Match *Match `json:"match,omitempty"`
+
+	// Tournament the table is part of: either its lobby, or one of the tables of its rounds.
+	Tournament *Tournament `json:"tournament,omitempty"`
+
+	// Snapshot is the server tracking of the last state sent, to send only what changes in the next one.
+	Snapshot *StateSnapshot `json:"-"`
+}
+
+// FormatDuration formats the time taken to finish a game as "MM:SS.s".
+func FormatDuration(d time.Duration) string {
+	return fmt.Sprintf("%02d:%04.1f", int(d.Minutes()), (d % time.Minute).Seconds())
 }
 
 func (t *Table) String() string {
 	var sb strings.Builder
-	fmt.Fprintf(&sb, "Table %s: name=%s, started=%t, round=%d, targetCard=%v, players: ", t.ID, t.Name, t.Started, t.Round, t.TargetCard)
+	fmt.Fprintf(&sb, "Table %s: name=%s, started=%t, seed=%d, round=%d, targetCard=%v, players: ", t.ID, t.Name, t.Started, t.Seed, t.Round, t.TargetCard)
 	for _, p := range t.Players {
 		fmt.Fprintf(&sb, "%s (%d, %d cards), ", p.Name, p.Score, len(p.Hand))
 	}
+	if len(t.Spectators) > 0 {
+		fmt.Fprintf(&sb, "%d spectators", len(t.Spectators))
+	}
 	return sb.String()
 }
@@ -0,0 +1,86 @@
+package game
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// NewPlayerID returns a new random player ID, used as the key of the player's persistent statistics.
+func NewPlayerID() string {
+	return newRandomID()
+}
+
+// newRandomID returns a random hexadecimal ID.
+func newRandomID() string {
+	var b [12]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// PlayerStats are the lifetime statistics of a player, kept by the server across games.
+type PlayerStats struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`   // Last name used by the player
+	Symbol int    `json:"symbol"` // Last symbol chosen by the player
+
+	GamesPlayed int `json:"games_played"` // Games played to the end
+	Wins        int `json:"wins"`         // Games won
+
+	// Matches is the number of rounds won, and PlayTime the time spent playing, until each player
+	// finished their games. See AvgTimePerMatch.
+	Matches  int           `json:"matches"`
+	PlayTime time.Duration `json:"play_time"`
+
+	// BonusDiscards is the number of extra cards discarded by matching a player's symbol, see TableSettings.BonusDiscards.
+	BonusDiscards int `json:"bonus_discards"`
+
+	// FastestSolo is the fastest time to finish a solo game, per game mode.
+	FastestSolo map[GameModeID]time.Duration `json:"fastest_solo,omitempty"`
+
+	LastPlayed time.Time `json:"last_played"`
+}
+
+// AvgTimePerMatch returns the average time it took the player to find each match, or 0 if they found none.
+func (s *PlayerStats) AvgTimePerMatch() time.Duration {
+	if s.Matches == 0 {
+		return 0
+	}
+	return s.PlayTime / time.Duration(s.Matches)
+}
+
+// GameResult is the outcome of a finished game for one of its players, as recorded in their PlayerStats.
+type GameResult struct {
+	PlayerID      string
+	Name          string
+	Symbol        int
+	Mode          GameModeID
+	Solo          bool          // Whether the player played alone
+	Won           bool          // Whether the player won the game
+	Matches       int           // Rounds won by the player
+	BonusDiscards int           // Extra cards discarded by matching a player's symbol
+	Duration      time.Duration // Time until the player finished
+	EndTime       time.Time
+}
+
+// Record adds the result of a game to the statistics.
+func (s *PlayerStats) Record(result GameResult) {
+	s.Name = result.Name
+	s.Symbol = result.Symbol
+	s.GamesPlayed++
+	if result.Won {
+		s.Wins++
+	}
+	s.Matches += result.Matches
+	s.PlayTime += result.Duration
+	s.BonusDiscards += result.BonusDiscards
+	if result.Solo && result.Won {
+		if s.FastestSolo == nil {
+			s.FastestSolo = make(map[GameModeID]time.Duration)
+		}
+		if fastest, ok := s.FastestSolo[result.Mode]; !ok || result.Duration < fastest {
+			s.FastestSolo[result.Mode] = result.Duration
+		}
+	}
+	s.LastPlayed = result.EndTime
+}
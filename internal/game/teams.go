@@ -0,0 +1,90 @@
+package game
+
+import (
+	"fmt"
+	"slices"
+)
+
+// MaxTeams is the maximum number of teams of a table, in modes played by teams.
+const MaxTeams = 4
+
+// TeamNames are the names of the teams, by Player.Team - 1.
+var TeamNames = [MaxTeams]string{"Red", "Blue", "Green", "Yellow"}
+
+// TeamName returns the name of the team, or "No team" if team is 0 (not assigned).
+func TeamName(team int) string {
+	if team < 1 || team > MaxTeams {
+		return "No team"
+	}
+	return TeamNames[team-1]
+}
+
+// Teams returns the teams with players at the table, in order. Players without a team (0) are not included.
+func (t *Table) Teams() []int {
+	var teams []int
+	for _, p := range t.Players {
+		if p.Team != 0 && !slices.Contains(teams, p.Team) {
+			teams = append(teams, p.Team)
+		}
+	}
+	slices.Sort(teams)
+	return teams
+}
+
+// BalanceTeams assigns the players without a team to the teams with fewer players, using at least two teams.
+func (t *Table) BalanceTeams() {
+	numTeams := 2
+	sizes := make([]int, MaxTeams+1)
+	for _, p := range t.Players {
+		sizes[p.Team]++
+		numTeams = max(numTeams, p.Team)
+	}
+	for _, p := range t.Players {
+		if p.Team != 0 {
+			continue
+		}
+		smallest := 1
+		for team := 2; team <= numTeams; team++ {
+			if sizes[team] < sizes[smallest] {
+				smallest = team
+			}
+		}
+		p.Team = smallest
+		sizes[smallest]++
+	}
+}
+
+// CheckTeams returns an error if the players of the table can't play a game in teams: all of them must have
+// a team, and there must be at least two teams.
+func (t *Table) CheckTeams() error {
+	for _, p := range t.Players {
+		if p.Team < 1 || p.Team > MaxTeams {
+			return fmt.Errorf("player %s has no team", p.Name)
+		}
+	}
+	if teams := t.Teams(); len(teams) < 2 {
+		return fmt.Errorf("players are in %d team(s), at least 2 are needed", len(teams))
+	}
+	return nil
+}
+
+// TeamFinished returns whether all the players of the team discarded all their cards.
+func (t *Table) TeamFinished(team int) bool {
+	found := false
+	for _, p := range t.Players {
+		if p.Team != team {
+			continue
+		}
+		if len(p.Hand) > 0 {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// Won returns whether the player won the finished game: either they are the winner, or they are in the team
+// that won.
+func (t *Table) Won(p *Player) bool {
+	return p.ID == t.WinnerID || (t.WinnerTeam != 0 && p.Team == t.WinnerTeam)
+}
@@ -0,0 +1,160 @@
+package game
+
+import (
+	"fmt"
+	"slices"
+)
+
+// TournamentPlayer is a player of a tournament.
+type TournamentPlayer struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Symbol int      `json:"symbol"`
+	Bot    BotLevel `json:"bot,omitempty"`
+}
+
+// TournamentTable is one of the tables of a round of a tournament.
+type TournamentTable struct {
+	TableID   string   `json:"table_id"`
+	PlayerIDs []string `json:"player_ids"` // By seed
+
+	Done     bool     `json:"done"`               // Set once the game (or match) of the table is over
+	Advanced []string `json:"advanced,omitempty"` // Players that advance to the next round, by finish order
+}
+
+// TournamentRound is a round of a tournament: its players are split across tables that play in parallel.
+type TournamentRound struct {
+	Tables []TournamentTable `json:"tables"`
+}
+
+// Tournament is a bracket of rounds: the players of each round are seeded across tables of at most TableSize
+// players, and the top Advance finishers of each table play the next round, until a single table is left,
+// whose winner wins the tournament.
+type Tournament struct {
+	ID        string             `json:"id"` // ID of the table where the tournament was created
+	TableSize int                `json:"table_size"`
+	Advance   int                `json:"advance"`
+	Settings  TableSettings      `json:"settings"` // Settings of the tables of the rounds
+	Players   []TournamentPlayer `json:"players"`  // By seed, the first one is the strongest
+	Rounds    []TournamentRound  `json:"rounds"`   // Rounds played so far, the last one is the current one
+	WinnerID  string             `json:"winner_id,omitempty"`
+}
+
+// NewTournament creates the tournament configured by the settings of the table where it's created, and seeds its
+// first round. The players are given by seed.
+func NewTournament(id string, settings TableSettings, players []TournamentPlayer) *Tournament {
+	t := &Tournament{
+		ID:        id,
+		TableSize: settings.TournamentTableSize,
+		Advance:   settings.TournamentAdvance,
+		Settings:  settings,
+		Players:   players,
+	}
+	t.Settings.TournamentTableSize = 0
+	t.Settings.MaxPlayers = max(t.Settings.MaxPlayers, t.TableSize)
+	ids := make([]string, len(players))
+	for i, p := range players {
+		ids[i] = p.ID
+	}
+	t.Rounds = append(t.Rounds, t.seedRound(ids))
+	return t
+}
+
+// seedRound splits the players, given by seed, across the tables of the next round.
+// Seeds are dealt back and forth across the tables ("snake" seeding), so the tables are balanced.
+func (t *Tournament) seedRound(ids []string) TournamentRound {
+	numTables := (len(ids) + t.TableSize - 1) / t.TableSize
+	round := TournamentRound{Tables: make([]TournamentTable, numTables)}
+	for i := range round.Tables {
+		round.Tables[i].TableID = fmt.Sprintf("%s R%d-%d", t.ID, len(t.Rounds)+1, i+1)
+	}
+	for i, id := range ids {
+		table := i % numTables
+		if (i/numTables)%2 == 1 {
+			table = numTables - 1 - table
+		}
+		round.Tables[table].PlayerIDs = append(round.Tables[table].PlayerIDs, id)
+	}
+	return round
+}
+
+// Over returns whether the tournament is over.
+func (t *Tournament) Over() bool {
+	return t.WinnerID != ""
+}
+
+// CurrentRound returns the round being played, or the last one if the tournament is over.
+func (t *Tournament) CurrentRound() *TournamentRound {
+	return &t.Rounds[len(t.Rounds)-1]
+}
+
+// TableOf returns the ID of the table of the current round where the player plays, or "" if they were eliminated.
+func (t *Tournament) TableOf(playerID string) string {
+	for _, table := range t.CurrentRound().Tables {
+		if slices.Contains(table.PlayerIDs, playerID) {
+			return table.TableID
+		}
+	}
+	return ""
+}
+
+// Player returns the player of the tournament with the given ID, or a player named after the ID if there is none.
+func (t *Tournament) Player(id string) TournamentPlayer {
+	for _, p := range t.Players {
+		if p.ID == id {
+			return p
+		}
+	}
+	return TournamentPlayer{ID: id, Name: id}
+}
+
+// Record sets the finish order (player IDs) of a table of the current round, once its game (or match) is over.
+// Once all tables of the round are done, it seeds the next round with the players that advanced, or sets the
+// winner of the tournament. It returns whether a new round was seeded.
+func (t *Tournament) Record(tableID string, order []string) (newRound bool) {
+	if t.Over() {
+		return false
+	}
+	round := t.CurrentRound()
+	idx := slices.IndexFunc(round.Tables, func(table TournamentTable) bool { return table.TableID == tableID })
+	if idx < 0 || round.Tables[idx].Done {
+		return false
+	}
+	table := &round.Tables[idx]
+	table.Done = true
+	if len(round.Tables) == 1 {
+		// The final table.
+		if len(order) > 0 {
+			table.Advanced = order[:1]
+			t.WinnerID = order[0]
+		}
+		return false
+	}
+	// At least one player is eliminated from each table, so that the tournament always progresses.
+	table.Advanced = slices.Clone(order[:max(min(t.Advance, len(order)-1), min(1, len(order)))])
+
+	var next []string
+	for position := 0; ; position++ {
+		found := false
+		for _, table := range round.Tables {
+			if !table.Done {
+				return false
+			}
+			if position < len(table.Advanced) {
+				next = append(next, table.Advanced[position])
+				found = true
+			}
+		}
+		if !found {
+			break
+		}
+	}
+	if len(next) <= 1 {
+		if len(next) == 1 {
+			t.WinnerID = next[0]
+		}
+		return false
+	}
+	t.Rounds = append(t.Rounds, t.seedRound(next))
+	return true
+}
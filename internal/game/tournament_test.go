@@ -0,0 +1,56 @@
+package game
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTournament(t *testing.T) {
+	var players []TournamentPlayer
+	for i := range 7 {
+		players = append(players, TournamentPlayer{ID: fmt.Sprintf("p%d", i)})
+	}
+	settings := DefaultTableSettings()
+	settings.TournamentTableSize, settings.TournamentAdvance = 3, 2
+	tournament := NewTournament("lobby", settings, players)
+	if tournament.Settings.TournamentTableSize != 0 {
+		t.Errorf("Expected the tables of the rounds not to start tournaments themselves")
+	}
+
+	// 7 players are snake seeded across 3 tables.
+	round := tournament.CurrentRound()
+	if got := fmt.Sprint(round.Tables); got != "[{lobby R1-1 [p0 p5 p6] false []} {lobby R1-2 [p1 p4] false []} {lobby R1-3 [p2 p3] false []}]" {
+		t.Fatalf("Unexpected first round: %s", got)
+	}
+	if tableID := tournament.TableOf("p4"); tableID != "lobby R1-2" {
+		t.Errorf("Expected p4 to play at lobby R1-2, got %q", tableID)
+	}
+
+	// At least one player of each table is eliminated, the others advance by finish order.
+	if tournament.Record("lobby R1-1", []string{"p6", "p0", "p5"}) || tournament.Record("lobby R1-2", []string{"p4", "p1"}) {
+		t.Fatalf("Expected the round to go on until all tables are done")
+	}
+	if tournament.Record("lobby R1-2", []string{"p1", "p4"}) {
+		t.Fatalf("Expected tables to be recorded only once")
+	}
+	if !tournament.Record("lobby R1-3", []string{"p2", "p3"}) {
+		t.Fatalf("Expected a new round once all tables are done")
+	}
+	round = tournament.CurrentRound()
+	if got := fmt.Sprint(round.Tables); got != "[{lobby R2-1 [p6 p0] false []} {lobby R2-2 [p4 p2] false []}]" {
+		t.Fatalf("Unexpected second round: %s", got)
+	}
+	if tableID := tournament.TableOf("p1"); tableID != "" {
+		t.Errorf("Expected p1 to be eliminated, got table %q", tableID)
+	}
+
+	// The winners of the tables of 2 play the final table, which decides the winner.
+	tournament.Record("lobby R2-1", []string{"p0", "p6"})
+	if !tournament.Record("lobby R2-2", []string{"p4", "p2"}) || len(tournament.CurrentRound().Tables) != 1 {
+		t.Fatalf("Expected a final table, got %+v", tournament.CurrentRound())
+	}
+	tournament.Record("lobby R3-1", []string{"p4", "p0"})
+	if !tournament.Over() || tournament.WinnerID != "p4" {
+		t.Errorf("Expected p4 to win the tournament, got %q", tournament.WinnerID)
+	}
+}
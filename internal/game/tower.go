@@ -0,0 +1,107 @@
+package game
+
+import "slices"
+
+// towerMode implements The Tower and The Well: players are dealt a pile of cards, and race to discard
+// them by matching their top card with the target card, which is replaced by the discarded card.
+//
+// In The Tower (bonus set), matching one's own player symbol discards the table's BonusDiscards, and
+// other players whose symbol was matched discard them as well.
+//
+// In Team Tower (teams set), only teammates of the player discard the bonus, and the game is won by the first team
+// whose members all discarded their cards.
+type towerMode struct {
+	info  GameModeInfo
+	bonus bool
+	teams bool
+}
+
+func (m towerMode) Info() GameModeInfo { return m.info }
+
+func (m towerMode) Deal(table *Table, deck Deck) {
+	table.TargetCard = deck[0]
+	deck = deck[1:]
+	cardsPerPlayer := table.Settings.HandSize(len(table.Players), len(deck))
+	for i, p := range table.Players {
+		p.Hand = deck[i*cardsPerPlayer : (i+1)*cardsPerPlayer]
+		p.Score = len(p.Hand)
+	}
+}
+
+func (m towerMode) ValidateClick(table *Table, player *Player, symbol int) RejectReason {
+	if len(player.Hand) == 0 {
+		return RejectNoCards
+	}
+	if !slices.Contains(table.TargetCard, symbol) || !slices.Contains(player.Hand[0], symbol) {
+		return RejectWrongSymbol
+	}
+	return ""
+}
+
+func (m towerMode) ApplyWin(table *Table, player *Player, symbol int) []string {
+	scoringIDs := []string{player.ID}
+	var finishers []*Player // players who finished their hand with this click.
+
+	numToDiscard := 1
+	if m.bonus {
+		numToDiscard = table.Settings.Discards(symbol == player.Symbol)
+	}
+	numToDiscard = min(numToDiscard, len(player.Hand))
+	player.BonusDiscards += numToDiscard - 1
+	// The target card becomes the last discarded card from the player's hand.
+	table.TargetCard = player.Hand[numToDiscard-1]
+	player.Hand = player.Hand[numToDiscard:]
+	player.Score = len(player.Hand)
+	if len(player.Hand) == 0 {
+		finishers = append(finishers, player)
+	}
+
+	// Give bonus discards to other players whose matching symbol was clicked
+	for _, p := range table.Players {
+		if p == player || !m.bonus || !table.Settings.PlayerSymbolBonus {
+			continue
+		}
+		if p.Symbol != symbol || len(p.Hand) == 0 || (m.teams && p.Team != player.Team) {
+			continue
+		}
+		numBonus := min(table.Settings.BonusDiscards, len(p.Hand))
+		p.BonusDiscards += numBonus
+		p.Hand = p.Hand[numBonus:]
+		p.Score = len(p.Hand)
+		if len(p.Hand) == 0 {
+			finishers = append(finishers, p)
+		}
+		scoringIDs = append(scoringIDs, p.ID)
+	}
+
+	for _, p := range finishers {
+		p.Finished = true
+	}
+	if m.teams {
+		// Only the clicker's team can finish with this click, and then the clicker is the last of them to finish.
+		if table.WinnerTeam == 0 && len(finishers) > 0 && table.TeamFinished(player.Team) {
+			table.WinnerTeam = player.Team
+			table.WinnerID = player.ID
+		}
+		return scoringIDs
+	}
+	// Check if any player finished the game, if they were the first.
+	if table.WinnerID == "" && len(finishers) > 0 {
+		// Break the tie randomly.
+		table.WinnerID = finishers[table.intn(len(finishers))].ID
+	}
+	return scoringIDs
+}
+
+// GameOver once all players discarded all their cards, or, when playing in teams, once a team won.
+func (m towerMode) GameOver(table *Table) bool {
+	if m.teams {
+		return table.WinnerTeam != 0
+	}
+	for _, p := range table.Players {
+		if len(p.Hand) > 0 {
+			return false
+		}
+	}
+	return true
+}
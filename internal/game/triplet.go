@@ -0,0 +1,107 @@
+package game
+
+// TripletGridSize is the number of cards laid out face up in Triplet.
+const TripletGridSize = 9
+
+// tripletMode implements Triplet: cards are laid out in a grid, and players race to find a symbol
+// shared by exactly three of them. The winner of the round collects the three cards, which are
+// replaced from the draw pile. If the grid has no triplet, a card from the pile is added to it.
+// When no triplet can be formed, the player with the most cards wins.
+type tripletMode struct{}
+
+func (tripletMode) Info() GameModeInfo {
+	return GameModeInfo{
+		ID:              ModeTriplet,
+		Name:            "Triplet",
+		Description:     "Find the symbol shared by three cards of the grid to collect them. When no more triplets can be formed, the one with the most cards wins.",
+		MinPlayers:      1,
+		HigherScoreWins: true,
+	}
+}
+
+func (m tripletMode) Deal(table *Table, deck Deck) {
+	n := min(TripletGridSize, len(deck))
+	table.Grid = deck[:n:n]
+	table.Pile = deck[n:]
+	m.ensureTriplet(table)
+}
+
+// tripletCards returns the indices of the grid cards with the symbol.
+func tripletCards(grid [][]int, symbol int) []int {
+	var indices []int
+	for i, card := range grid {
+		for _, s := range card {
+			if s == symbol {
+				indices = append(indices, i)
+				break
+			}
+		}
+	}
+	return indices
+}
+
+// hasTriplet returns whether any symbol is shared by exactly three cards of the grid.
+func hasTriplet(grid [][]int) bool {
+	counts := make(map[int]int)
+	for _, card := range grid {
+		for _, s := range card {
+			counts[s]++
+		}
+	}
+	for _, count := range counts {
+		if count == 3 {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureTriplet adds cards from the pile to the grid until there is a triplet, or the pile is exhausted.
+func (tripletMode) ensureTriplet(table *Table) {
+	for !hasTriplet(table.Grid) && len(table.Pile) > 0 {
+		table.Grid = append(table.Grid, table.Pile[0])
+		table.Pile = table.Pile[1:]
+	}
+}
+
+// ValidateClick accepts a symbol shared by exactly three cards of the grid.
+// Players need no cards to play Triplet.
+func (tripletMode) ValidateClick(table *Table, player *Player, symbol int) RejectReason {
+	if len(tripletCards(table.Grid, symbol)) != 3 {
+		return RejectWrongSymbol
+	}
+	return ""
+}
+
+func (m tripletMode) ApplyWin(table *Table, player *Player, symbol int) []string {
+	indices := tripletCards(table.Grid, symbol)
+	// Collected cards are replaced in place from the pile, unless the grid had grown larger than usual.
+	replacements := min(max(TripletGridSize-(len(table.Grid)-len(indices)), 0), len(table.Pile))
+	grid := make([][]int, 0, len(table.Grid))
+	for i, card := range table.Grid {
+		if len(indices) == 0 || indices[0] != i {
+			grid = append(grid, card)
+			continue
+		}
+		player.Hand = append(player.Hand, card)
+		indices = indices[1:]
+		if replacements > 0 {
+			grid = append(grid, table.Pile[0])
+			table.Pile = table.Pile[1:]
+			replacements--
+		}
+	}
+	table.Grid = grid
+	player.Score = len(player.Hand)
+	m.ensureTriplet(table)
+	return []string{player.ID}
+}
+
+// GameOver once no triplet can be formed.
+func (tripletMode) GameOver(table *Table) bool {
+	if hasTriplet(table.Grid) {
+		return false
+	}
+	setBestScoreWinner(table, true)
+	return true
+}
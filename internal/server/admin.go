@@ -0,0 +1,346 @@
+package server
+
+import (
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/janpfeifer/GoSpot/internal/game"
+	"k8s.io/klog/v2"
+)
+
+// adminPage is the dashboard served at /admin: it asks for the token, and uses the admin API.
+//
+//go:embed admin.html
+var adminPage []byte
+
+// adminTable is a table as listed by the admin API.
+type adminTable struct {
+	ID          string             `json:"id"`
+	Name        string             `json:"name"`
+	Mode        string             `json:"mode"`
+	Public      bool               `json:"public"`
+	Tournament  string             `json:"tournament,omitempty"`
+	Started     bool               `json:"started"`
+	Finished    bool               `json:"finished"`
+	Round       int                `json:"round"`
+	Players     []adminPlayer      `json:"players"`
+	Spectators  []adminPlayer      `json:"spectators"`
+	Connections int                `json:"connections"`
+	StartTime   time.Time          `json:"start_time,omitzero"`
+	Settings    game.TableSettings `json:"settings"`
+	Clients     []adminClient      `json:"clients,omitempty"` // Only in the full state of a table.
+	State       *savedTable        `json:"state,omitempty"`   // Only in the full state of a table.
+}
+
+// adminPlayer is a player (or spectator) of a table, as listed by the admin API.
+type adminPlayer struct {
+	ID           string        `json:"id"`
+	Name         string        `json:"name"`
+	Bot          game.BotLevel `json:"bot,omitempty"`
+	Connected    bool          `json:"connected"`
+	Disconnected bool          `json:"disconnected"`
+	Latency      time.Duration `json:"latency"` // One-way estimate, half the measured round trip time.
+	Cards        int           `json:"cards"`
+}
+
+// adminClient is a connection to a table, in the full state of the table.
+type adminClient struct {
+	PlayerID  string        `json:"player_id"`
+	Spectator bool          `json:"spectator"`
+	Encoding  game.Encoding `json:"encoding"`
+	Deltas    bool          `json:"deltas"`
+}
+
+// adminTableLocked describes the table for the admin API, with its full state if full is set.
+// Assumes the table is locked.
+func (s *ServerState) adminTableLocked(room *tableRoom, table *game.Table, full bool) adminTable {
+	at := adminTable{
+		ID:          table.ID,
+		Name:        table.Name,
+		Mode:        table.Mode().Info().Name,
+		Public:      table.Public,
+		Started:     table.Started,
+		Finished:    table.Finished,
+		Round:       table.Round,
+		Connections: len(room.clients),
+		StartTime:   table.StartTime,
+		Settings:    table.Settings,
+	}
+	if table.Tournament != nil {
+		at.Tournament = table.Tournament.ID
+	}
+	describe := func(p *game.Player, connected bool) adminPlayer {
+		return adminPlayer{
+			ID: p.ID, Name: p.Name, Bot: p.Bot, Connected: connected, Disconnected: p.Disconnected,
+			Latency: p.Latency, Cards: len(p.Hand),
+		}
+	}
+	at.Players = make([]adminPlayer, 0, len(table.Players))
+	for _, p := range table.Players {
+		at.Players = append(at.Players, describe(p, s.isConnectedLocked(table, p.ID)))
+	}
+	at.Spectators = make([]adminPlayer, 0, len(table.Spectators))
+	for _, p := range table.Spectators {
+		at.Spectators = append(at.Spectators, describe(p, s.isSpectatingLocked(table, p.ID)))
+	}
+	if full {
+		for _, c := range room.clients {
+			at.Clients = append(at.Clients, adminClient{
+				PlayerID: c.playerID, Spectator: c.spectator, Encoding: c.encoding, Deltas: c.deltas,
+			})
+		}
+		saved := saveTableLocked(table)
+		at.State = &saved
+	}
+	return at
+}
+
+// adminEndTableLocked ends the game in progress on the table, as if it was over. Its results are not recorded, but
+// its replay is saved, without a winner. Tables of a tournament can't be ended this way: their results are needed
+// to advance the tournament.
+// Assumes the table is locked.
+func (s *ServerState) adminEndTableLocked(table *game.Table) {
+	table.Finished = true
+	if table.Replay != nil {
+		logEventLocked(table, game.GameEvent{Type: game.EventGameOver}, time.Now())
+		go s.saveReplay(table.Replay)
+		table.Replay = nil
+	}
+	table.PendingClick = nil
+	if table.ClickTimer != nil {
+		table.ClickTimer.Stop()
+		table.ClickTimer = nil
+	}
+	stopBotsLocked(table)
+	for _, p := range table.Players {
+		p.Finished = true
+	}
+	s.broadcastStateLocked(table)
+}
+
+// adminDeleteTableLocked tells the clients of the table it was closed, and deletes it. Tables of a tournament can't
+// be deleted this way: the tournament would wait for their results forever.
+// Assumes the table is locked.
+func (s *ServerState) adminDeleteTableLocked(room *tableRoom, table *game.Table) {
+	errorMsg, _ := game.NewWsMessage(game.MsgTypeError, game.ErrorMessage{
+		Message: "Table was closed by the administrators.",
+	})
+	for _, c := range room.clients {
+		c.send(errorMsg)
+		c.out.closeAfter(websocket.StatusNormalClosure, "Table closed")
+	}
+	if table.ClickTimer != nil {
+		table.ClickTimer.Stop()
+	}
+	stopBotsLocked(table)
+	s.removeTableLocked(table)
+}
+
+// adminKickLocked removes the player (or spectator) from the table, and closes their connections to it.
+// They can join again, as a new player. It returns false if there is no such player.
+// Assumes the table is locked.
+func (s *ServerState) adminKickLocked(room *tableRoom, table *game.Table, playerID string) bool {
+	isPlayer := func(p *game.Player) bool { return p.ID == playerID }
+	found := slices.ContainsFunc(table.Players, isPlayer) || slices.ContainsFunc(table.Spectators, isPlayer)
+	if !found {
+		return false
+	}
+	errorMsg, _ := game.NewWsMessage(game.MsgTypeError, game.ErrorMessage{
+		Message: "You were removed from the table by the administrators.",
+	})
+	// Forgetting the connections first, so their disconnection doesn't keep a seat.
+	for conn, c := range room.clients {
+		if c.playerID == playerID {
+			c.send(errorMsg)
+			c.out.closeAfter(websocket.StatusNormalClosure, "Removed from the table")
+			delete(room.clients, conn)
+		}
+	}
+	table.Players = slices.DeleteFunc(table.Players, func(p *game.Player) bool {
+		if !isPlayer(p) {
+			return false
+		}
+		for _, timer := range []*time.Timer{p.DisconnectTimer, p.PenaltyTimer, p.BotTimer} {
+			if timer != nil {
+				timer.Stop()
+			}
+		}
+		p.DisconnectTimer = nil
+		return true
+	})
+	table.Spectators = slices.DeleteFunc(table.Spectators, isPlayer)
+	klog.Infof("adminKick: Removed player %s from table %s", playerID, table.ID)
+	ensureHumanCreatorLocked(table)
+	if !s.deleteIfAbandonedLocked(table) {
+		s.broadcastStateLocked(table)
+	}
+	return true
+}
+
+// adminNotice sends the notice to all clients of the tables, and returns to how many.
+// The players waiting in the quick match queue don't get it.
+func (s *ServerState) adminNotice(message string) int {
+	noticeMsg, err := game.NewWsMessage(game.MsgTypeNotice, game.NoticeMessage{Message: message})
+	if err != nil {
+		klog.Errorf("adminNotice: Failed to create notice message: %v", err)
+		return 0
+	}
+	s.mu.RLock()
+	tables := slices.Collect(maps.Values(s.Tables))
+	s.mu.RUnlock()
+	var sent int
+	for _, table := range tables {
+		room := s.lockTable(table)
+		if room == nil {
+			continue
+		}
+		for _, c := range room.clients {
+			c.send(noticeMsg)
+			sent++
+		}
+		room.mu.Unlock()
+	}
+	klog.Infof("adminNotice: Sent notice %q to %d connections", message, sent)
+	return sent
+}
+
+// adminAuthorized checks the token of an admin API request, given as "Authorization: Bearer <token>".
+// The admin API is disabled (it's never authorized) if the server has no AdminToken.
+func (s *ServerState) adminAuthorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && s.AdminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.AdminToken)) == 1
+}
+
+// HandleAdminPage serves the admin dashboard, at /admin. The page itself has no data: it's only shown if
+// the admin API is enabled, and it asks for the token to use it.
+func (s *ServerState) HandleAdminPage(w http.ResponseWriter, r *http.Request) {
+	if s.AdminToken == "" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(adminPage)
+}
+
+// HandleAdmin serves the admin API, at /admin/api/, for the operators of the server:
+//
+//   - GET tables: lists the tables, with their players and latencies.
+//   - GET tables/<table_id>: the full state of the table, including the hands of the players.
+//   - DELETE tables/<table_id>: closes the table, disconnecting its clients, if it's not part of a tournament.
+//   - POST tables/<table_id>/end: ends the game in progress on the table, if it's not part of a tournament.
+//   - POST tables/<table_id>/kick?player=<player_id>: removes the player from the table.
+//   - POST notice, with a JSON game.NoticeMessage: sends the notice to all clients (e.g.: before a maintenance).
+//
+// Only the tables run by this instance are listed, see TableStore.
+func (s *ServerState) HandleAdmin(w http.ResponseWriter, r *http.Request) {
+	if !s.adminAuthorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var path []string
+	for _, segment := range strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), "/admin/api/"), "/") {
+		segment, err := url.PathUnescape(segment)
+		if err != nil {
+			http.Error(w, "invalid path", http.StatusBadRequest)
+			return
+		}
+		path = append(path, segment)
+	}
+
+	switch {
+	case len(path) == 1 && path[0] == "tables" && r.Method == http.MethodGet:
+		s.mu.RLock()
+		tables := slices.SortedFunc(maps.Values(s.Tables), func(a, b *game.Table) int {
+			return strings.Compare(a.ID, b.ID)
+		})
+		s.mu.RUnlock()
+		list := make([]adminTable, 0, len(tables))
+		for _, table := range tables {
+			room := s.lockTable(table)
+			if room == nil {
+				continue
+			}
+			list = append(list, s.adminTableLocked(room, table, false))
+			room.mu.Unlock()
+		}
+		writeAdminJSON(w, list)
+
+	case len(path) == 1 && path[0] == "notice" && r.Method == http.MethodPost:
+		var notice game.NoticeMessage
+		if err := json.NewDecoder(r.Body).Decode(&notice); err != nil || strings.TrimSpace(notice.Message) == "" {
+			http.Error(w, "invalid notice", http.StatusBadRequest)
+			return
+		}
+		writeAdminJSON(w, map[string]int{"connections": s.adminNotice(notice.Message)})
+
+	case len(path) >= 2 && len(path) <= 3 && path[0] == "tables":
+		s.mu.RLock()
+		table := s.Tables[path[1]]
+		s.mu.RUnlock()
+		var room *tableRoom
+		if table != nil {
+			room = s.lockTable(table)
+		}
+		if room == nil {
+			http.Error(w, "table not found", http.StatusNotFound)
+			return
+		}
+		defer room.mu.Unlock()
+
+		action := ""
+		if len(path) == 3 {
+			action = path[2]
+		}
+		switch {
+		case action == "" && r.Method == http.MethodGet:
+			writeAdminJSON(w, s.adminTableLocked(room, table, true))
+		case action == "" && r.Method == http.MethodDelete:
+			if table.Tournament != nil {
+				http.Error(w, "tables of a tournament can't be deleted", http.StatusConflict)
+				return
+			}
+			klog.Infof("HandleAdmin: Closing table %s", table.ID)
+			s.adminDeleteTableLocked(room, table)
+			w.WriteHeader(http.StatusNoContent)
+		case action == "end" && r.Method == http.MethodPost:
+			if !table.Started || table.Finished {
+				http.Error(w, "no game in progress", http.StatusConflict)
+				return
+			}
+			if table.Tournament != nil {
+				http.Error(w, "games of a tournament can't be ended", http.StatusConflict)
+				return
+			}
+			klog.Infof("HandleAdmin: Ending the game on table %s", table.ID)
+			s.adminEndTableLocked(table)
+			w.WriteHeader(http.StatusNoContent)
+		case action == "kick" && r.Method == http.MethodPost:
+			if !s.adminKickLocked(room, table, r.URL.Query().Get("player")) {
+				http.Error(w, "player not found", http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+// writeAdminJSON writes the response of the admin API.
+func writeAdminJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		klog.Errorf("HandleAdmin: Failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,248 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/janpfeifer/GoSpot/internal/game"
+)
+
+// testAdmin makes a request to the admin API of the server, with the given token, and returns the response.
+func testAdmin(s *ServerState, token, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/admin/api/"+path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.HandleAdmin(rec, req)
+	return rec
+}
+
+func TestAdmin(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		const token = "secret"
+		started := make(chan *ServerState, 1)
+		go RunWithConfig(ctx, Config{Addr: NetPipeAddr, AdminToken: token}, started)
+		s := <-started
+		wsURL := "ws://" + s.Address + "/ws"
+		const tableID = "test/admin"
+
+		// The messages received by each player, and how their connection was closed.
+		type received struct {
+			types  []game.MessageType
+			notice string
+			closed websocket.StatusCode
+		}
+		join := func(playerID, name string) (*websocket.Conn, *received) {
+			conn, err := testConnectAndJoin(ctx, s, wsURL, tableID, playerID, name, 0, 0)
+			if err != nil {
+				t.Fatalf("%s failed to join: %v", name, err)
+			}
+			r := &received{closed: -1}
+			go func() {
+				for {
+					var msg game.WsMessage
+					if err := wsjson.Read(ctx, conn, &msg); err != nil {
+						r.closed = websocket.CloseStatus(err)
+						return
+					}
+					r.types = append(r.types, msg.Type)
+					if msg.Type == game.MsgTypeNotice {
+						p, _ := msg.Parse()
+						r.notice = p.(*game.NoticeMessage).Message
+					}
+				}
+			}()
+			return conn, r
+		}
+		alice, aliceMsgs := join("p1", "Alice")
+		defer alice.CloseNow()
+		bob, bobMsgs := join("p2", "Bob")
+		defer bob.CloseNow()
+		startMsg, _ := game.NewWsMessage(game.MsgTypeStart, nil)
+		_ = wsjson.Write(ctx, alice, startMsg)
+		synctest.Wait()
+
+		// Only requests with the token are served.
+		for _, badToken := range []string{"", "wrong"} {
+			if rec := testAdmin(s, badToken, "GET", "tables", ""); rec.Code != http.StatusUnauthorized {
+				t.Errorf("Expected token %q to be refused, got %d", badToken, rec.Code)
+			}
+		}
+
+		rec := testAdmin(s, token, "GET", "tables", "")
+		var tables []adminTable
+		if err := json.Unmarshal(rec.Body.Bytes(), &tables); err != nil {
+			t.Fatalf("Failed to parse tables: %v, %s", err, rec.Body)
+		}
+		if len(tables) != 1 || tables[0].ID != tableID || len(tables[0].Players) != 2 ||
+			!tables[0].Players[1].Connected || tables[0].Connections != 2 || tables[0].State != nil {
+			t.Errorf("Expected table %s with Alice and Bob connected, got %+v", tableID, tables)
+		}
+
+		// The full state includes the hands. Table IDs are escaped in the path.
+		rec = testAdmin(s, token, "GET", "tables/test%2Fadmin", "")
+		var full adminTable
+		if err := json.Unmarshal(rec.Body.Bytes(), &full); err != nil {
+			t.Fatalf("Failed to parse table: %v, %s", err, rec.Body)
+		}
+		if full.State == nil || len(full.State.Players) != 2 || len(full.State.Players[0].Hand) == 0 || len(full.Clients) != 2 {
+			t.Errorf("Expected the full state of the table, with the hands, got %s", rec.Body)
+		}
+		if rec := testAdmin(s, token, "GET", "tables/unknown", ""); rec.Code != http.StatusNotFound {
+			t.Errorf("Expected unknown table to be not found, got %d", rec.Code)
+		}
+
+		// Notices are sent to every client.
+		rec = testAdmin(s, token, "POST", "notice", `{"message": "Maintenance in 5 minutes"}`)
+		synctest.Wait()
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"connections":2`) {
+			t.Errorf("Expected the notice to be sent to 2 connections, got %d: %s", rec.Code, rec.Body)
+		}
+		if aliceMsgs.notice != "Maintenance in 5 minutes" || bobMsgs.notice != "Maintenance in 5 minutes" {
+			t.Errorf("Expected the players to get the notice, got %q and %q", aliceMsgs.notice, bobMsgs.notice)
+		}
+
+		// Bob is kicked: told why, and disconnected without keeping his seat.
+		if rec := testAdmin(s, token, "POST", "tables/test%2Fadmin/kick?player=p2", ""); rec.Code != http.StatusNoContent {
+			t.Fatalf("Failed to kick Bob: %d %s", rec.Code, rec.Body)
+		}
+		synctest.Wait()
+		if !slices.Contains(bobMsgs.types, game.MsgTypeError) || bobMsgs.closed != websocket.StatusNormalClosure {
+			t.Errorf("Expected Bob to get an error and be disconnected, got %v, %v", bobMsgs.types, bobMsgs.closed)
+		}
+		table, unlock := testLockTableID(s, tableID)
+		if table == nil || len(table.Players) != 1 || table.Players[0].ID != "p1" {
+			t.Errorf("Expected only Alice left, got %v", table)
+		}
+		unlock()
+		if rec := testAdmin(s, token, "POST", "tables/test%2Fadmin/kick?player=p2", ""); rec.Code != http.StatusNotFound {
+			t.Errorf("Expected kicking Bob again to fail, got %d", rec.Code)
+		}
+
+		// The game is ended, and its replay saved.
+		testLock(t, s, table)
+		replayID := table.Replay.ID
+		testUnlock(s, table)
+		if rec := testAdmin(s, token, "POST", "tables/test%2Fadmin/end", ""); rec.Code != http.StatusNoContent {
+			t.Fatalf("Failed to end the game: %d %s", rec.Code, rec.Body)
+		}
+		synctest.Wait()
+		testLock(t, s, table)
+		if !table.Finished || !table.Players[0].Finished || table.Replay != nil {
+			t.Errorf("Expected the game to be over, got %s", table)
+		}
+		testUnlock(s, table)
+		replay, err := s.Replays.Get(replayID)
+		if err != nil || replay == nil || replay.Events[len(replay.Events)-1].Type != game.EventGameOver {
+			t.Errorf("Expected replay %s to be saved, ending with the game over, got %+v, %v", replayID, replay, err)
+		}
+		if rec := testAdmin(s, token, "POST", "tables/test%2Fadmin/end", ""); rec.Code != http.StatusConflict {
+			t.Errorf("Expected ending the game again to fail, got %d", rec.Code)
+		}
+
+		// The table is deleted.
+		if rec := testAdmin(s, token, "DELETE", "tables/test%2Fadmin", ""); rec.Code != http.StatusNoContent {
+			t.Fatalf("Failed to delete the table: %d %s", rec.Code, rec.Body)
+		}
+		synctest.Wait()
+		if testTable(s, tableID) != nil {
+			t.Errorf("Expected table %s to be deleted", tableID)
+		}
+		if aliceMsgs.closed != websocket.StatusNormalClosure {
+			t.Errorf("Expected Alice to be disconnected, got %v", aliceMsgs.closed)
+		}
+	})
+}
+
+func TestAdminTournament(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		s := NewServerState()
+		const token = "secret"
+		s.AdminToken = token
+		alice := &game.Player{ID: "p1", Name: "Alice", Symbol: 1}
+		lobby := &game.Table{
+			ID:       "test-admin-tournament",
+			Name:     "test-admin-tournament",
+			Players:  []*game.Player{alice},
+			Settings: game.DefaultTableSettings(),
+		}
+		lobby.Settings.CardsPerPlayer = 5
+		lobby.Settings.TournamentTableSize = 3
+		lobby.Settings.TournamentAdvance = 1
+		s.addTable(lobby, nil)
+		mu := s.roomOf(lobby).mu
+		mu.Lock()
+		for range 5 {
+			s.handleAddBot(lobby, alice, &game.AddBotMessage{Level: game.BotHard})
+		}
+		s.handleGameStart(lobby, alice, nil)
+		mu.Unlock()
+		synctest.Wait()
+		mu.Lock()
+		tournament := lobby.Tournament
+		var botTable *game.Table
+		for _, tt := range tournament.CurrentRound().Tables {
+			if table := testTable(s, tt.TableID); table.Started {
+				botTable = table
+			}
+		}
+		mu.Unlock()
+		if botTable == nil {
+			t.Fatalf("Expected a table with only bots to be started")
+		}
+
+		// Ending a game of the tournament, or deleting its table, would leave it stuck, without its result.
+		path := "tables/" + url.PathEscape(botTable.ID)
+		if rec := testAdmin(s, token, "POST", path+"/end", ""); rec.Code != http.StatusConflict {
+			t.Errorf("Expected ending a game of the tournament to fail, got %d %s", rec.Code, rec.Body)
+		}
+		if rec := testAdmin(s, token, "DELETE", path, ""); rec.Code != http.StatusConflict {
+			t.Errorf("Expected deleting a table of the tournament to fail, got %d %s", rec.Code, rec.Body)
+		}
+		mu.Lock()
+		if botTable.Finished || testTable(s, botTable.ID) != botTable {
+			t.Errorf("Expected the game of the tournament to go on, got %s", botTable)
+		}
+		mu.Unlock()
+
+		// The tournament is played to the end.
+		time.Sleep(s.Matchmaker.JoinTimeout + 30*time.Minute)
+		synctest.Wait()
+		mu.Lock()
+		defer mu.Unlock()
+		if !tournament.Over() {
+			t.Errorf("Expected the tournament to be over, got %+v", tournament)
+		}
+	})
+}
+
+func TestAdminDisabled(t *testing.T) {
+	s := NewServerState()
+	if rec := testAdmin(s, "", "GET", "tables", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the admin API to be disabled without a token, got %d", rec.Code)
+	}
+	rec := httptest.NewRecorder()
+	s.HandleAdminPage(rec, httptest.NewRequest("GET", "/admin", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected the admin page to be disabled without a token, got %d", rec.Code)
+	}
+	s.AdminToken = "secret"
+	rec = httptest.NewRecorder()
+	s.HandleAdminPage(rec, httptest.NewRequest("GET", "/admin", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "/admin/api/") {
+		t.Errorf("Expected the admin page, got %d", rec.Code)
+	}
+}
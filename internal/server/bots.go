@@ -0,0 +1,226 @@
+package server
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"time"
+
+	"github.com/janpfeifer/GoSpot/internal/game"
+	"k8s.io/klog/v2"
+)
+
+// BotSkill configures how fast and how accurately the bots of a level play.
+type BotSkill struct {
+	// MinDelay and MaxDelay bound the reaction time of the bot after each new round (or after a penalty).
+	// It is drawn randomly in between, skewed towards the faster end, like humans are.
+	MinDelay, MaxDelay time.Duration
+
+	// MistakeRate is the probability that the bot clicks a wrong symbol.
+	MistakeRate float64
+}
+
+// BotSkills configures the bots of each level.
+var BotSkills = map[game.BotLevel]BotSkill{
+	game.BotEasy:   {MinDelay: 3 * time.Second, MaxDelay: 8 * time.Second, MistakeRate: 0.15},
+	game.BotMedium: {MinDelay: 2 * time.Second, MaxDelay: 5 * time.Second, MistakeRate: 0.08},
+	game.BotHard:   {MinDelay: 1 * time.Second, MaxDelay: 3 * time.Second, MistakeRate: 0.03},
+}
+
+// delay returns a random reaction time.
+func (skill BotSkill) delay() time.Duration {
+	// The product of two uniform variables is skewed towards 0: most reactions are fast, some are slow.
+	r := rand.Float64() * rand.Float64()
+	return skill.MinDelay + time.Duration(r*float64(skill.MaxDelay-skill.MinDelay))
+}
+
+// handleAddBot adds a bot player to the table: only the creator can do it, and only before the game starts.
+// It is called from the locked tableHandleMessage function.
+func (s *ServerState) handleAddBot(table *game.Table, player *game.Player, msg *game.AddBotMessage) {
+	if len(table.Players) == 0 || table.Players[0].ID != player.ID {
+		klog.Errorf("handleAddBot: Only creator can add bots to table %s", table.ID)
+		return
+	}
+	if table.Started {
+		klog.Errorf("handleAddBot: Can't add bots to table %s, game already started", table.ID)
+		return
+	}
+	if _, ok := BotSkills[msg.Level]; !ok {
+		klog.Errorf("handleAddBot: Invalid bot level %q for table %s", msg.Level, table.ID)
+		return
+	}
+	if len(table.Players) >= table.Settings.MaxPlayers {
+		klog.Errorf("handleAddBot: Table %s is full (%d players)", table.ID, len(table.Players))
+		return
+	}
+
+	// Pick a name and a symbol not used by other players, if possible.
+	usedNames := make(map[string]bool)
+	usedSymbols := make(map[int]bool)
+	for _, p := range table.Players {
+		usedNames[p.Name] = true
+		usedSymbols[p.Symbol] = true
+	}
+	name := fmt.Sprintf("Bot %d", len(table.Players)+1)
+	for _, i := range rand.Perm(len(game.BotNames)) {
+		if !usedNames[game.BotNames[i]] {
+			name = game.BotNames[i]
+			break
+		}
+	}
+	symbols := rand.Perm(table.Settings.DeckSize())
+	symbol := symbols[0]
+	for _, sym := range symbols {
+		if !usedSymbols[sym] {
+			symbol = sym
+			break
+		}
+	}
+
+	bot := &game.Player{
+		ID:     fmt.Sprintf("bot-%x", rand.Int63()),
+		Name:   name,
+		Symbol: symbol,
+		Bot:    msg.Level,
+	}
+	klog.Infof("handleAddBot: Adding %s bot %q to table %s", bot.Bot, bot.Name, table.ID)
+	table.Players = append(table.Players, bot)
+	s.broadcastStateLocked(table)
+}
+
+// handleRemoveBot removes a bot player from the table: only the creator can do it, and only before the game starts.
+// It is called from the locked tableHandleMessage function.
+func (s *ServerState) handleRemoveBot(table *game.Table, player *game.Player, msg *game.RemoveBotMessage) {
+	if len(table.Players) == 0 || table.Players[0].ID != player.ID {
+		klog.Errorf("handleRemoveBot: Only creator can remove bots from table %s", table.ID)
+		return
+	}
+	if table.Started {
+		klog.Errorf("handleRemoveBot: Can't remove bots from table %s, game already started", table.ID)
+		return
+	}
+	table.Players = slices.DeleteFunc(table.Players, func(p *game.Player) bool {
+		if p.ID != msg.PlayerID || p.Bot == "" {
+			return false
+		}
+		klog.Infof("handleRemoveBot: Removing bot %q from table %s", p.Name, table.ID)
+		if p.BotTimer != nil {
+			p.BotTimer.Stop()
+		}
+		return true
+	})
+	s.broadcastStateLocked(table)
+}
+
+// scheduleBotsLocked schedules the next click of every bot on the table, for the current round.
+// Assumes the table is locked.
+func (s *ServerState) scheduleBotsLocked(table *game.Table) {
+	for _, p := range table.Players {
+		if p.Bot != "" {
+			s.scheduleBotLocked(table, p)
+		}
+	}
+}
+
+// scheduleBotLocked schedules the next click of the bot, for the current round, after its reaction time.
+// Nothing is scheduled if the bot has no match to find in this round (e.g.: it has no cards).
+// Assumes the table is locked.
+func (s *ServerState) scheduleBotLocked(table *game.Table, bot *game.Player) {
+	if bot.BotTimer != nil {
+		bot.BotTimer.Stop()
+		bot.BotTimer = nil
+	}
+	if !table.Started || table.Finished || botMatch(table, bot) < 0 {
+		return
+	}
+	round := table.Round
+	bot.BotTimer = time.AfterFunc(BotSkills[bot.Bot].delay(), func() {
+		s.botClick(table, bot, round)
+	})
+}
+
+// stopBotsLocked stops the timers of all bots of the table.
+// Assumes the table is locked.
+func stopBotsLocked(table *game.Table) {
+	for _, p := range table.Players {
+		if p.BotTimer != nil {
+			p.BotTimer.Stop()
+			p.BotTimer = nil
+		}
+	}
+}
+
+// botClick is called when the reaction time of the bot expires: the bot clicks on the match it found,
+// or on a wrong symbol if it makes a mistake.
+// The click goes through handleClick, exactly as the clicks of human players.
+func (s *ServerState) botClick(table *game.Table, bot *game.Player, round int) {
+	room := s.lockTable(table)
+	if room == nil {
+		return
+	}
+	defer room.mu.Unlock()
+
+	if !table.Started || table.Finished || table.Round != round {
+		// Table is gone, or the round is over.
+		return
+	}
+	bot.BotTimer = nil
+
+	symbol := botMatch(table, bot)
+	if symbol < 0 {
+		return
+	}
+	if rand.Float64() < BotSkills[bot.Bot].MistakeRate {
+		symbol = botMistake(table, bot)
+	}
+	reject := s.handleClick(table, bot, &game.ClickMessage{Symbol: symbol, Round: round})
+	if reject != nil {
+		klog.Infof("botClick: Bot %s click on %d rejected: %s", bot.Name, symbol, reject.Reason)
+		if !bot.InPenalty {
+			// No penalty to wait for (e.g.: penalties disabled), try again.
+			s.scheduleBotLocked(table, bot)
+		}
+	}
+}
+
+// botMatch returns a symbol that is a match for the bot in the current round, or -1 if there is none.
+func botMatch(table *game.Table, bot *game.Player) int {
+	mode := table.Mode()
+	for symbol := range table.Settings.DeckSize() {
+		if mode.ValidateClick(table, bot, symbol) == "" {
+			return symbol
+		}
+	}
+	return -1
+}
+
+// botMistake returns a symbol that is not a match for the bot, preferably one of its top card.
+func botMistake(table *game.Table, bot *game.Player) int {
+	mode := table.Mode()
+	var candidates []int
+	if len(bot.Hand) > 0 {
+		candidates = slices.Clone(bot.Hand[0])
+	}
+	if len(candidates) == 0 {
+		candidates = rand.Perm(table.Settings.DeckSize())
+	}
+	rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+	for _, symbol := range candidates {
+		if mode.ValidateClick(table, bot, symbol) == game.RejectWrongSymbol {
+			return symbol
+		}
+	}
+	return candidates[0]
+}
+
+// ensureHumanCreatorLocked makes sure the creator of the table (the first player) is not a bot,
+// if there is any human player left.
+// Assumes the table is locked.
+func ensureHumanCreatorLocked(table *game.Table) {
+	idx := slices.IndexFunc(table.Players, func(p *game.Player) bool { return p.Bot == "" })
+	if idx > 0 {
+		creator := table.Players[idx]
+		copy(table.Players[1:idx+1], table.Players[:idx])
+		table.Players[0] = creator
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/janpfeifer/GoSpot/internal/game"
+)
+
+func TestBots(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		s := NewServerState()
+		tableID := "test-bots"
+		alice := &game.Player{ID: "p1", Name: "Alice", Symbol: 1}
+		bob := &game.Player{ID: "p2", Name: "Bob", Symbol: 2}
+		table := &game.Table{
+			ID:       tableID,
+			Name:     tableID,
+			Players:  []*game.Player{alice, bob},
+			Settings: game.DefaultTableSettings(),
+		}
+		table.Settings.CardsPerPlayer = 5
+		s.addTable(table, nil)
+
+		testLock(t, s, table)
+		// Only the creator can add and remove bots.
+		s.handleAddBot(table, bob, &game.AddBotMessage{Level: game.BotHard})
+		if len(table.Players) != 2 {
+			t.Fatalf("Expected bots added by non-creator to be ignored")
+		}
+		s.handleAddBot(table, alice, &game.AddBotMessage{Level: "genius"})
+		if len(table.Players) != 2 {
+			t.Fatalf("Expected bots with invalid level to be ignored")
+		}
+		s.handleAddBot(table, alice, &game.AddBotMessage{Level: game.BotEasy})
+		s.handleAddBot(table, alice, &game.AddBotMessage{Level: game.BotHard})
+		if len(table.Players) != 4 {
+			t.Fatalf("Expected 2 bots to be added, got %s", table)
+		}
+		easyBot, hardBot := table.Players[2], table.Players[3]
+		if easyBot.Bot != game.BotEasy || hardBot.Bot != game.BotHard || easyBot.Name == hardBot.Name {
+			t.Fatalf("Expected an easy and a hard bot with different names, got %+v and %+v", easyBot, hardBot)
+		}
+		s.handleRemoveBot(table, alice, &game.RemoveBotMessage{PlayerID: easyBot.ID})
+		s.handleRemoveBot(table, alice, &game.RemoveBotMessage{PlayerID: bob.ID}) // Not a bot.
+		if len(table.Players) != 3 || table.Players[2] != hardBot {
+			t.Fatalf("Expected only the easy bot to be removed, got %s", table)
+		}
+
+		// Humans don't play: the bot discards all its cards.
+		s.handleGameStart(table, alice, &game.StartMessage{})
+		testUnlock(s, table)
+		time.Sleep(10 * time.Minute)
+		synctest.Wait()
+
+		testLock(t, s, table)
+		defer testUnlock(s, table)
+		if !hardBot.Finished || len(hardBot.Hand) != 0 {
+			t.Errorf("Expected bot to have discarded all its cards, got %d left", len(hardBot.Hand))
+		}
+		if table.WinnerID != hardBot.ID {
+			t.Errorf("Expected bot to win, got winner %q", table.WinnerID)
+		}
+		if alice.Score != 5 || bob.Score != 5 {
+			t.Errorf("Expected humans to keep their cards, got %d and %d", alice.Score, bob.Score)
+		}
+		if hardBot.BotTimer != nil {
+			t.Errorf("Expected no more clicks scheduled for a finished bot")
+		}
+	})
+}
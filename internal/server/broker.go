@@ -0,0 +1,311 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"k8s.io/klog/v2"
+)
+
+// Broker is the publish/subscribe channel between the server instances that share their tables, see TableStore.
+//
+// Implementations must be safe for concurrent use, and deliver the messages of each publisher in order.
+type Broker interface {
+	// Publish sends data to the current subscribers of the topic. The data must not be modified afterwards.
+	Publish(topic string, data []byte) error
+
+	// Subscribe calls handle with the data published to the topic, in order, until unsubscribe is called.
+	// handle must not block, nor modify the data.
+	Subscribe(topic string, handle func(data []byte)) (unsubscribe func(), err error)
+}
+
+// subscriptions keeps the handlers subscribed to each topic.
+type subscriptions struct {
+	mu     sync.Mutex
+	topics map[string]map[int]func([]byte)
+	nextID int
+}
+
+// add subscribes handle to the topic. It returns the subscription ID, and whether it's the first one of the topic.
+func (subs *subscriptions) add(topic string, handle func([]byte)) (id int, first bool) {
+	subs.mu.Lock()
+	defer subs.mu.Unlock()
+	if subs.topics == nil {
+		subs.topics = make(map[string]map[int]func([]byte))
+	}
+	handlers := subs.topics[topic]
+	if handlers == nil {
+		handlers = make(map[int]func([]byte))
+		subs.topics[topic] = handlers
+	}
+	subs.nextID++
+	handlers[subs.nextID] = handle
+	return subs.nextID, len(handlers) == 1
+}
+
+// remove unsubscribes the handler with the given ID. It returns whether it was the last one of the topic.
+func (subs *subscriptions) remove(topic string, id int) (last bool) {
+	subs.mu.Lock()
+	defer subs.mu.Unlock()
+	handlers := subs.topics[topic]
+	if _, ok := handlers[id]; !ok {
+		return false
+	}
+	delete(handlers, id)
+	if len(handlers) > 0 {
+		return false
+	}
+	delete(subs.topics, topic)
+	return true
+}
+
+// deliver calls the handlers of the topic with data. The lock is not held while calling them, so they can
+// subscribe and publish.
+func (subs *subscriptions) deliver(topic string, data []byte) {
+	subs.mu.Lock()
+	handlers := make([]func([]byte), 0, len(subs.topics[topic]))
+	for _, handle := range subs.topics[topic] {
+		handlers = append(handlers, handle)
+	}
+	subs.mu.Unlock()
+	for _, handle := range handlers {
+		handle(data)
+	}
+}
+
+// MemoryBroker is a Broker for the instances of the same process: messages are delivered as they are published.
+type MemoryBroker struct {
+	subs subscriptions
+}
+
+var _ Broker = (*MemoryBroker)(nil)
+
+// NewMemoryBroker creates a MemoryBroker with no subscribers.
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{}
+}
+
+// Publish implements Broker.
+func (m *MemoryBroker) Publish(topic string, data []byte) error {
+	m.subs.deliver(topic, data)
+	return nil
+}
+
+// Subscribe implements Broker.
+func (m *MemoryBroker) Subscribe(topic string, handle func([]byte)) (func(), error) {
+	id, _ := m.subs.add(topic, handle)
+	return func() { m.subs.remove(topic, id) }, nil
+}
+
+// Operations of the broker protocol, spoken between BrokerClient and ServeBroker.
+// Each frame is the operation, the length (uint16) and the topic, and the length (uint32) and the data.
+const (
+	brokerSubscribe   byte = 's'
+	brokerUnsubscribe byte = 'u'
+	brokerPublish     byte = 'p'
+)
+
+// brokerQueueSize is the number of messages queued to a slow connection of the broker, before it's dropped.
+const brokerQueueSize = 4096
+
+func writeBrokerFrame(w io.Writer, op byte, topic string, data []byte) error {
+	header := make([]byte, 0, 7+len(topic))
+	header = append(header, op)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(topic)))
+	header = append(header, topic...)
+	header = binary.BigEndian.AppendUint32(header, uint32(len(data)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+func readBrokerFrame(r *bufio.Reader) (op byte, topic string, data []byte, err error) {
+	var header [3]byte
+	if _, err = io.ReadFull(r, header[:]); err != nil {
+		return
+	}
+	op = header[0]
+	topicBytes := make([]byte, binary.BigEndian.Uint16(header[1:]))
+	if _, err = io.ReadFull(r, topicBytes); err != nil {
+		return
+	}
+	var size [4]byte
+	if _, err = io.ReadFull(r, size[:]); err != nil {
+		return
+	}
+	data = make([]byte, binary.BigEndian.Uint32(size[:]))
+	_, err = io.ReadFull(r, data)
+	return op, string(topicBytes), data, err
+}
+
+// brokerConn is a connection to the broker served by ServeBroker.
+type brokerConn struct {
+	conn  net.Conn
+	queue chan brokerMessage
+}
+
+type brokerMessage struct {
+	topic string
+	data  []byte
+}
+
+// ServeBroker serves a Broker on the listener, until the context is canceled, for the instances of the same
+// machine (or network): they connect to it with DialBroker.
+func ServeBroker(ctx context.Context, ln net.Listener) error {
+	var mu sync.Mutex
+	subscribers := make(map[string]map[*brokerConn]bool)
+	go func() {
+		<-ctx.Done()
+		_ = ln.Close()
+	}()
+	klog.Infof("ServeBroker: Listening on %s", ln.Addr())
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("broker failed to accept connection: %w", err)
+		}
+		bc := &brokerConn{conn: conn, queue: make(chan brokerMessage, brokerQueueSize)}
+		go func() {
+			w := bufio.NewWriter(conn)
+			for msg := range bc.queue {
+				if err := writeBrokerFrame(w, brokerPublish, msg.topic, msg.data); err != nil {
+					break
+				}
+				if len(bc.queue) == 0 && w.Flush() != nil {
+					break
+				}
+			}
+			_ = conn.Close()
+		}()
+		go func() {
+			defer func() {
+				mu.Lock()
+				for topic, conns := range subscribers {
+					delete(conns, bc)
+					if len(conns) == 0 {
+						delete(subscribers, topic)
+					}
+				}
+				close(bc.queue)
+				mu.Unlock()
+			}()
+			r := bufio.NewReader(conn)
+			for {
+				op, topic, data, err := readBrokerFrame(r)
+				if err != nil {
+					if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+						klog.Warningf("ServeBroker: Dropping connection from %s: %v", conn.RemoteAddr(), err)
+					}
+					return
+				}
+				mu.Lock()
+				switch op {
+				case brokerSubscribe:
+					if subscribers[topic] == nil {
+						subscribers[topic] = make(map[*brokerConn]bool)
+					}
+					subscribers[topic][bc] = true
+				case brokerUnsubscribe:
+					delete(subscribers[topic], bc)
+					if len(subscribers[topic]) == 0 {
+						delete(subscribers, topic)
+					}
+				case brokerPublish:
+					for sub := range subscribers[topic] {
+						select {
+						case sub.queue <- brokerMessage{topic: topic, data: data}:
+						default:
+							klog.Warningf("ServeBroker: Connection from %s fell behind, dropping it", sub.conn.RemoteAddr())
+							_ = sub.conn.Close()
+						}
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+}
+
+// BrokerClient is a Broker connected to the one served by ServeBroker.
+type BrokerClient struct {
+	conn net.Conn
+	subs subscriptions
+
+	wmu sync.Mutex
+	w   *bufio.Writer
+}
+
+var _ Broker = (*BrokerClient)(nil)
+
+// DialBroker connects to the broker served by ServeBroker at addr.
+func DialBroker(addr string) (*BrokerClient, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the broker: %w", err)
+	}
+	c := &BrokerClient{conn: conn, w: bufio.NewWriter(conn)}
+	go c.readLoop()
+	return c, nil
+}
+
+// Close disconnects from the broker.
+func (c *BrokerClient) Close() error {
+	return c.conn.Close()
+}
+
+func (c *BrokerClient) readLoop() {
+	r := bufio.NewReader(c.conn)
+	for {
+		op, topic, data, err := readBrokerFrame(r)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				klog.Errorf("BrokerClient: Lost the connection to the broker: %v", err)
+			}
+			return
+		}
+		if op == brokerPublish {
+			c.subs.deliver(topic, data)
+		}
+	}
+}
+
+func (c *BrokerClient) write(op byte, topic string, data []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if err := writeBrokerFrame(c.w, op, topic, data); err != nil {
+		return err
+	}
+	return c.w.Flush()
+}
+
+// Publish implements Broker.
+func (c *BrokerClient) Publish(topic string, data []byte) error {
+	return c.write(brokerPublish, topic, data)
+}
+
+// Subscribe implements Broker.
+func (c *BrokerClient) Subscribe(topic string, handle func([]byte)) (func(), error) {
+	id, first := c.subs.add(topic, handle)
+	if first {
+		if err := c.write(brokerSubscribe, topic, nil); err != nil {
+			c.subs.remove(topic, id)
+			return nil, err
+		}
+	}
+	return func() {
+		if c.subs.remove(topic, id) {
+			_ = c.write(brokerUnsubscribe, topic, nil)
+		}
+	}, nil
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestServeBroker(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- ServeBroker(ctx, ln) }()
+
+	subscriber, err := DialBroker(ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer subscriber.Close()
+	publisher, err := DialBroker(ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer publisher.Close()
+
+	const numMessages = 100
+	received := make(chan string, 2*numMessages)
+	unsubscribe, err := subscriber.Subscribe("topic", func(data []byte) { received <- string(data) })
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	// Once its own message comes back, the subscription reached the broker.
+	if err := subscriber.Publish("topic", []byte("ready")); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	receive := func() string {
+		select {
+		case msg := <-received:
+			return msg
+		case <-ctx.Done():
+			t.Fatalf("Timed out waiting for a message")
+			return ""
+		}
+	}
+	if msg := receive(); msg != "ready" {
+		t.Fatalf("Expected the ready message, got %q", msg)
+	}
+
+	// Messages of a publisher arrive in order, and other topics are not delivered.
+	for i := range numMessages {
+		if err := publisher.Publish("other", []byte("ignored")); err != nil {
+			t.Fatalf("Failed to publish: %v", err)
+		}
+		if err := publisher.Publish("topic", fmt.Appendf(nil, "%d", i)); err != nil {
+			t.Fatalf("Failed to publish: %v", err)
+		}
+	}
+	for i := range numMessages {
+		if msg, want := receive(), fmt.Sprint(i); msg != want {
+			t.Fatalf("Expected message %q, got %q", want, msg)
+		}
+	}
+
+	// Once unsubscribed, nothing else is delivered.
+	unsubscribe()
+	if err := publisher.Publish("topic", []byte("late")); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if len(received) != 0 {
+		t.Errorf("Expected no messages after unsubscribing, got %q", <-received)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Broker failed: %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/janpfeifer/GoSpot/internal/game"
+	"k8s.io/klog/v2"
+)
+
+// Leaderboard keeps the best solo games, ranked by the time the server measured, see game.LeaderboardKey.
+//
+// For each leaderboard it keeps the game.LeaderboardSize best games of all time, and all the games
+// of the current week, so the daily and weekly leaderboards can be computed.
+type Leaderboard struct {
+	mu      sync.Mutex
+	entries map[game.LeaderboardKey][]game.LeaderboardEntry // Sorted by Duration.
+	path    string                                          // If not empty, the entries are saved in this JSON file.
+}
+
+// NewMemoryLeaderboard creates an empty Leaderboard that is only kept in memory.
+func NewMemoryLeaderboard() *Leaderboard {
+	return &Leaderboard{entries: make(map[game.LeaderboardKey][]game.LeaderboardEntry)}
+}
+
+// NewLeaderboard creates a Leaderboard saved in path, and loads the entries already saved there, if any.
+// If path is empty, the entries are only kept in memory.
+func NewLeaderboard(path string) (*Leaderboard, error) {
+	l := NewMemoryLeaderboard()
+	l.path = path
+	if path == "" {
+		return l, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		klog.Infof("NewLeaderboard: %s doesn't exist yet, starting with an empty leaderboard", path)
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read leaderboard file: %w", err)
+	}
+	var entries []game.LeaderboardEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse leaderboard file %s: %w", path, err)
+	}
+	for _, entry := range entries {
+		l.entries[entry.LeaderboardKey] = append(l.entries[entry.LeaderboardKey], entry)
+	}
+	for key := range l.entries {
+		slices.SortStableFunc(l.entries[key], compareEntries)
+	}
+	klog.Infof("NewLeaderboard: Loaded %d solo games from %s", len(entries), path)
+	return l, nil
+}
+
+// compareEntries ranks the faster games first, and the oldest first among equally fast ones.
+func compareEntries(a, b game.LeaderboardEntry) int {
+	return cmp.Or(cmp.Compare(a.Duration, b.Duration), a.EndTime.Compare(b.EndTime))
+}
+
+// Add a solo game to its leaderboard, and drop the games no longer needed.
+func (l *Leaderboard) Add(entry game.LeaderboardEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries := l.entries[entry.LeaderboardKey]
+	idx, _ := slices.BinarySearchFunc(entries, entry, compareEntries)
+	entries = slices.Insert(entries, idx, entry)
+
+	// Keep the best of all time, and all games of this week.
+	weekStart := game.PeriodWeekly.Since(entry.EndTime)
+	var kept []game.LeaderboardEntry
+	for i, e := range entries {
+		if i < game.LeaderboardSize || !e.EndTime.Before(weekStart) {
+			kept = append(kept, e)
+		}
+	}
+	l.entries[entry.LeaderboardKey] = kept
+	return l.saveLocked()
+}
+
+// Top returns the best games of the leaderboard played within the period that includes now,
+// at most one per player (their best).
+func (l *Leaderboard) Top(key game.LeaderboardKey, period game.LeaderboardPeriod, now time.Time) []game.LeaderboardEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	since := period.Since(now)
+	seen := make(map[string]bool)
+	var top []game.LeaderboardEntry
+	for _, e := range l.entries[key] {
+		if e.EndTime.Before(since) || seen[e.PlayerID] {
+			continue
+		}
+		seen[e.PlayerID] = true
+		top = append(top, e)
+		if len(top) == game.LeaderboardSize {
+			break
+		}
+	}
+	return top
+}
+
+// saveLocked writes all the entries to the file, if any. Assumes l.mu is locked.
+func (l *Leaderboard) saveLocked() error {
+	if l.path == "" {
+		return nil
+	}
+	var entries []game.LeaderboardEntry
+	for _, e := range l.entries {
+		entries = append(entries, e...)
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode leaderboard: %w", err)
+	}
+	return writeFileAtomic(l.path, data)
+}
+
+// soloEntryLocked returns the leaderboard entry of the table's game, if it was a ranked solo game
+// (see game.LeaderboardKeyFor) that the player finished. It returns nil otherwise.
+// The time is the one measured by the server, from the start of the game until the last match.
+// Assumes the table is locked.
+func soloEntryLocked(table *game.Table) *game.LeaderboardEntry {
+	if len(table.Players) != 1 {
+		return nil
+	}
+	p := table.Players[0]
+	if p.Bot != "" || !p.Finished || p.TimeTaken <= 0 || p.ID != table.WinnerID {
+		return nil
+	}
+	key, ok := game.LeaderboardKeyFor(table.Settings, p.CardsDealt)
+	if !ok {
+		return nil
+	}
+	return &game.LeaderboardEntry{
+		LeaderboardKey: key,
+		PlayerID:       p.ID,
+		Name:           p.Name,
+		Symbol:         p.Symbol,
+		Duration:       p.TimeTaken,
+		EndTime:        table.StartTime.Add(p.TimeTaken),
+		Seed:           table.Seed,
+	}
+}
+
+// HandleLeaderboard serves the best solo games in JSON, at /api/leaderboard.
+// The leaderboard is selected with the query parameters mode, deck_order, hand_size and period.
+func (s *ServerState) HandleLeaderboard(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	key := game.LeaderboardKey{Mode: game.GameModeID(q.Get("mode"))}
+	var err error
+	if key.DeckOrder, err = strconv.Atoi(q.Get("deck_order")); err != nil {
+		http.Error(w, "invalid deck_order", http.StatusBadRequest)
+		return
+	}
+	if key.HandSize, err = strconv.Atoi(q.Get("hand_size")); err != nil {
+		http.Error(w, "invalid hand_size", http.StatusBadRequest)
+		return
+	}
+	period := game.LeaderboardPeriod(q.Get("period"))
+	if !slices.Contains(game.LeaderboardPeriods, period) {
+		http.Error(w, "invalid period", http.StatusBadRequest)
+		return
+	}
+
+	top := s.Leaderboard.Top(key, period, time.Now())
+	if top == nil {
+		top = []game.LeaderboardEntry{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(top); err != nil {
+		klog.Errorf("HandleLeaderboard: Failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"path/filepath"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/janpfeifer/GoSpot/internal/game"
+)
+
+func TestLeaderboard(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leaderboard.json")
+	l, err := NewLeaderboard(path)
+	if err != nil {
+		t.Fatalf("Failed to create leaderboard: %v", err)
+	}
+	key := game.LeaderboardKey{Mode: game.ModeTower, DeckOrder: 7, HandSize: 10}
+	now := time.Date(2024, 5, 16, 15, 30, 0, 0, time.UTC) // A Thursday.
+	add := func(playerID string, duration time.Duration, endTime time.Time) {
+		t.Helper()
+		err := l.Add(game.LeaderboardEntry{LeaderboardKey: key, PlayerID: playerID, Name: playerID, Duration: duration, EndTime: endTime})
+		if err != nil {
+			t.Fatalf("Failed to add entry: %v", err)
+		}
+	}
+	add("old", 10*time.Second, now.AddDate(0, -1, 0))
+	add("monday", 20*time.Second, now.AddDate(0, 0, -3))
+	add("today", 40*time.Second, now.Add(-time.Hour))
+	add("today", 30*time.Second, now.Add(-time.Minute)) // Improves their own time.
+	add("other", 30*time.Second, now.Add(-time.Second)) // Same time, later.
+
+	// Reload from the file.
+	l, err = NewLeaderboard(path)
+	if err != nil {
+		t.Fatalf("Failed to reload leaderboard: %v", err)
+	}
+	tests := map[game.LeaderboardPeriod][]string{
+		game.PeriodDaily:   {"today", "other"},
+		game.PeriodWeekly:  {"monday", "today", "other"},
+		game.PeriodAllTime: {"old", "monday", "today", "other"},
+	}
+	for period, want := range tests {
+		top := l.Top(key, period, now)
+		var got []string
+		for _, e := range top {
+			got = append(got, e.PlayerID)
+		}
+		if len(got) != len(want) {
+			t.Errorf("%s: expected %v, got %v", period, want, got)
+			continue
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s: expected %v, got %v", period, want, got)
+				break
+			}
+		}
+	}
+	if top := l.Top(key, game.PeriodDaily, now); top[0].Duration != 30*time.Second {
+		t.Errorf("Expected the best time of each player, got %v", top[0].Duration)
+	}
+	if top := l.Top(game.LeaderboardKey{Mode: game.ModeWell, DeckOrder: 7, HandSize: 10}, game.PeriodAllTime, now); len(top) != 0 {
+		t.Errorf("Expected no games for The Well, got %v", top)
+	}
+}
+
+func TestSoloGameRanked(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		s := NewServerState()
+		tableID := "Solo-test"
+		alice := &game.Player{ID: "p1", Name: "Alice", Symbol: 1}
+		table := &game.Table{
+			ID:       tableID,
+			Name:     tableID,
+			Players:  []*game.Player{alice},
+			Settings: game.DefaultTableSettings(),
+		}
+		s.addTable(table, nil)
+
+		testLock(t, s, table)
+		s.handleGameStart(table, alice, &game.StartMessage{})
+		testUnlock(s, table)
+		for !table.Finished {
+			time.Sleep(time.Second)
+			testLock(t, s, table)
+			s.handleClick(table, alice, &game.ClickMessage{Symbol: botMatch(table, alice), Round: table.Round})
+			testUnlock(s, table)
+			synctest.Wait()
+		}
+
+		key := game.LeaderboardKey{Mode: game.ModeTower, DeckOrder: 7, HandSize: game.SoloCardsPerPlayer}
+		top := s.Leaderboard.Top(key, game.PeriodDaily, time.Now())
+		if len(top) != 1 || top[0].PlayerID != alice.ID {
+			t.Fatalf("Expected the solo game to be ranked, got %v", top)
+		}
+		if top[0].Duration != alice.TimeTaken || top[0].Duration != time.Duration(alice.Matches)*time.Second {
+			t.Errorf("Expected the time measured by the server (%v), got %v", alice.TimeTaken, top[0].Duration)
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"cmp"
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	"github.com/janpfeifer/GoSpot/internal/game"
+	"k8s.io/klog/v2"
+)
+
+// handleVisibility makes the table public (listed in the open tables) or private: only the creator can do it.
+// Solo tables are always private.
+// It is called from the locked tableHandleMessage function.
+func (s *ServerState) handleVisibility(table *game.Table, player *game.Player, msg *game.VisibilityMessage) {
+	if len(table.Players) == 0 || table.Players[0].ID != player.ID {
+		klog.Errorf("handleVisibility: Only creator can change the visibility of table %s", table.ID)
+		return
+	}
+	if msg.Public && game.IsSoloTable(table.ID) {
+		klog.Errorf("handleVisibility: Solo table %s can't be made public", table.ID)
+		return
+	}
+	klog.Infof("handleVisibility: Table %s public=%t", table.ID, msg.Public)
+	table.Public = msg.Public
+	s.broadcastStateLocked(table)
+}
+
+// OpenTables returns the summaries of the public tables, excluding solo tables and the ones whose game is over.
+// Tables whose game hasn't started come first, since they can be joined, and then they are sorted by name.
+func (s *ServerState) OpenTables() []game.TableSummary {
+	s.mu.RLock()
+	tables := make([]*game.Table, 0, len(s.Tables))
+	for _, table := range s.Tables {
+		tables = append(tables, table)
+	}
+	s.mu.RUnlock()
+
+	summaries := []game.TableSummary{}
+	for _, table := range tables {
+		room := s.lockTable(table)
+		if room == nil {
+			continue
+		}
+		if table.Public && !table.Finished && !game.IsSoloTable(table.ID) {
+			summaries = append(summaries, table.Summary())
+		}
+		room.mu.Unlock()
+	}
+	slices.SortFunc(summaries, func(a, b game.TableSummary) int {
+		if a.Started != b.Started {
+			if a.Started {
+				return 1
+			}
+			return -1
+		}
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.ID, b.ID))
+	})
+	return summaries
+}
+
+// HandleOpenTables serves the list of open tables in JSON, at /api/tables.
+func (s *ServerState) HandleOpenTables(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.OpenTables()); err != nil {
+		klog.Errorf("HandleOpenTables: Failed to write response: %v", err)
+	}
+}
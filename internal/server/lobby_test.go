@@ -0,0 +1,64 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/janpfeifer/GoSpot/internal/game"
+)
+
+func TestOpenTables(t *testing.T) {
+	s := NewServerState()
+	addTable := func(id string, players int) *game.Table {
+		table := &game.Table{ID: id, Name: id, Settings: game.DefaultTableSettings()}
+		for i := range players {
+			table.Players = append(table.Players, &game.Player{ID: id + string(rune('a'+i)), Name: "Player"})
+		}
+		s.addTable(table, nil)
+		return table
+	}
+	waiting := addTable("Waiting", 2)
+	playing := addTable("Playing", 3)
+	private := addTable("Private", 1)
+	solo := addTable(game.SoloTablePrefix+"1", 1)
+	over := addTable("Over", 2)
+
+	for _, table := range []*game.Table{waiting, playing, solo, over} {
+		testLock(t, s, table)
+		s.handleVisibility(table, table.Players[0], &game.VisibilityMessage{Public: true})
+		testUnlock(s, table)
+	}
+	testLock(t, s, private)
+	s.handleVisibility(private, &game.Player{ID: "someone-else"}, &game.VisibilityMessage{Public: true})
+	testUnlock(s, private)
+	playing.Started = true
+	over.Started, over.Finished = true, true
+	if private.Public {
+		t.Errorf("Expected only the creator to be able to make a table public")
+	}
+	if solo.Public {
+		t.Errorf("Expected solo tables to never be public")
+	}
+
+	rec := httptest.NewRecorder()
+	s.HandleOpenTables(rec, httptest.NewRequest(http.MethodGet, "/api/tables", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status OK, got %d", rec.Code)
+	}
+	var tables []game.TableSummary
+	if err := json.NewDecoder(rec.Body).Decode(&tables); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(tables) != 2 || tables[0].ID != "Waiting" || tables[1].ID != "Playing" {
+		t.Fatalf("Expected the waiting and then the playing tables, got %+v", tables)
+	}
+	want := game.TableSummary{ID: "Waiting", Name: "Waiting", Mode: game.ModeTower, Players: 2, MaxPlayers: 10}
+	if tables[0] != want {
+		t.Errorf("Expected %+v, got %+v", want, tables[0])
+	}
+	if !tables[1].Started || tables[1].Players != 3 {
+		t.Errorf("Expected the playing table to be started with 3 players, got %+v", tables[1])
+	}
+}
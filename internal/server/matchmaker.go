@@ -0,0 +1,345 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/janpfeifer/GoSpot/internal/game"
+	"k8s.io/klog/v2"
+)
+
+// Matchmaker keeps the quick match queue: players waiting to be grouped into a new table.
+//
+// Players are matched as soon as TargetSize of them (with similar latencies, if LatencySpread is set) are waiting,
+// or once the oldest one has waited for Timeout, with whoever is in the queue, if there are at least MinSize.
+type Matchmaker struct {
+	TargetSize int           // Number of players of a full match
+	MinSize    int           // Minimum number of players of a match, made after Timeout
+	Timeout    time.Duration // How long to wait for a full match
+
+	// LatencySpread, if not zero, is the maximum difference of the measured latencies of the players of
+	// a full match. It's not enforced for the smaller matches made after Timeout.
+	LatencySpread time.Duration
+
+	// Interval between matchmaking rounds, when the queued players are also sent their status.
+	Interval time.Duration
+
+	// JoinTimeout is how long matched players have to join their table, before the game starts without them.
+	JoinTimeout time.Duration
+
+	mu    sync.Mutex
+	queue []*queueEntry // Oldest first.
+}
+
+// queueEntry is a player waiting in the quick match queue.
+type queueEntry struct {
+	player  game.Player
+	out     *outbox // Queue of messages to the player's connection.
+	joined  time.Time
+	latency time.Duration // Measured one-way latency, only valid if measured is true.
+
+	measured bool
+}
+
+// NewMatchmaker creates a Matchmaker with the default parameters.
+func NewMatchmaker() *Matchmaker {
+	return &Matchmaker{
+		TargetSize:  4,
+		MinSize:     2,
+		Timeout:     30 * time.Second,
+		Interval:    time.Second,
+		JoinTimeout: 15 * time.Second,
+	}
+}
+
+// add puts the player in the queue, or replaces their previous entry if they were already queued.
+func (m *Matchmaker) add(player game.Player, out *outbox, now time.Time) *queueEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry := &queueEntry{player: player, out: out, joined: now}
+	for i, e := range m.queue {
+		if e.player.ID == player.ID {
+			klog.Infof("Matchmaker: Player %s queued again, dropping the old connection", player.Name)
+			e.out.closeAfter(websocket.StatusNormalClosure, "Queued from another connection")
+			m.queue[i] = entry
+			return entry
+		}
+	}
+	m.queue = append(m.queue, entry)
+	return entry
+}
+
+// remove takes the entry out of the queue, if it's still there.
+func (m *Matchmaker) remove(entry *queueEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, e := range m.queue {
+		if e == entry {
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// setLatency records the measured latency of the queued player.
+func (m *Matchmaker) setLatency(entry *queueEntry, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry.latency = latency
+	entry.measured = true
+}
+
+// compatibleLocked returns whether the two players can be grouped in a full match.
+// Players whose latency hasn't been measured yet are compatible with everyone.
+// Assumes m.mu is locked.
+func (m *Matchmaker) compatibleLocked(a, b *queueEntry) bool {
+	if m.LatencySpread == 0 || !a.measured || !b.measured {
+		return true
+	}
+	return max(a.latency-b.latency, b.latency-a.latency) <= m.LatencySpread
+}
+
+// matchLocked takes the groups of players that can be matched now out of the queue.
+// Each group is built around the oldest player left in the queue.
+// Assumes m.mu is locked.
+func (m *Matchmaker) matchLocked(now time.Time) [][]*queueEntry {
+	var groups [][]*queueEntry
+	for i := 0; i < len(m.queue); {
+		oldest := m.queue[i]
+		var group []*queueEntry
+		if now.Sub(oldest.joined) >= m.Timeout {
+			// Waited long enough: match with anyone.
+			group = m.queue[i:min(len(m.queue), i+m.TargetSize)]
+			if len(group) < m.MinSize {
+				group = nil
+			}
+		} else {
+			for _, e := range m.queue[i:] {
+				if m.compatibleLocked(oldest, e) {
+					group = append(group, e)
+					if len(group) == m.TargetSize {
+						break
+					}
+				}
+			}
+			if len(group) < m.TargetSize {
+				group = nil
+			}
+		}
+		if group == nil {
+			i++
+			continue
+		}
+		group = append([]*queueEntry(nil), group...)
+		groups = append(groups, group)
+		inGroup := make(map[*queueEntry]bool, len(group))
+		for _, e := range group {
+			inGroup[e] = true
+		}
+		remaining := m.queue[:i:i]
+		for _, e := range m.queue[i:] {
+			if !inGroup[e] {
+				remaining = append(remaining, e)
+			}
+		}
+		m.queue = remaining
+	}
+	return groups
+}
+
+// sendStatusLocked sends each queued player their position in the queue.
+// Assumes m.mu is locked.
+func (m *Matchmaker) sendStatusLocked(now time.Time) {
+	for i, e := range m.queue {
+		statusMsg, err := game.NewWsMessage(game.MsgTypeQueueStatus, game.QueueStatusMessage{
+			Position:   i + 1,
+			Waiting:    len(m.queue),
+			TargetSize: m.TargetSize,
+			Waited:     now.Sub(e.joined),
+			Timeout:    m.Timeout,
+		})
+		if err != nil {
+			klog.Errorf("Matchmaker: Failed to create queue status message: %v", err)
+			return
+		}
+		e.out.sendJSON(statusMsg)
+	}
+}
+
+// handleQueue keeps the connection in the quick match queue, until the player is matched or leaves.
+// Messages to the player are sent through out.
+func (s *ServerState) handleQueue(ctx context.Context, conn *websocket.Conn, out *outbox, player game.Player) {
+	m := s.Matchmaker
+	klog.Infof("handleQueue: Player %s (%s) joined the quick match queue", player.Name, player.ID)
+	entry := m.add(player, out, time.Now())
+	defer m.remove(entry)
+
+	// Measure the latency, so players can be grouped by it.
+	pingMsg, _ := game.NewWsMessage(game.MsgTypePing, game.PingMessage{
+		ServerTime: time.Now().UnixNano(),
+	})
+	out.sendJSON(pingMsg)
+	m.mu.Lock()
+	m.sendStatusLocked(time.Now())
+	m.mu.Unlock()
+
+	for {
+		wsMsg, err := readMessage(ctx, conn)
+		if websocket.CloseStatus(err) == websocket.StatusNormalClosure || websocket.CloseStatus(err) == websocket.StatusGoingAway {
+			klog.Infof("handleQueue: Player %s left the queue", player.Name)
+			return
+		}
+		if err != nil {
+			klog.Infof("handleQueue: Connection of player %s closed: %v", player.Name, err)
+			return
+		}
+		msgAny, err := wsMsg.Parse()
+		if wsMsg.Ignorable(err) {
+			continue
+		}
+		if err != nil {
+			klog.Errorf("handleQueue: Failed to parse message: %v", err)
+			continue
+		}
+		if pong, ok := msgAny.(*game.PongMessage); ok {
+			latency := time.Duration((time.Now().UnixNano() - pong.ServerTime) / 2)
+			klog.Infof("handleQueue: Player %s latency: %v", player.Name, latency)
+			m.setLatency(entry, latency)
+		}
+	}
+}
+
+// runMatchmaker matches the queued players every Matchmaker.Interval, until the context is cancelled.
+func (s *ServerState) runMatchmaker(ctx context.Context) {
+	m := s.Matchmaker
+	ticker := time.NewTicker(m.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		now := time.Now()
+		m.mu.Lock()
+		groups := m.matchLocked(now)
+		m.sendStatusLocked(now)
+		m.mu.Unlock()
+		for _, group := range groups {
+			s.createMatch(group)
+		}
+	}
+}
+
+// createMatch creates a quick match table for the group, and sends each player the table to join.
+func (s *ServerState) createMatch(group []*queueEntry) {
+	s.mu.RLock()
+	tableID := game.RandomTableName()
+	for i := 2; s.Tables[tableID] != nil; i++ {
+		tableID = fmt.Sprintf("%s %d", game.RandomTableName(), i)
+	}
+	s.mu.RUnlock()
+
+	// Locked before it's registered, so the players can't join it before it's set up.
+	mu := &sync.Mutex{}
+	mu.Lock()
+	defer mu.Unlock()
+	players := make([]game.Player, 0, len(group))
+	var names []string
+	for _, e := range group {
+		player := e.player
+		player.Latency = e.latency
+		players = append(players, player)
+		names = append(names, player.Name)
+	}
+	s.newSeatedTableLocked(tableID, game.DefaultTableSettings(), players, mu)
+	klog.Infof("createMatch: Matched %v on table %s", names, tableID)
+
+	for _, e := range group {
+		statusMsg, err := game.NewWsMessage(game.MsgTypeQueueStatus, game.QueueStatusMessage{
+			TargetSize: s.Matchmaker.TargetSize,
+			TableID:    tableID,
+		})
+		if err != nil {
+			klog.Errorf("createMatch: Failed to create queue status message: %v", err)
+			continue
+		}
+		e.out.sendJSON(statusMsg)
+		e.out.closeAfter(websocket.StatusNormalClosure, "Matched")
+	}
+}
+
+// newSeatedTableLocked creates a table with the players already seated, for the tables created by the server:
+// quick matches and the rounds of tournaments. Human players are seated as disconnected: the game starts once
+// all of them join, see autoStartLocked, or once the ones that didn't join in Matchmaker.JoinTimeout are removed.
+// The table is registered with the lock mu, which must be held by the caller.
+func (s *ServerState) newSeatedTableLocked(tableID string, settings game.TableSettings, players []game.Player, mu *sync.Mutex) *game.Table {
+	table := &game.Table{
+		ID:        tableID,
+		Name:      tableID,
+		Players:   make([]*game.Player, 0, len(players)),
+		Settings:  settings,
+		AutoStart: true,
+	}
+	if old := s.addTable(table, mu); old != nil {
+		// Its pending timers find it's no longer registered, and do nothing.
+		klog.Warningf("newSeatedTableLocked: Replaced existing table %s", tableID)
+	}
+
+	// Players choose their symbol, but they must be different at the table.
+	usedSymbols := make(map[int]bool)
+	for _, p := range players {
+		player := &game.Player{
+			ID:      p.ID,
+			Name:    p.Name,
+			Symbol:  p.Symbol,
+			Latency: p.Latency,
+			Bot:     p.Bot,
+		}
+		if usedSymbols[player.Symbol] {
+			for _, sym := range rand.Perm(table.Settings.DeckSize()) {
+				if !usedSymbols[sym] {
+					player.Symbol = sym
+					break
+				}
+			}
+		}
+		usedSymbols[player.Symbol] = true
+		if player.Bot == "" {
+			player.Disconnected = true
+			player.DisconnectTimer = time.AfterFunc(s.Matchmaker.JoinTimeout, func() {
+				s.expireDisconnected(table, player)
+			})
+		}
+		table.Players = append(table.Players, player)
+	}
+	ensureHumanCreatorLocked(table)
+	return table
+}
+
+// autoStartLocked starts the game of a table created by the server once all of its players have joined.
+// Assumes the table is locked.
+func (s *ServerState) autoStartLocked(table *game.Table) {
+	if !table.AutoStart || table.Started {
+		return
+	}
+	for _, p := range table.Players {
+		if p.Disconnected {
+			return
+		}
+	}
+	if len(table.Players) < s.Matchmaker.MinSize {
+		if len(table.Players) == 1 && table.Tournament != nil {
+			// Nobody else at the table of the tournament showed up: the remaining player advances.
+			klog.Infof("autoStartLocked: %s advances by walkover on table %s", table.Players[0].Name, table.ID)
+			s.recordTournamentLocked(table, []string{table.Players[0].ID})
+		}
+		return
+	}
+	klog.Infof("autoStartLocked: All players joined table %s, starting", table.ID)
+	s.handleGameStart(table, table.Players[0], &game.StartMessage{})
+}
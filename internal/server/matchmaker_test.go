@@ -0,0 +1,241 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/janpfeifer/GoSpot/internal/game"
+)
+
+func TestMatchmakerGroups(t *testing.T) {
+	now := time.Now()
+	m := NewMatchmaker()
+	m.TargetSize = 3
+	m.LatencySpread = 50 * time.Millisecond
+	newEntry := func(id string, waited, latency time.Duration) *queueEntry {
+		return &queueEntry{player: game.Player{ID: id}, joined: now.Add(-waited), latency: latency, measured: true}
+	}
+	ids := func(group []*queueEntry) (ids []string) {
+		for _, e := range group {
+			ids = append(ids, e.player.ID)
+		}
+		return ids
+	}
+
+	// Full matches are grouped by latency, around the oldest player.
+	m.queue = []*queueEntry{
+		newEntry("slow1", 10*time.Second, 300*time.Millisecond),
+		newEntry("fast1", 9*time.Second, 20*time.Millisecond),
+		newEntry("fast2", 8*time.Second, 40*time.Millisecond),
+		newEntry("slow2", 7*time.Second, 320*time.Millisecond),
+		newEntry("fast3", 6*time.Second, 60*time.Millisecond),
+	}
+	groups := m.matchLocked(now)
+	if len(groups) != 1 || fmt.Sprint(ids(groups[0])) != "[fast1 fast2 fast3]" {
+		t.Fatalf("Expected the fast players to be matched, got %v", groups)
+	}
+	if fmt.Sprint(ids(m.queue)) != "[slow1 slow2]" {
+		t.Errorf("Expected the slow players to remain queued, got %v", ids(m.queue))
+	}
+
+	// After the timeout, players are matched with anyone, even if the match is not full.
+	groups = m.matchLocked(now.Add(m.Timeout))
+	if len(groups) != 1 || fmt.Sprint(ids(groups[0])) != "[slow1 slow2]" {
+		t.Fatalf("Expected the slow players to be matched after the timeout, got %v", groups)
+	}
+	if len(m.queue) != 0 {
+		t.Errorf("Expected the queue to be empty, got %v", ids(m.queue))
+	}
+
+	// A player alone keeps waiting.
+	m.queue = []*queueEntry{newEntry("alone", time.Hour, 0)}
+	if groups = m.matchLocked(now); len(groups) != 0 || len(m.queue) != 1 {
+		t.Errorf("Expected a lonely player to keep waiting, got groups %v", groups)
+	}
+}
+
+// testQueue connects to the server and joins the quick match queue. It returns the ID of the table matched, and
+// the queue statuses received before it.
+func testQueue(ctx context.Context, serverState *ServerState, wsURL string, player game.Player) (string, []game.QueueStatusMessage, error) {
+	opts := &websocket.DialOptions{
+		HTTPClient: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+					return serverState.LocalDial()
+				},
+				DisableKeepAlives: true,
+			},
+		},
+	}
+	conn, _, err := websocket.Dial(ctx, wsURL, opts)
+	if err != nil {
+		return "", nil, fmt.Errorf("dial error: %w", err)
+	}
+	defer conn.CloseNow()
+	queueMsg, _ := game.NewWsMessage(game.MsgTypeQueue, game.QueueMessage{Player: player})
+	if err := wsjson.Write(ctx, conn, queueMsg); err != nil {
+		return "", nil, fmt.Errorf("failed to write QueueMessage: %w", err)
+	}
+
+	var statuses []game.QueueStatusMessage
+	for {
+		var wsMsg game.WsMessage
+		if err := wsjson.Read(ctx, conn, &wsMsg); err != nil {
+			return "", statuses, fmt.Errorf("failed to read message: %w", err)
+		}
+		msgAny, err := wsMsg.Parse()
+		if err != nil {
+			return "", statuses, err
+		}
+		switch msg := msgAny.(type) {
+		case *game.PingMessage:
+			pongMsg, _ := game.NewWsMessage(game.MsgTypePong, game.PongMessage{ServerTime: msg.ServerTime})
+			_ = wsjson.Write(ctx, conn, pongMsg)
+		case *game.QueueStatusMessage:
+			if msg.TableID != "" {
+				return msg.TableID, statuses, nil
+			}
+			statuses = append(statuses, *msg)
+		}
+	}
+}
+
+func TestQuickMatch(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		started := make(chan *ServerState, 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go Run(ctx, NetPipeAddr, started)
+		s := <-started
+		wsURL := "ws://" + s.Address + "/ws"
+		s.Matchmaker.mu.Lock()
+		s.Matchmaker.TargetSize = 2
+		s.Matchmaker.mu.Unlock()
+
+		type result struct {
+			tableID  string
+			statuses []game.QueueStatusMessage
+			err      error
+		}
+		results := make(chan result, 2)
+		queue := func(id, name string) {
+			tableID, statuses, err := testQueue(ctx, s, wsURL, game.Player{ID: id, Name: name, Symbol: 7})
+			results <- result{tableID, statuses, err}
+		}
+		go queue("p1", "Alice")
+		time.Sleep(2 * time.Second)
+		go queue("p2", "Bob")
+		time.Sleep(2 * time.Second)
+		synctest.Wait()
+
+		var tableID string
+		for range 2 {
+			r := <-results
+			if r.err != nil {
+				t.Fatalf("Failed to queue: %v", r.err)
+			}
+			if tableID != "" && r.tableID != tableID {
+				t.Fatalf("Expected both players to be matched on the same table, got %q and %q", tableID, r.tableID)
+			}
+			tableID = r.tableID
+			if len(r.statuses) == 0 || r.statuses[0].TargetSize != 2 {
+				t.Errorf("Expected queue statuses before being matched, got %+v", r.statuses)
+			}
+		}
+
+		table, unlock := testLockTableID(s, tableID)
+		if table == nil || !table.AutoStart || len(table.Players) != 2 || table.Started {
+			t.Fatalf("Expected a quick match table with 2 players waiting for them to join, got %v", table)
+		}
+		if table.Players[0].Symbol == table.Players[1].Symbol {
+			t.Errorf("Expected matched players to have different symbols")
+		}
+		unlock()
+
+		// The game starts once both players join.
+		for _, p := range []struct{ id, name string }{{"p1", "Alice"}, {"p2", "Bob"}} {
+			conn, err := testConnectAndJoin(ctx, s, wsURL, tableID, p.id, p.name, 7, 0)
+			if err != nil {
+				t.Fatalf("%s failed to join: %v", p.name, err)
+			}
+			defer conn.CloseNow()
+			go func() {
+				for {
+					var msg game.WsMessage
+					if err := wsjson.Read(ctx, conn, &msg); err != nil {
+						return
+					}
+				}
+			}()
+			synctest.Wait()
+			testLock(t, s, table)
+			if table.Started != (p.id == "p2") {
+				t.Errorf("After %s joined, expected started=%t", p.name, p.id == "p2")
+			}
+			testUnlock(s, table)
+		}
+	})
+}
+
+func TestQuickMatchTimeout(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		started := make(chan *ServerState, 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go Run(ctx, NetPipeAddr, started)
+		s := <-started
+		wsURL := "ws://" + s.Address + "/ws"
+
+		// Two players, short of the target size, are matched after the timeout.
+		tableIDs := make(chan string, 2)
+		for _, id := range []string{"p1", "p2"} {
+			go func() {
+				tableID, _, err := testQueue(ctx, s, wsURL, game.Player{ID: id, Name: id})
+				if err != nil {
+					t.Errorf("Failed to queue %s: %v", id, err)
+				}
+				tableIDs <- tableID
+			}()
+		}
+		time.Sleep(s.Matchmaker.Timeout - 2*s.Matchmaker.Interval)
+		synctest.Wait()
+		if len(tableIDs) != 0 {
+			t.Fatalf("Expected players to be kept waiting before the timeout")
+		}
+		time.Sleep(3 * s.Matchmaker.Interval)
+		synctest.Wait()
+		if len(tableIDs) != 2 {
+			t.Fatalf("Expected players to be matched after the timeout")
+		}
+		tableID := <-tableIDs
+
+		// Only p1 joins: once p2's time to join is over, they are removed and p1 is left alone in the lobby.
+		conn, err := testConnectAndJoin(ctx, s, wsURL, tableID, "p1", "p1", 0, 0)
+		if err != nil {
+			t.Fatalf("Failed to join: %v", err)
+		}
+		defer conn.CloseNow()
+		go func() {
+			for {
+				var msg game.WsMessage
+				if err := wsjson.Read(ctx, conn, &msg); err != nil {
+					return
+				}
+			}
+		}()
+		time.Sleep(s.Matchmaker.JoinTimeout)
+		synctest.Wait()
+		table, unlock := testLockTableID(s, tableID)
+		defer unlock()
+		if table == nil || len(table.Players) != 1 || table.Started {
+			t.Errorf("Expected p1 alone in the lobby of the table, got %v", table)
+		}
+	})
+}
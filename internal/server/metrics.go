@@ -0,0 +1,283 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/janpfeifer/GoSpot/internal/game"
+	"k8s.io/klog/v2"
+)
+
+// Buckets, in seconds, of the histograms of Metrics.
+var (
+	// DelayBuckets of the latency compensation delay given to valid clicks.
+	DelayBuckets = []float64{0, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+
+	// RTTBuckets of the round trip times measured with pings.
+	RTTBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5}
+)
+
+// Results of the clicks counted by Metrics.
+const (
+	clickValid      = "valid"      // Accepted, and scheduled to be processed after its latency compensation delay.
+	clickInvalid    = "invalid"    // Rejected, see game.RejectReason.
+	clickSuperseded = "superseded" // Valid (and counted as such), but an earlier click won the round.
+)
+
+// Reasons of the failed writes counted by Metrics.
+const (
+	writeError  = "error"  // The write failed or timed out.
+	writeBehind = "behind" // The connection fell too far behind, see outbox.
+)
+
+// Directions of the websocket messages counted by Metrics.
+const (
+	messageReceived = "received"
+	messageSent     = "sent"
+)
+
+// messageUnknown is the type the websocket messages of unknown types are counted as: the types come from the
+// clients, and counting each would let them add series without limit.
+const messageUnknown = "unknown"
+
+// Metrics of the server, served in the Prometheus text format at /metrics, see ServerState.HandleMetrics.
+//
+// All methods are safe for concurrent use, and do nothing on a nil Metrics.
+type Metrics struct {
+	connections   atomic.Int64
+	gamesStarted  atomic.Int64
+	gamesFinished atomic.Int64
+
+	mu            sync.Mutex
+	clicks        map[string]int64
+	writeFailures map[string]int64
+	messages      map[[2]string]int64 // By direction and game.MessageType.
+	delay         *histogram
+	players       map[string]*playerMetrics // By player ID, only while connected.
+}
+
+// playerMetrics are the metrics of one player, kept while they have some connection open.
+type playerMetrics struct {
+	conns int
+	rtt   *histogram
+}
+
+// NewMetrics creates Metrics with every count at 0.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		clicks:        make(map[string]int64),
+		writeFailures: make(map[string]int64),
+		messages:      make(map[[2]string]int64),
+		delay:         newHistogram(DelayBuckets),
+		players:       make(map[string]*playerMetrics),
+	}
+}
+
+// histogram counts observations in cumulative buckets, as Prometheus does. Protected by Metrics.mu.
+type histogram struct {
+	buckets []float64 // Upper bounds, in increasing order.
+	counts  []int64   // Observations in each bucket, not cumulative: the last one is for +Inf.
+	sum     float64
+	count   int64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]int64, len(buckets)+1)}
+}
+
+func (h *histogram) observe(d time.Duration) {
+	v := d.Seconds()
+	i, _ := slices.BinarySearch(h.buckets, v)
+	h.counts[i]++
+	h.sum += v
+	h.count++
+}
+
+// connected counts a new connection of the player, until disconnected is called.
+func (m *Metrics) connected(playerID string) {
+	if m == nil {
+		return
+	}
+	m.connections.Add(1)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pm := m.players[playerID]
+	if pm == nil {
+		pm = &playerMetrics{rtt: newHistogram(RTTBuckets)}
+		m.players[playerID] = pm
+	}
+	pm.conns++
+}
+
+// disconnected ends a connection counted by connected. The metrics of the player are dropped with their last one.
+func (m *Metrics) disconnected(playerID string) {
+	if m == nil {
+		return
+	}
+	m.connections.Add(-1)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if pm := m.players[playerID]; pm != nil {
+		if pm.conns--; pm.conns <= 0 {
+			delete(m.players, playerID)
+		}
+	}
+}
+
+// observeRTT records a round trip time measured for a connected player.
+func (m *Metrics) observeRTT(playerID string, rtt time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if pm := m.players[playerID]; pm != nil {
+		pm.rtt.observe(rtt)
+	}
+}
+
+// observeDelay records the latency compensation delay given to a valid click.
+func (m *Metrics) observeDelay(delay time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.delay.observe(delay)
+}
+
+func (m *Metrics) gameStarted() {
+	if m != nil {
+		m.gamesStarted.Add(1)
+	}
+}
+
+func (m *Metrics) gameFinished() {
+	if m != nil {
+		m.gamesFinished.Add(1)
+	}
+}
+
+// click counts a click with the given result: clickValid, clickInvalid or clickSuperseded.
+func (m *Metrics) click(result string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clicks[result]++
+}
+
+// writeFailed counts a connection dropped because the messages to it couldn't be written.
+func (m *Metrics) writeFailed(reason string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.writeFailures[reason]++
+}
+
+// message counts a websocket message received or sent (direction), of the given type, or as messageUnknown if the
+// type is not known.
+func (m *Metrics) message(direction string, msgType game.MessageType) {
+	if m == nil || msgType == "" {
+		return
+	}
+	typ := string(msgType)
+	if !msgType.Known() {
+		typ = messageUnknown
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages[[2]string{direction, typ}]++
+}
+
+// HandleMetrics serves the metrics of the server in the Prometheus text format, at /metrics.
+func (s *ServerState) HandleMetrics(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	tables := len(s.Tables)
+	s.mu.RUnlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := s.Metrics.write(w, tables); err != nil {
+		klog.Errorf("HandleMetrics: Failed to write response: %v", err)
+	}
+}
+
+// write the metrics in the Prometheus text format, with the number of active tables.
+func (m *Metrics) write(w io.Writer, tables int) error {
+	bw := bufio.NewWriter(w)
+	metric := func(name, typ, help string) {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	}
+
+	metric("gospot_tables_active", "gauge", "Tables currently open.")
+	fmt.Fprintf(bw, "gospot_tables_active %d\n", tables)
+	metric("gospot_connections_active", "gauge", "Websocket connections currently joined to a table.")
+	fmt.Fprintf(bw, "gospot_connections_active %d\n", m.connections.Load())
+	metric("gospot_games_started_total", "counter", "Games started.")
+	fmt.Fprintf(bw, "gospot_games_started_total %d\n", m.gamesStarted.Load())
+	metric("gospot_games_finished_total", "counter", "Games played to the end.")
+	fmt.Fprintf(bw, "gospot_games_finished_total %d\n", m.gamesFinished.Load())
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	metric("gospot_clicks_total", "counter", "Clicks on symbols, by result: valid, invalid or superseded by an earlier click.")
+	for _, result := range []string{clickValid, clickInvalid, clickSuperseded} {
+		fmt.Fprintf(bw, "gospot_clicks_total{result=%q} %d\n", result, m.clicks[result])
+	}
+	metric("gospot_click_delay_seconds", "histogram", "Latency compensation delay given to the valid clicks.")
+	writeHistogram(bw, "gospot_click_delay_seconds", "", m.delay)
+	metric("gospot_player_rtt_seconds", "histogram", "Round trip time of the connected players, measured with pings.")
+	for _, id := range slices.Sorted(maps.Keys(m.players)) {
+		writeHistogram(bw, "gospot_player_rtt_seconds", `player_id="`+escapeLabel(id)+`"`, m.players[id].rtt)
+	}
+	metric("gospot_write_failures_total", "counter", "Connections dropped because the messages to them couldn't be written, by reason.")
+	for _, reason := range []string{writeError, writeBehind} {
+		fmt.Fprintf(bw, "gospot_write_failures_total{reason=%q} %d\n", reason, m.writeFailures[reason])
+	}
+	metric("gospot_messages_total", "counter", "Websocket messages, by direction and type.")
+	keys := slices.SortedFunc(maps.Keys(m.messages), func(a, b [2]string) int {
+		return strings.Compare(a[0]+"/"+a[1], b[0]+"/"+b[1])
+	})
+	for _, key := range keys {
+		fmt.Fprintf(bw, "gospot_messages_total{direction=%q,type=%q} %d\n", key[0], key[1], m.messages[key])
+	}
+	return bw.Flush()
+}
+
+// writeHistogram writes the series of the histogram, with the given labels (if any) added to each.
+func writeHistogram(w io.Writer, name, labels string, h *histogram) {
+	sep := ""
+	if labels != "" {
+		sep = ","
+	}
+	var cumulative int64
+	for i, bound := range h.buckets {
+		cumulative += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket{%s%sle=\"%s\"} %d\n", name, labels, sep,
+			strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{%s%sle=\"+Inf\"} %d\n", name, labels, sep, h.count)
+	if labels != "" {
+		labels = "{" + labels + "}"
+	}
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, labels, h.count)
+}
+
+// labelEscaper escapes label values, as required by the Prometheus text format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/janpfeifer/GoSpot/internal/game"
+)
+
+// testMetrics returns the metrics served by the server, by series (name and labels).
+func testMetrics(t *testing.T, s *ServerState) map[string]string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.HandleMetrics(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Unexpected content type %q", ct)
+	}
+	series := make(map[string]string)
+	for line := range strings.Lines(rec.Body.String()) {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndexByte(line, ' ')
+		series[line[:i]] = strings.TrimSpace(line[i+1:])
+	}
+	return series
+}
+
+func TestMetricsHistogram(t *testing.T) {
+	m := NewMetrics()
+	m.connected(`odd"id`)
+	for _, rtt := range []time.Duration{3 * time.Millisecond, 20 * time.Millisecond, 20 * time.Millisecond, 3 * time.Second} {
+		m.observeRTT(`odd"id`, rtt)
+	}
+	m.observeRTT("unknown", time.Millisecond) // Not connected: ignored.
+	var b strings.Builder
+	if err := m.write(&b, 0); err != nil {
+		t.Fatalf("Failed to write metrics: %v", err)
+	}
+	for _, want := range []string{
+		`gospot_player_rtt_seconds_bucket{player_id="odd\"id",le="0.005"} 1`,
+		`gospot_player_rtt_seconds_bucket{player_id="odd\"id",le="0.025"} 3`,
+		`gospot_player_rtt_seconds_bucket{player_id="odd\"id",le="2.5"} 3`,
+		`gospot_player_rtt_seconds_bucket{player_id="odd\"id",le="+Inf"} 4`,
+		`gospot_player_rtt_seconds_sum{player_id="odd\"id"} 3.043`,
+		`gospot_player_rtt_seconds_count{player_id="odd\"id"} 4`,
+		`gospot_click_delay_seconds_count 0`,
+	} {
+		if !strings.Contains(b.String(), want+"\n") {
+			t.Errorf("Expected %q in the metrics:\n%s", want, b.String())
+		}
+	}
+	if strings.Contains(b.String(), "unknown") {
+		t.Errorf("Expected no metrics for players not connected:\n%s", b.String())
+	}
+
+	// The metrics of the player are dropped when they disconnect.
+	m.disconnected(`odd"id`)
+	b.Reset()
+	_ = m.write(&b, 0)
+	if strings.Contains(b.String(), "player_id") {
+		t.Errorf("Expected no player metrics once disconnected:\n%s", b.String())
+	}
+}
+
+func TestMetricsUnknownMessages(t *testing.T) {
+	m := NewMetrics()
+	m.message(messageReceived, game.MsgTypeJoin)
+	for _, msgType := range []game.MessageType{`random"1`, "random2"} {
+		m.message(messageReceived, msgType)
+	}
+	var b strings.Builder
+	if err := m.write(&b, 0); err != nil {
+		t.Fatalf("Failed to write metrics: %v", err)
+	}
+	for _, want := range []string{
+		`gospot_messages_total{direction="received",type="join"} 1`,
+		`gospot_messages_total{direction="received",type="unknown"} 2`,
+	} {
+		if !strings.Contains(b.String(), want+"\n") {
+			t.Errorf("Expected %q in the metrics:\n%s", want, b.String())
+		}
+	}
+	if strings.Contains(b.String(), "random") {
+		t.Errorf("Expected no series for the unknown message types:\n%s", b.String())
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		started := make(chan *ServerState, 1)
+		go Run(ctx, NetPipeAddr, started)
+		s := <-started
+		wsURL := "ws://" + s.Address + "/ws"
+		const tableID = "test-metrics"
+
+		alice, err := testConnectAndJoin(ctx, s, wsURL, tableID, "p1", "Alice", 0, 0)
+		if err != nil {
+			t.Fatalf("Alice failed to join: %v", err)
+		}
+		defer alice.CloseNow()
+		bob, err := testConnectAndJoin(ctx, s, wsURL, tableID, "p2", "Bob", 0, 40*time.Millisecond)
+		if err != nil {
+			t.Fatalf("Bob failed to join: %v", err)
+		}
+		defer bob.CloseNow()
+		for _, conn := range []*websocket.Conn{alice, bob} {
+			go func() {
+				for {
+					var msg game.WsMessage
+					if err := wsjson.Read(ctx, conn, &msg); err != nil {
+						return
+					}
+				}
+			}()
+		}
+
+		// A click before the game starts is invalid.
+		clickMsg, _ := game.NewWsMessage(game.MsgTypeClick, game.ClickMessage{Symbol: 1})
+		_ = wsjson.Write(ctx, bob, clickMsg)
+		startMsg, _ := game.NewWsMessage(game.MsgTypeStart, nil)
+		_ = wsjson.Write(ctx, alice, startMsg)
+		synctest.Wait()
+
+		metrics := testMetrics(t, s)
+		for series, want := range map[string]string{
+			"gospot_tables_active":                                        "1",
+			"gospot_connections_active":                                   "2",
+			"gospot_games_started_total":                                  "1",
+			"gospot_games_finished_total":                                 "0",
+			`gospot_clicks_total{result="invalid"}`:                       "1",
+			`gospot_clicks_total{result="valid"}`:                         "0",
+			`gospot_player_rtt_seconds_count{player_id="p2"}`:             "1",
+			`gospot_player_rtt_seconds_bucket{player_id="p2",le="0.05"}`:  "1",
+			`gospot_player_rtt_seconds_bucket{player_id="p2",le="0.025"}`: "0",
+			`gospot_messages_total{direction="received",type="join"}`:     "2",
+			`gospot_messages_total{direction="received",type="pong"}`:     "2",
+			`gospot_messages_total{direction="received",type="start"}`:    "1",
+			`gospot_messages_total{direction="sent",type="reject"}`:       "1",
+			`gospot_write_failures_total{reason="error"}`:                 "0",
+		} {
+			if got := metrics[series]; got != want {
+				t.Errorf("Expected %s to be %s, got %q", series, want, got)
+			}
+		}
+
+		// Bob leaving is no longer counted.
+		bob.CloseNow()
+		synctest.Wait()
+		metrics = testMetrics(t, s)
+		if got := metrics["gospot_connections_active"]; got != "1" {
+			t.Errorf("Expected 1 connection left, got %q", got)
+		}
+		if got, ok := metrics[`gospot_player_rtt_seconds_count{player_id="p2"}`]; ok {
+			t.Errorf("Expected no RTT metrics of Bob once gone, got %q", got)
+		}
+	})
+}
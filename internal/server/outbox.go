@@ -0,0 +1,183 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/janpfeifer/GoSpot/internal/game"
+	"k8s.io/klog/v2"
+)
+
+// DefaultOutboxSize is the default maximum number of messages queued to a connection, see ServerState.OutboxSize.
+const DefaultOutboxSize = 256
+
+// OutboxWriteTimeout is how long the write of a message to a connection can take, before the connection is dropped.
+var OutboxWriteTimeout = 5 * time.Second
+
+// supersededBy lists, for the message types that carry the whole of some state, the queued message types
+// they make obsolete: those not yet written are dropped.
+var supersededBy = map[game.MessageType][]game.MessageType{
+	game.MsgTypeState:       {game.MsgTypeState, game.MsgTypeStateDelta},
+	game.MsgTypeQueueStatus: {game.MsgTypeQueueStatus},
+}
+
+// outgoing is a message queued to a connection, already encoded.
+type outgoing struct {
+	msgType  game.MessageType
+	data     []byte
+	isBinary bool
+
+	// closeStatus, if set, closes the connection (with closeReason) once the previous messages are written.
+	closeStatus websocket.StatusCode
+	closeReason string
+}
+
+// outbox queues the messages to one connection, written in order by a single goroutine, so the callers never block.
+//
+// Messages made obsolete by a newer one (see supersededBy) are replaced by it while queued. If the queue still grows
+// beyond its size, the client is too far behind to catch up, and the connection is closed: it reconnects and
+// gets the full state again.
+type outbox struct {
+	conn    *websocket.Conn
+	size    int
+	metrics *Metrics // Counts the messages written, and the failures. May be nil.
+
+	mu     sync.Mutex
+	queue  []outgoing
+	closed bool
+	wake   chan struct{}
+	done   chan struct{} // Closed when the writer goroutine ends.
+}
+
+// newOutbox creates the outbox of the connection, and starts its writer goroutine.
+// If size is 0, DefaultOutboxSize is used.
+func newOutbox(conn *websocket.Conn, size int, metrics *Metrics) *outbox {
+	if size <= 0 {
+		size = DefaultOutboxSize
+	}
+	o := &outbox{conn: conn, size: size, metrics: metrics, wake: make(chan struct{}, 1), done: make(chan struct{})}
+	go o.run()
+	return o
+}
+
+// send queues a message to be written to the connection. msgType is used to drop superseded messages.
+func (o *outbox) send(msgType game.MessageType, data []byte, isBinary bool) {
+	o.push(outgoing{msgType: msgType, data: data, isBinary: isBinary})
+}
+
+// sendJSON queues a message encoded as JSON, as wsjson.Write would.
+func (o *outbox) sendJSON(msg game.WsMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		klog.Errorf("outbox: Failed to encode %s message: %v", msg.Type, err)
+		return
+	}
+	o.send(msg.Type, data, false)
+}
+
+// closeAfter closes the connection once the messages already queued are written.
+func (o *outbox) closeAfter(status websocket.StatusCode, reason string) {
+	o.push(outgoing{closeStatus: status, closeReason: reason})
+}
+
+// stop discards the queued messages and ends the writer goroutine. The connection itself is closed by its owner.
+func (o *outbox) stop() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.closed = true
+	o.queue = nil
+	o.signal()
+}
+
+func (o *outbox) push(msg outgoing) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return
+	}
+	if obsolete := supersededBy[msg.msgType]; len(obsolete) > 0 {
+		// The message takes the place of the first one it supersedes, so it's still written before the messages
+		// queued after it (e.g.: an Update that applies to the new State).
+		replaced := false
+		kept := o.queue[:0]
+		for _, queued := range o.queue {
+			if !slices.Contains(obsolete, queued.msgType) {
+				kept = append(kept, queued)
+			} else if !replaced {
+				kept = append(kept, msg)
+				replaced = true
+			}
+		}
+		clear(o.queue[len(kept):])
+		o.queue = kept
+		if replaced {
+			o.signal()
+			return
+		}
+	}
+	if len(o.queue) >= o.size {
+		klog.Warningf("outbox: Connection fell %d messages behind, closing it", len(o.queue))
+		o.metrics.writeFailed(writeBehind)
+		o.closed = true
+		o.queue = nil
+		o.signal()
+		go func() {
+			_ = o.conn.Close(websocket.StatusTryAgainLater, "Too far behind")
+		}()
+		return
+	}
+	o.queue = append(o.queue, msg)
+	o.signal()
+}
+
+// signal wakes up the writer goroutine, if it's waiting. Assumes o.mu is locked.
+func (o *outbox) signal() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run writes the queued messages, in order, until the outbox is stopped or the connection fails.
+func (o *outbox) run() {
+	defer close(o.done)
+	for {
+		<-o.wake
+		for {
+			o.mu.Lock()
+			if o.closed || len(o.queue) == 0 {
+				closed := o.closed
+				o.mu.Unlock()
+				if closed {
+					return
+				}
+				break
+			}
+			msg := o.queue[0]
+			o.queue[0] = outgoing{}
+			o.queue = o.queue[1:]
+			o.mu.Unlock()
+
+			if msg.closeStatus != 0 {
+				o.stop()
+				_ = o.conn.Close(msg.closeStatus, msg.closeReason)
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), OutboxWriteTimeout)
+			err := writeEncoded(ctx, o.conn, msg.data, msg.isBinary)
+			cancel()
+			if err != nil {
+				klog.Infof("outbox: Failed to write %s message, dropping the connection: %v", msg.msgType, err)
+				o.metrics.writeFailed(writeError)
+				o.stop()
+				o.conn.CloseNow()
+				return
+			}
+			o.metrics.message(messageSent, msg.msgType)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/coder/websocket/wsjson"
+	"github.com/janpfeifer/GoSpot/internal/game"
+)
+
+func TestOutboxCoalescing(t *testing.T) {
+	// Without the writer goroutine, so the queue can be inspected.
+	o := &outbox{size: 10, wake: make(chan struct{}, 1)}
+	for i, msgType := range []game.MessageType{
+		game.MsgTypeState, game.MsgTypeStateDelta, game.MsgTypeUpdate, game.MsgTypeQueueStatus,
+		game.MsgTypeStateDelta, game.MsgTypeQueueStatus, game.MsgTypeState,
+	} {
+		o.send(msgType, []byte(fmt.Sprint(i)), false)
+	}
+	var got []string
+	for _, msg := range o.queue {
+		got = append(got, fmt.Sprintf("%s:%s", msg.msgType, msg.data))
+	}
+	want := fmt.Sprint([]string{"state:6", "update:2", "queue_status:5"})
+	if fmt.Sprint(got) != want {
+		t.Errorf("Expected the superseded messages to be replaced, leaving %s, got %v", want, got)
+	}
+}
+
+func TestOutboxOrder(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		started := make(chan *ServerState, 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go Run(ctx, NetPipeAddr, started)
+		s := <-started
+		wsURL := "ws://" + s.Address + "/ws"
+		tableID := "test-order"
+
+		// Alice doesn't read for now: the state broadcast after her pong blocks the writer of her outbox.
+		alice, err := testConnectAndJoin(ctx, s, wsURL, tableID, "p1", "Alice", 1, 0)
+		if err != nil {
+			t.Fatalf("Alice failed to join: %v", err)
+		}
+		defer alice.CloseNow()
+		synctest.Wait()
+
+		// A State, then an Update that applies to it, then a newer State: the Update must still follow a State.
+		table, unlock := testLockTableID(s, tableID)
+		for _, c := range s.clientsLocked(table) {
+			for i, msgType := range []game.MessageType{game.MsgTypeState, game.MsgTypeUpdate, game.MsgTypeState} {
+				c.send(game.WsMessage{Type: msgType, Payload: []byte(fmt.Sprintf(`{"test_order":%d}`, i))})
+			}
+		}
+		unlock()
+
+		var got []string
+		for len(got) < 2 {
+			var msg game.WsMessage
+			if err := wsjson.Read(ctx, alice, &msg); err != nil {
+				t.Fatalf("Failed to read: %v", err)
+			}
+			if strings.Contains(string(msg.Payload), "test_order") {
+				got = append(got, fmt.Sprintf("%s:%s", msg.Type, msg.Payload))
+			}
+		}
+		want := fmt.Sprint([]string{`state:{"test_order":2}`, `update:{"test_order":1}`})
+		if fmt.Sprint(got) != want {
+			t.Errorf("Expected the new state to take the place of the old one, delivering %s, got %v", want, got)
+		}
+	})
+}
+
+func TestSlowClientDisconnected(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		started := make(chan *ServerState, 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go Run(ctx, NetPipeAddr, started)
+		s := <-started
+		wsURL := "ws://" + s.Address + "/ws"
+		tableID := "test-slow"
+
+		bob, err := testConnectAndJoin(ctx, s, wsURL, tableID, "p1", "Bob", 1, 0)
+		if err != nil {
+			t.Fatalf("Bob failed to join: %v", err)
+		}
+		defer bob.CloseNow()
+		go func() {
+			for {
+				var msg game.WsMessage
+				if err := wsjson.Read(ctx, bob, &msg); err != nil {
+					return
+				}
+			}
+		}()
+		// Alice stops reading after joining.
+		alice, err := testConnectAndJoin(ctx, s, wsURL, tableID, "p2", "Alice", 2, 0)
+		if err != nil {
+			t.Fatalf("Alice failed to join: %v", err)
+		}
+		defer alice.CloseNow()
+		// Only Alice's outbox is small, so Bob's traffic never makes it overflow.
+		table, unlock := testLockTableID(s, tableID)
+		for _, c := range s.clientsLocked(table) {
+			if c.playerID == "p2" {
+				c.out.mu.Lock()
+				c.out.size = 3
+				c.out.mu.Unlock()
+			}
+		}
+		unlock()
+
+		// Chat messages are not superseded by newer ones, so they pile up in Alice's outbox.
+		for i := range ChatRateLimit {
+			chatMsg, _ := game.NewWsMessage(game.MsgTypeChat, game.ChatMessage{Text: fmt.Sprintf("Hi %d", i)})
+			if err := wsjson.Write(ctx, bob, chatMsg); err != nil {
+				t.Fatalf("Failed to send chat: %v", err)
+			}
+		}
+		synctest.Wait()
+		var closed []string
+		table, unlock = testLockTableID(s, tableID)
+		for _, c := range s.clientsLocked(table) {
+			c.out.mu.Lock()
+			if c.out.closed {
+				closed = append(closed, c.playerID)
+			}
+			c.out.mu.Unlock()
+		}
+		unlock()
+		if len(closed) != 1 || closed[0] != "p2" {
+			t.Fatalf("Expected only Alice's outbox to be closed for falling behind, got %v closed", closed)
+		}
+
+		// Once the connection is closed, Alice leaves the table, while Bob stays.
+		time.Sleep(2 * OutboxWriteTimeout)
+		var connected []string
+		table, unlock = testLockTableID(s, tableID)
+		for _, c := range s.clientsLocked(table) {
+			connected = append(connected, c.playerID)
+		}
+		unlock()
+		if len(connected) != 1 || connected[0] != "p1" {
+			t.Errorf("Expected only Bob to be connected, got %v", connected)
+		}
+	})
+}
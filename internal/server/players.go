@@ -0,0 +1,223 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/janpfeifer/GoSpot/internal/game"
+	"k8s.io/klog/v2"
+)
+
+// PlayerStore keeps the statistics of the players across games and server restarts.
+//
+// Implementations must be safe for concurrent use.
+type PlayerStore interface {
+	// Get returns the statistics of the player, or nil if there are none.
+	Get(playerID string) (*game.PlayerStats, error)
+
+	// Update calls fn with the statistics of the player (empty if there are none yet),
+	// and saves the changes it makes.
+	Update(playerID string, fn func(stats *game.PlayerStats)) error
+}
+
+// MemoryPlayerStore is a PlayerStore that only keeps the statistics in memory, they are lost when the server stops.
+type MemoryPlayerStore struct {
+	mu      sync.Mutex
+	players map[string]*game.PlayerStats
+}
+
+var _ PlayerStore = (*MemoryPlayerStore)(nil)
+
+// NewMemoryPlayerStore creates an empty MemoryPlayerStore.
+func NewMemoryPlayerStore() *MemoryPlayerStore {
+	return &MemoryPlayerStore{players: make(map[string]*game.PlayerStats)}
+}
+
+// Get implements PlayerStore.
+func (m *MemoryPlayerStore) Get(playerID string) (*game.PlayerStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return cloneStats(m.players[playerID]), nil
+}
+
+// Update implements PlayerStore.
+func (m *MemoryPlayerStore) Update(playerID string, fn func(stats *game.PlayerStats)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.updateLocked(playerID, fn)
+	return nil
+}
+
+// updateLocked applies fn to the statistics of the player. Assumes m.mu is locked.
+func (m *MemoryPlayerStore) updateLocked(playerID string, fn func(stats *game.PlayerStats)) {
+	stats := cloneStats(m.players[playerID])
+	if stats == nil {
+		stats = &game.PlayerStats{ID: playerID}
+	}
+	fn(stats)
+	stats.ID = playerID
+	m.players[playerID] = stats
+}
+
+// cloneStats returns a copy of the stats that doesn't share memory with it, or nil if stats is nil.
+func cloneStats(stats *game.PlayerStats) *game.PlayerStats {
+	if stats == nil {
+		return nil
+	}
+	clone := *stats
+	clone.FastestSolo = maps.Clone(stats.FastestSolo)
+	return &clone
+}
+
+// FilePlayerStore is a PlayerStore that saves the statistics of all players in a JSON file.
+//
+// The whole file is rewritten on every update, which is fine for the number of players of a single server.
+type FilePlayerStore struct {
+	MemoryPlayerStore
+	path string
+}
+
+var _ PlayerStore = (*FilePlayerStore)(nil)
+
+// NewFilePlayerStore creates a FilePlayerStore saved in path, and loads the statistics already saved there, if any.
+func NewFilePlayerStore(path string) (*FilePlayerStore, error) {
+	f := &FilePlayerStore{
+		MemoryPlayerStore: *NewMemoryPlayerStore(),
+		path:              path,
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		klog.Infof("NewFilePlayerStore: %s doesn't exist yet, starting with no players", path)
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read players file: %w", err)
+	}
+	if err := json.Unmarshal(data, &f.players); err != nil {
+		return nil, fmt.Errorf("failed to parse players file %s: %w", path, err)
+	}
+	klog.Infof("NewFilePlayerStore: Loaded %d players from %s", len(f.players), path)
+	return f, nil
+}
+
+// Update implements PlayerStore.
+func (f *FilePlayerStore) Update(playerID string, fn func(stats *game.PlayerStats)) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.updateLocked(playerID, fn)
+	return f.saveLocked()
+}
+
+// saveLocked writes all players to the file. Assumes f.mu is locked.
+func (f *FilePlayerStore) saveLocked() error {
+	data, err := json.MarshalIndent(f.players, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode players: %w", err)
+	}
+	return writeFileAtomic(f.path, data)
+}
+
+// writeFileAtomic writes data to a temporary file first, and then renames it to path,
+// so a crash never leaves the file half-written.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name()) // No-op after a successful rename.
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save %s: %w", path, err)
+	}
+	return nil
+}
+
+// gameResultsLocked returns the results of the finished game for each of its human players.
+// Assumes the table is locked.
+func gameResultsLocked(table *game.Table) []game.GameResult {
+	var humans []*game.Player
+	for _, p := range table.Players {
+		if p.Bot == "" {
+			humans = append(humans, p)
+		}
+	}
+	results := make([]game.GameResult, 0, len(humans))
+	var gameDuration time.Duration
+	for _, p := range table.Players {
+		gameDuration = max(gameDuration, p.TimeTaken)
+	}
+	for _, p := range humans {
+		results = append(results, game.GameResult{
+			PlayerID:      p.ID,
+			Name:          p.Name,
+			Symbol:        p.Symbol,
+			Mode:          table.Mode().Info().ID,
+			Solo:          len(table.Players) == 1,
+			Won:           table.Won(p),
+			Matches:       p.Matches,
+			BonusDiscards: p.BonusDiscards,
+			Duration:      p.TimeTaken,
+			EndTime:       table.StartTime.Add(gameDuration),
+		})
+	}
+	return results
+}
+
+// recordGame saves the results of a finished game in the players' statistics, and adds soloEntry, if not nil,
+// to the leaderboard.
+// It doesn't need the table locked, and can be run in its own goroutine, so the stores are not accessed with the
+// lock held.
+func (s *ServerState) recordGame(tableID string, results []game.GameResult, soloEntry *game.LeaderboardEntry) {
+	if soloEntry != nil {
+		klog.Infof("recordGame: Solo game on table %s by %s in %v ranked in %s", tableID, soloEntry.Name, soloEntry.Duration, soloEntry.LeaderboardKey)
+		if err := s.Leaderboard.Add(*soloEntry); err != nil {
+			klog.Errorf("recordGame: Failed to add solo game on table %s to the leaderboard: %v", tableID, err)
+		}
+	}
+	for _, result := range results {
+		err := s.Players.Update(result.PlayerID, func(stats *game.PlayerStats) {
+			stats.Record(result)
+		})
+		if err != nil {
+			klog.Errorf("recordGame: Failed to record game on table %s for player %s: %v", tableID, result.Name, err)
+		}
+	}
+}
+
+// HandlePlayerStats serves the statistics of a player in JSON, at /api/players/<player_id>.
+func (s *ServerState) HandlePlayerStats(w http.ResponseWriter, r *http.Request) {
+	playerID := strings.TrimPrefix(r.URL.Path, "/api/players/")
+	if playerID == "" || strings.Contains(playerID, "/") {
+		http.Error(w, "invalid player ID", http.StatusBadRequest)
+		return
+	}
+	stats, err := s.Players.Get(playerID)
+	if err != nil {
+		klog.Errorf("HandlePlayerStats: Failed to get player %s: %v", playerID, err)
+		http.Error(w, "failed to get player statistics", http.StatusInternalServerError)
+		return
+	}
+	if stats == nil {
+		// Players that haven't finished a game yet have no statistics.
+		stats = &game.PlayerStats{ID: playerID}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		klog.Errorf("HandlePlayerStats: Failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"path/filepath"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/janpfeifer/GoSpot/internal/game"
+)
+
+func TestFilePlayerStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "players.json")
+	store, err := NewFilePlayerStore(path)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	if stats, err := store.Get("p1"); err != nil || stats != nil {
+		t.Fatalf("Expected no statistics for a new player, got %+v, %v", stats, err)
+	}
+	result := game.GameResult{
+		PlayerID: "p1", Name: "Alice", Symbol: 3, Mode: game.ModeTower,
+		Solo: true, Won: true, Matches: 4, BonusDiscards: 2, Duration: 20 * time.Second,
+	}
+	for _, d := range []time.Duration{20 * time.Second, 12 * time.Second} {
+		result.Duration = d
+		if err := store.Update("p1", func(stats *game.PlayerStats) { stats.Record(result) }); err != nil {
+			t.Fatalf("Failed to update: %v", err)
+		}
+	}
+
+	// Reload from the file.
+	store, err = NewFilePlayerStore(path)
+	if err != nil {
+		t.Fatalf("Failed to reload store: %v", err)
+	}
+	stats, err := store.Get("p1")
+	if err != nil || stats == nil {
+		t.Fatalf("Expected statistics to be saved, got %+v, %v", stats, err)
+	}
+	if stats.Name != "Alice" || stats.GamesPlayed != 2 || stats.Wins != 2 || stats.Matches != 8 || stats.BonusDiscards != 4 {
+		t.Errorf("Unexpected statistics: %+v", stats)
+	}
+	if got := stats.AvgTimePerMatch(); got != 4*time.Second {
+		t.Errorf("Expected 4s per match, got %v", got)
+	}
+	if got := stats.FastestSolo[game.ModeTower]; got != 12*time.Second {
+		t.Errorf("Expected fastest solo game of 12s, got %v", got)
+	}
+}
+
+func TestGameRecorded(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		s := NewServerState()
+		tableID := "test-stats"
+		alice := &game.Player{ID: "p1", Name: "Alice", Symbol: 1}
+		table := &game.Table{
+			ID:       tableID,
+			Name:     tableID,
+			Players:  []*game.Player{alice},
+			Settings: game.DefaultTableSettings(),
+		}
+		table.Settings.CardsPerPlayer = 3
+		table.Settings.PlayerSymbolBonus = false
+		s.addTable(table, nil)
+
+		testLock(t, s, table)
+		s.handleGameStart(table, alice, &game.StartMessage{})
+		testUnlock(s, table)
+		for range 3 {
+			time.Sleep(5 * time.Second)
+			testLock(t, s, table)
+			symbol := botMatch(table, alice)
+			s.handleClick(table, alice, &game.ClickMessage{Symbol: symbol, Round: table.Round})
+			testUnlock(s, table)
+			synctest.Wait()
+		}
+
+		stats, err := s.Players.Get(alice.ID)
+		if err != nil || stats == nil {
+			t.Fatalf("Expected the game to be recorded, got %+v, %v", stats, err)
+		}
+		if stats.GamesPlayed != 1 || stats.Wins != 1 || stats.Matches != 3 {
+			t.Errorf("Unexpected statistics: %+v", stats)
+		}
+		if got := stats.FastestSolo[game.ModeTower]; got != 15*time.Second {
+			t.Errorf("Expected a solo game of 15s, got %v", got)
+		}
+		if got := stats.AvgTimePerMatch(); got != 5*time.Second {
+			t.Errorf("Expected 5s per match, got %v", got)
+		}
+	})
+}
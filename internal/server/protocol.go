@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+
+	"github.com/coder/websocket"
+	"github.com/janpfeifer/GoSpot/internal/game"
+	"k8s.io/klog/v2"
+)
+
+// readMessage reads the next message from the connection, sent in either encoding, see game.DecodeMessage.
+func readMessage(ctx context.Context, conn *websocket.Conn) (game.WsMessage, error) {
+	typ, data, err := conn.Read(ctx)
+	if err != nil {
+		return game.WsMessage{}, err
+	}
+	return game.DecodeMessage(data, typ == websocket.MessageBinary)
+}
+
+// writeEncoded writes a message encoded with game.EncodeMessage to the connection.
+func writeEncoded(ctx context.Context, conn *websocket.Conn, data []byte, isBinary bool) error {
+	typ := websocket.MessageText
+	if isBinary {
+		typ = websocket.MessageBinary
+	}
+	return conn.Write(ctx, typ, data)
+}
+
+// send queues a message to the client, encoded as JSON, without blocking the caller.
+func (c *client) send(msg game.WsMessage) {
+	c.out.sendJSON(msg)
+}
+
+// sendEncoded queues a message to the client, in the encoding negotiated for it, without blocking the caller.
+func (c *client) sendEncoded(msgType game.MessageType, payload any) {
+	data, isBinary, err := game.EncodeMessage(c.encoding, msgType, payload)
+	if err != nil {
+		klog.Errorf("sendEncoded: Failed to encode %s message: %v", msgType, err)
+		return
+	}
+	c.out.send(msgType, data, isBinary)
+}
+
+// welcome answers the handshake of a client: it negotiates the encoding and the features of the protocol, or sets
+// the error if the client is incompatible.
+func (s *ServerState) welcome(hello *game.HelloMessage) game.WelcomeMessage {
+	welcome := game.WelcomeMessage{
+		ProtocolVersion: game.ProtocolVersion,
+		Version:         game.Version,
+		Encoding:        game.EncodingJSON,
+	}
+	if err := game.CheckProtocolVersion(hello.ProtocolVersion); err != nil {
+		welcome.Error = err.Error()
+		return welcome
+	}
+	welcome.Encoding = game.NegotiateEncoding(hello.Encodings, s.Encodings)
+	welcome.Features = game.NegotiateFeatures(hello.Features, s.Features)
+	klog.Infof("welcome: Client version %s (protocol %d) uses the %s encoding, features %v",
+		hello.Version, hello.ProtocolVersion, welcome.Encoding, welcome.Features)
+	return welcome
+}
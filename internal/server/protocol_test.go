@@ -0,0 +1,303 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"testing/synctest"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/janpfeifer/GoSpot/internal/game"
+)
+
+// testReadEncoded reads the next message from the connection, in either encoding, and parses it.
+// It returns the parsed payload and whether it was received in a binary frame.
+func testReadEncoded(t *testing.T, ctx context.Context, conn *websocket.Conn) (any, bool) {
+	t.Helper()
+	typ, data, err := conn.Read(ctx)
+	if err != nil {
+		t.Fatalf("Failed to read message: %v", err)
+	}
+	msg, err := game.DecodeMessage(data, typ == websocket.MessageBinary)
+	if err != nil {
+		t.Fatalf("Failed to decode message: %v", err)
+	}
+	payload, err := msg.Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse %s message: %v", msg.Type, err)
+	}
+	return payload, typ == websocket.MessageBinary
+}
+
+func TestBinaryProtocol(t *testing.T) {
+	for _, jsonOnly := range []bool{false, true} {
+		synctest.Test(t, func(t *testing.T) {
+			started := make(chan *ServerState, 1)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go RunWithConfig(ctx, Config{Addr: NetPipeAddr, JSONOnly: jsonOnly}, started)
+			s := <-started
+			want := game.EncodingBinary
+			if jsonOnly {
+				want = game.EncodingJSON
+			}
+
+			opts := &websocket.DialOptions{HTTPClient: &http.Client{Transport: &http.Transport{
+				DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+					return s.LocalDial()
+				},
+				DisableKeepAlives: true,
+			}}}
+			conn, _, err := websocket.Dial(ctx, "ws://"+s.Address+"/ws", opts)
+			if err != nil {
+				t.Fatalf("Failed to dial: %v", err)
+			}
+			defer conn.CloseNow()
+			helloMsg, _ := game.NewWsMessage(game.MsgTypeHello, game.HelloMessage{
+				ProtocolVersion: game.ProtocolVersion,
+				Encodings:       game.Encodings,
+				Features:        []game.Feature{game.FeatureDeltas},
+			})
+			if err := wsjson.Write(ctx, conn, helloMsg); err != nil {
+				t.Fatalf("Failed to send hello: %v", err)
+			}
+
+			// The server tells the encoding, and then uses it for the frequent messages.
+			welcome, ok := testMustRead(t, ctx, conn).(*game.WelcomeMessage)
+			if !ok {
+				t.Fatalf("Expected the welcome message")
+			}
+			encoding := welcome.Encoding
+			joinMsg, _ := game.NewWsMessage(game.MsgTypeJoin, game.JoinMessage{
+				TableID: "test-binary",
+				Player:  game.Player{ID: "p1", Name: "Alice", Symbol: 1},
+			})
+			if err := wsjson.Write(ctx, conn, joinMsg); err != nil {
+				t.Fatalf("Failed to join: %v", err)
+			}
+			if encoding != want {
+				t.Fatalf("Expected the %s encoding, got %s", want, encoding)
+			}
+			payload, isBinary := testReadEncoded(t, ctx, conn)
+			for _, ok := payload.(*game.StateMessage); ok; _, ok = payload.(*game.StateMessage) {
+				payload, isBinary = testReadEncoded(t, ctx, conn)
+			}
+			ping, ok := payload.(*game.PingMessage)
+			if !ok || isBinary != (want == game.EncodingBinary) {
+				t.Fatalf("Expected a ping (binary=%t), got %T (binary=%t)", want == game.EncodingBinary, payload, isBinary)
+			}
+			testWriteEncoded(t, ctx, conn, encoding, game.MsgTypePong, game.PongMessage{ServerTime: ping.ServerTime})
+
+			// Play a round of a solo game.
+			startMsg, _ := game.NewWsMessage(game.MsgTypeStart, nil)
+			if err := wsjson.Write(ctx, conn, startMsg); err != nil {
+				t.Fatalf("Failed to start: %v", err)
+			}
+			var update *game.UpdateMessage
+			var deltas int
+			for update == nil {
+				payload, isBinary := testReadEncoded(t, ctx, conn)
+				switch msg := payload.(type) {
+				case *game.UpdateMessage:
+					update = msg
+					if isBinary != (want == game.EncodingBinary) {
+						t.Errorf("Expected a binary=%t update", want == game.EncodingBinary)
+					}
+				case *game.StateDeltaMessage:
+					deltas++
+					if isBinary != (want == game.EncodingBinary) {
+						t.Errorf("Expected a binary=%t state delta", want == game.EncodingBinary)
+					}
+				}
+			}
+			if deltas == 0 {
+				t.Errorf("Expected the state of the started game to be sent as a delta")
+			}
+			var symbol int
+			for _, sym := range update.TopCard {
+				if slices.Contains(update.TargetCard, sym) {
+					symbol = sym
+				}
+			}
+			testWriteEncoded(t, ctx, conn, encoding, game.MsgTypeClick, game.ClickMessage{Symbol: symbol, Round: update.Round})
+			for {
+				payload, _ := testReadEncoded(t, ctx, conn)
+				if msg, ok := payload.(*game.UpdateMessage); ok && msg.Round > update.Round {
+					break
+				}
+			}
+		})
+	}
+}
+
+// testMustRead reads the next message from the connection, in either encoding, and returns its payload.
+func testMustRead(t *testing.T, ctx context.Context, conn *websocket.Conn) any {
+	t.Helper()
+	payload, _ := testReadEncoded(t, ctx, conn)
+	return payload
+}
+
+// testWriteEncoded sends a message to the server in the given encoding.
+func testWriteEncoded(t *testing.T, ctx context.Context, conn *websocket.Conn, enc game.Encoding, msgType game.MessageType, payload any) {
+	t.Helper()
+	data, isBinary, err := game.EncodeMessage(enc, msgType, payload)
+	if err != nil {
+		t.Fatalf("Failed to encode %s: %v", msgType, err)
+	}
+	if err := writeEncoded(ctx, conn, data, isBinary); err != nil {
+		t.Fatalf("Failed to write %s: %v", msgType, err)
+	}
+}
+
+func TestStateDeltas(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		started := make(chan *ServerState, 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go Run(ctx, NetPipeAddr, started)
+		s := <-started
+		wsURL := "ws://" + s.Address + "/ws"
+		tableID := "test-deltas"
+
+		hello := &game.HelloMessage{ProtocolVersion: game.ProtocolVersion, Features: []game.Feature{game.FeatureDeltas}}
+		alice, err := testConnectWithHello(ctx, s, wsURL, hello, game.JoinMessage{
+			TableID: tableID,
+			Player:  game.Player{ID: "p1", Name: "Alice", Symbol: 1},
+		}, 0)
+		if err != nil {
+			t.Fatalf("Alice failed to join: %v", err)
+		}
+		defer alice.CloseNow()
+
+		// Alice gets the full state, and then only deltas. Like the client, deltas already covered by the full
+		// state are skipped.
+		received := make(chan any, 100)
+		go func() {
+			for {
+				typ, data, err := alice.Read(ctx)
+				if err != nil {
+					return
+				}
+				msg, err := game.DecodeMessage(data, typ == websocket.MessageBinary)
+				if err != nil {
+					t.Errorf("Failed to decode message: %v", err)
+					return
+				}
+				payload, _ := msg.Parse()
+				received <- payload
+			}
+		}()
+		var table *game.Table
+		var deltas int
+		readAll := func() {
+			for len(received) > 0 {
+				switch msg := (<-received).(type) {
+				case *game.StateMessage:
+					table = &msg.Table
+				case *game.StateDeltaMessage:
+					if table == nil || msg.Revision <= table.Revision {
+						continue
+					}
+					if table, err = table.ApplyDelta(msg); err != nil {
+						t.Fatalf("Failed to apply delta: %v", err)
+					}
+					deltas++
+				}
+			}
+		}
+		// The full state sent when joining was skipped by testConnectWithHello: ask for it again.
+		resyncMsg, _ := game.NewWsMessage(game.MsgTypeResync, nil)
+		if err := wsjson.Write(ctx, alice, resyncMsg); err != nil {
+			t.Fatalf("Failed to send resync: %v", err)
+		}
+		synctest.Wait()
+		readAll()
+		if table == nil || len(table.Players) != 1 {
+			t.Fatalf("Expected the full state after the resync, got %v", table)
+		}
+
+		bob, err := testConnectAndJoin(ctx, s, wsURL, tableID, "p2", "Bob", 2, 0)
+		if err != nil {
+			t.Fatalf("Bob failed to join: %v", err)
+		}
+		defer bob.CloseNow()
+		go func() {
+			for {
+				var msg game.WsMessage
+				if err := wsjson.Read(ctx, bob, &msg); err != nil {
+					return
+				}
+				if msg.Type == game.MsgTypeStateDelta {
+					t.Errorf("Bob didn't ask for state deltas")
+				}
+			}
+		}()
+		synctest.Wait()
+		readAll()
+		if deltas == 0 {
+			t.Fatalf("Expected state deltas after Bob joined")
+		}
+		serverTable, unlock := testLockTableID(s, tableID)
+		want, _ := json.Marshal(serverTable)
+		unlock()
+		if got, _ := json.Marshal(table); string(got) != string(want) {
+			t.Errorf("Expected the state after the deltas to be\n%s\ngot\n%s", want, got)
+		}
+	})
+}
+
+func TestHandshake(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		started := make(chan *ServerState, 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go Run(ctx, NetPipeAddr, started)
+		s := <-started
+		wsURL := "ws://" + s.Address + "/ws"
+
+		// Clients newer than the server are asked to reload.
+		_, err := testConnectWithHello(ctx, s, wsURL, &game.HelloMessage{ProtocolVersion: game.ProtocolVersion + 1},
+			game.JoinMessage{TableID: "test-handshake", Player: game.Player{ID: "p1", Name: "Alice", Symbol: 1}}, 0)
+		if err == nil || !strings.Contains(err.Error(), "please reload") {
+			t.Fatalf("Expected the handshake to be refused asking to reload, got %v", err)
+		}
+
+		// Unknown optional messages are ignored, while unknown required ones are reported.
+		alice, err := testConnectWithHello(ctx, s, wsURL, &game.HelloMessage{ProtocolVersion: game.ProtocolVersion},
+			game.JoinMessage{TableID: "test-handshake", Player: game.Player{ID: "p1", Name: "Alice", Symbol: 1}}, 0)
+		if err != nil {
+			t.Fatalf("Alice failed to join: %v", err)
+		}
+		defer alice.CloseNow()
+		received := make(chan game.WsMessage, 100)
+		go func() {
+			for {
+				var msg game.WsMessage
+				if err := wsjson.Read(ctx, alice, &msg); err != nil {
+					return
+				}
+				received <- msg
+			}
+		}()
+		for _, optional := range []bool{true, false} {
+			msg := game.WsMessage{Type: "from_the_future", Optional: optional}
+			if err := wsjson.Write(ctx, alice, msg); err != nil {
+				t.Fatalf("Failed to send message: %v", err)
+			}
+			synctest.Wait()
+			var gotError bool
+			for len(received) > 0 {
+				msg := <-received
+				gotError = gotError || msg.Type == game.MsgTypeError
+			}
+			if gotError == optional {
+				t.Errorf("Expected an error only for required unknown messages: optional=%t, got error=%t", optional, gotError)
+			}
+		}
+	})
+}
@@ -0,0 +1,309 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/janpfeifer/GoSpot/internal/game"
+	"k8s.io/klog/v2"
+)
+
+// InstanceDialTimeout is how long an instance waits for the instance running a table to accept a relayed
+// connection, before taking the table over, see ServerState.relayTable.
+var InstanceDialTimeout = 5 * time.Second
+
+// errInstanceUnreachable is returned by dialInstance if the instance doesn't accept the connection in time.
+var errInstanceUnreachable = errors.New("instance unreachable")
+
+// Kinds of the messages of a tunnel, the first byte of each message published.
+const (
+	tunnelAccept byte = 'a' // The instance dialed is ready to read.
+	tunnelData   byte = 'd' // Followed by the bytes written to the connection.
+	tunnelClose  byte = 'c' // The connection was closed.
+)
+
+// newInstanceID returns a random ID for a server instance.
+func newInstanceID() string {
+	var b [6]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+func dialTopic(instance string) string { return "gospot/instance/" + instance + "/dial" }
+
+func tunnelTopic(id, direction string) string { return "gospot/tunnel/" + id + "/" + direction }
+
+// tunnel is a connection between two instances carried by the Broker: the bytes written to conn are published
+// to the out topic, and those published to the in topic are read from it.
+type tunnel struct {
+	broker  Broker
+	in, out string
+	conn    net.Conn // Returned to the user of the tunnel.
+	local   net.Conn // The other end of conn, copied to and from the broker.
+
+	mu       sync.Mutex
+	incoming [][]byte
+	closed   bool
+	wake     chan struct{}
+	accepted chan struct{}
+
+	unsubscribe func()
+}
+
+// newTunnel creates a tunnel, subscribed to its in topic.
+func newTunnel(broker Broker, in, out string) (*tunnel, error) {
+	t := &tunnel{broker: broker, in: in, out: out, wake: make(chan struct{}, 1), accepted: make(chan struct{})}
+	t.conn, t.local = net.Pipe()
+	var err error
+	t.unsubscribe, err = broker.Subscribe(in, t.receive)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// receive handles a message published to the in topic. It doesn't block: the data is queued to be written.
+func (t *tunnel) receive(msg []byte) {
+	if len(msg) == 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch msg[0] {
+	case tunnelAccept:
+		select {
+		case <-t.accepted:
+		default:
+			close(t.accepted)
+		}
+		return
+	case tunnelData:
+		t.incoming = append(t.incoming, msg[1:])
+	case tunnelClose:
+		t.closed = true
+	}
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run copies the data between the broker and the connection, until either side closes it.
+func (t *tunnel) run() {
+	go func() {
+		defer t.local.Close()
+		for range t.wake {
+			t.mu.Lock()
+			incoming, closed := t.incoming, t.closed
+			t.incoming = nil
+			t.mu.Unlock()
+			for _, data := range incoming {
+				if _, err := t.local.Write(data); err != nil {
+					return
+				}
+			}
+			if closed {
+				return
+			}
+		}
+	}()
+
+	defer t.unsubscribe()
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := t.local.Read(buf)
+		if n > 0 {
+			msg := append([]byte{tunnelData}, buf[:n]...)
+			if pubErr := t.broker.Publish(t.out, msg); pubErr != nil {
+				klog.Errorf("tunnel: Failed to publish to %s: %v", t.out, pubErr)
+				err = pubErr
+			}
+		}
+		if err != nil {
+			_ = t.broker.Publish(t.out, []byte{tunnelClose})
+			_ = t.local.Close()
+			t.receive([]byte{tunnelClose})
+			return
+		}
+	}
+}
+
+// listenInstances accepts the connections relayed by other instances through the Broker, see dialInstance,
+// and returns the listener to serve them, as any other connection.
+func (s *ServerState) listenInstances() (net.Listener, error) {
+	ln := &pipeListener{
+		conns:  make(chan net.Conn),
+		closed: make(chan struct{}),
+	}
+	unsubscribe, err := s.Broker.Subscribe(dialTopic(s.InstanceID), func(data []byte) {
+		id := string(data)
+		t, err := newTunnel(s.Broker, tunnelTopic(id, "up"), tunnelTopic(id, "down"))
+		if err != nil {
+			klog.Errorf("listenInstances: Failed to accept tunnel %s: %v", id, err)
+			return
+		}
+		// Subscribed to the incoming data: the dialer can start sending it.
+		if err := s.Broker.Publish(t.out, []byte{tunnelAccept}); err != nil {
+			klog.Errorf("listenInstances: Failed to accept tunnel %s: %v", id, err)
+			t.unsubscribe()
+			return
+		}
+		go t.run()
+		go func() {
+			select {
+			case ln.conns <- t.conn:
+			case <-ln.closed:
+				_ = t.conn.Close()
+			}
+		}()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen to other instances: %w", err)
+	}
+	go func() {
+		<-ln.closed
+		unsubscribe()
+	}()
+	return ln, nil
+}
+
+// dialInstance opens a connection to the instance, through the Broker. It returns errInstanceUnreachable if the
+// instance doesn't accept it within InstanceDialTimeout.
+func (s *ServerState) dialInstance(ctx context.Context, instance string) (net.Conn, error) {
+	id := newInstanceID() + newInstanceID()
+	t, err := newTunnel(s.Broker, tunnelTopic(id, "down"), tunnelTopic(id, "up"))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Broker.Publish(dialTopic(instance), []byte(id)); err != nil {
+		t.unsubscribe()
+		return nil, err
+	}
+	timer := time.NewTimer(InstanceDialTimeout)
+	defer timer.Stop()
+	select {
+	case <-t.accepted:
+	case <-timer.C:
+		t.unsubscribe()
+		return nil, errInstanceUnreachable
+	case <-ctx.Done():
+		t.unsubscribe()
+		return nil, ctx.Err()
+	}
+	go t.run()
+	return t.conn, nil
+}
+
+// tableOwner returns the instance that runs the table: this one if the table is here, or if it's not run by any.
+func (s *ServerState) tableOwner(tableID string) string {
+	s.mu.RLock()
+	table := s.Tables[tableID]
+	s.mu.RUnlock()
+	if table != nil {
+		return s.InstanceID
+	}
+	owner, err := s.TableStore.Claim(tableID, s.InstanceID)
+	if err != nil {
+		klog.Errorf("tableOwner: Failed to claim table %s, running it here: %v", tableID, err)
+		return s.InstanceID
+	}
+	return owner
+}
+
+// relayTable relays the connection of a client joining a table run by another instance: the owner is connected
+// through the Broker, and it handles the client as any other, while the messages are copied both ways.
+//
+// The client already negotiated the protocol with this instance (if hello is set): the owner is asked to use
+// the same encoding and features. It returns errInstanceUnreachable if the owner doesn't answer.
+func (s *ServerState) relayTable(ctx context.Context, conn *websocket.Conn, out *outbox, owner string,
+	hello *game.HelloMessage, welcome game.WelcomeMessage, join *game.JoinMessage) error {
+	tunnelConn, err := s.dialInstance(ctx, owner)
+	if err != nil {
+		return err
+	}
+	opts := &websocket.DialOptions{
+		HTTPClient: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+					return tunnelConn, nil
+				},
+				DisableKeepAlives: true,
+			},
+		},
+	}
+	upstream, _, err := websocket.Dial(ctx, "ws://"+owner+"/ws", opts)
+	if err != nil {
+		_ = tunnelConn.Close()
+		return fmt.Errorf("failed to connect to instance %s: %w", owner, err)
+	}
+	defer upstream.CloseNow()
+
+	if hello != nil {
+		relayedHello := *hello
+		relayedHello.Encodings = []game.Encoding{welcome.Encoding}
+		relayedHello.Features = welcome.Features
+		helloMsg, _ := game.NewWsMessage(game.MsgTypeHello, relayedHello)
+		if err := wsjson.Write(ctx, upstream, helloMsg); err != nil {
+			return fmt.Errorf("failed to relay the handshake to instance %s: %w", owner, err)
+		}
+		// The answer of the owner must match the one the client already has.
+		var welcomeMsg game.WsMessage
+		if err := wsjson.Read(ctx, upstream, &welcomeMsg); err != nil {
+			return fmt.Errorf("failed to relay the handshake to instance %s: %w", owner, err)
+		}
+		p, err := welcomeMsg.Parse()
+		ownerWelcome, ok := p.(*game.WelcomeMessage)
+		if err != nil || !ok || ownerWelcome.Error != "" || ownerWelcome.Encoding != welcome.Encoding {
+			return fmt.Errorf("instance %s refused the handshake: %+v (%v)", owner, p, err)
+		}
+	}
+	joinMsg, _ := game.NewWsMessage(game.MsgTypeJoin, join)
+	if err := wsjson.Write(ctx, upstream, joinMsg); err != nil {
+		return fmt.Errorf("failed to relay the join to instance %s: %w", owner, err)
+	}
+	klog.Infof("relayTable: Relaying %s to table %s on instance %s", join.Player.Name, join.TableID, owner)
+
+	// From the owner to the client, through the outbox. The close status is relayed, so the client knows
+	// whether to reconnect.
+	go func() {
+		for {
+			typ, data, err := upstream.Read(ctx)
+			if err != nil {
+				var closeErr websocket.CloseError
+				if errors.As(err, &closeErr) {
+					out.closeAfter(closeErr.Code, closeErr.Reason)
+				} else {
+					out.closeAfter(websocket.StatusTryAgainLater, "Lost the connection to the table")
+				}
+				return
+			}
+			out.send("", data, typ == websocket.MessageBinary)
+		}
+	}()
+
+	// From the client to the owner.
+	for {
+		typ, data, err := conn.Read(ctx)
+		if err != nil {
+			status := websocket.CloseStatus(err)
+			if status == -1 {
+				status = websocket.StatusGoingAway
+			}
+			_ = upstream.Close(status, "Client disconnected")
+			return nil
+		}
+		if err := upstream.Write(ctx, typ, data); err != nil {
+			klog.Errorf("relayTable: Failed to relay message to instance %s: %v", owner, err)
+			return nil
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"testing/synctest"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/janpfeifer/GoSpot/internal/game"
+)
+
+func TestRelayTable(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		store, broker := NewMemoryTableStore(), NewMemoryBroker()
+
+		// Two instances sharing their tables.
+		servers := make(map[string]*ServerState)
+		for _, id := range []string{"one", "two"} {
+			started := make(chan *ServerState, 1)
+			go RunWithConfig(ctx, Config{Addr: NetPipeAddr, InstanceID: id, TableStore: store, Broker: broker}, started)
+			servers[id] = <-started
+		}
+		join := func(instance, tableID, playerID, name string) *websocket.Conn {
+			s := servers[instance]
+			conn, err := testConnectAndJoin(ctx, s, "ws://"+s.Address+"/ws", tableID, playerID, name, 0, 0)
+			if err != nil {
+				t.Fatalf("%s failed to join on instance %s: %v", name, instance, err)
+			}
+			go func() {
+				for {
+					var msg game.WsMessage
+					if err := wsjson.Read(ctx, conn, &msg); err != nil {
+						return
+					}
+				}
+			}()
+			synctest.Wait()
+			return conn
+		}
+
+		// Alice creates the table on instance one, and Bob joins it from instance two.
+		const tableID = "test-relay"
+		alice := join("one", tableID, "p1", "Alice")
+		defer alice.CloseNow()
+		bob := join("two", tableID, "p2", "Bob")
+		defer bob.CloseNow()
+		if testTable(servers["two"], tableID) != nil {
+			t.Errorf("Expected table %s to only be run by instance one", tableID)
+		}
+		table, unlock := testLockTableID(servers["one"], tableID)
+		if table == nil || len(table.Players) != 2 {
+			unlock()
+			t.Fatalf("Expected Alice and Bob on table %s of instance one, got %v", tableID, table)
+		}
+		unlock()
+
+		// Bob's messages are relayed as well.
+		chatMsg, _ := game.NewWsMessage(game.MsgTypeChat, game.ChatMessage{Text: "hello"})
+		if err := wsjson.Write(ctx, bob, chatMsg); err != nil {
+			t.Fatalf("Failed to send chat message: %v", err)
+		}
+		synctest.Wait()
+		testLock(t, servers["one"], table)
+		if len(table.Chat) != 1 || table.Chat[0].Text != "hello" {
+			t.Errorf("Expected Bob's chat message on the table, got %+v", table.Chat)
+		}
+		testUnlock(servers["one"], table)
+
+		// Bob leaving is seen by the owner.
+		bob.CloseNow()
+		synctest.Wait()
+		testLock(t, servers["one"], table)
+		if len(table.Players) != 2 || !table.Players[1].Disconnected {
+			t.Errorf("Expected Bob to be disconnected, got %s", table)
+		}
+		testUnlock(servers["one"], table)
+
+		// A table of an instance that is gone is taken over.
+		const orphanID = "test-orphan"
+		if _, err := store.Claim(orphanID, "gone"); err != nil {
+			t.Fatalf("Failed to claim: %v", err)
+		}
+		carol := join("two", orphanID, "p3", "Carol")
+		defer carol.CloseNow()
+		if testTable(servers["two"], orphanID) == nil {
+			t.Errorf("Expected instance two to take table %s over", orphanID)
+		}
+		if owner, _ := store.Claim(orphanID, "one"); owner != "two" {
+			t.Errorf("Expected table %s to be owned by instance two, got %q", orphanID, owner)
+		}
+	})
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/janpfeifer/GoSpot/internal/game"
+	"k8s.io/klog/v2"
+)
+
+// ReplayStore keeps the logs of the finished games, see game.Replay.
+//
+// Implementations must be safe for concurrent use.
+type ReplayStore interface {
+	// Save the replay of a finished game.
+	Save(replay *game.Replay) error
+
+	// Get returns the replay with the given ID, or nil if there is none.
+	Get(id string) (*game.Replay, error)
+}
+
+// MemoryReplaysKept is the number of replays kept by a MemoryReplayStore: older ones are dropped.
+const MemoryReplaysKept = 100
+
+// MemoryReplayStore is a ReplayStore that only keeps the last MemoryReplaysKept replays in memory.
+type MemoryReplayStore struct {
+	mu      sync.Mutex
+	replays map[string]*game.Replay
+	order   []string // IDs of the replays, from oldest to newest.
+}
+
+var _ ReplayStore = (*MemoryReplayStore)(nil)
+
+// NewMemoryReplayStore creates an empty MemoryReplayStore.
+func NewMemoryReplayStore() *MemoryReplayStore {
+	return &MemoryReplayStore{replays: make(map[string]*game.Replay)}
+}
+
+// Save implements ReplayStore.
+func (m *MemoryReplayStore) Save(replay *game.Replay) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.replays[replay.ID]; !ok {
+		m.order = append(m.order, replay.ID)
+	}
+	m.replays[replay.ID] = replay
+	for len(m.order) > MemoryReplaysKept {
+		delete(m.replays, m.order[0])
+		m.order = m.order[1:]
+	}
+	return nil
+}
+
+// Get implements ReplayStore.
+func (m *MemoryReplayStore) Get(id string) (*game.Replay, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.replays[id], nil
+}
+
+// DirReplayStore is a ReplayStore that saves each replay in a JSON file in a directory.
+type DirReplayStore struct {
+	dir string
+}
+
+var _ ReplayStore = (*DirReplayStore)(nil)
+
+// NewDirReplayStore creates a DirReplayStore that saves the replays in dir, creating it if needed.
+func NewDirReplayStore(dir string) (*DirReplayStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create replays directory: %w", err)
+	}
+	return &DirReplayStore{dir: dir}, nil
+}
+
+// path returns the file of the replay, or an error if the ID is not a valid file name.
+func (d *DirReplayStore) path(id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return "", fmt.Errorf("invalid replay ID %q", id)
+	}
+	return filepath.Join(d.dir, id+".json"), nil
+}
+
+// Save implements ReplayStore.
+func (d *DirReplayStore) Save(replay *game.Replay) error {
+	path, err := d.path(replay.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(replay)
+	if err != nil {
+		return fmt.Errorf("failed to encode replay: %w", err)
+	}
+	return writeFileAtomic(path, data)
+}
+
+// Get implements ReplayStore.
+func (d *DirReplayStore) Get(id string) (*game.Replay, error) {
+	path, err := d.path(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replay: %w", err)
+	}
+	var replay game.Replay
+	if err := json.Unmarshal(data, &replay); err != nil {
+		return nil, fmt.Errorf("failed to parse replay %s: %w", id, err)
+	}
+	return &replay, nil
+}
+
+// saveReplay saves the replay of a finished game.
+// It doesn't need the table locked, and can be run in its own goroutine, so the store is not accessed with the
+// lock held.
+func (s *ServerState) saveReplay(replay *game.Replay) {
+	if err := s.Replays.Save(replay); err != nil {
+		klog.Errorf("saveReplay: Failed to save replay %s of table %s: %v", replay.ID, replay.TableID, err)
+		return
+	}
+	klog.Infof("saveReplay: Saved replay %s of table %s with %d events", replay.ID, replay.TableID, len(replay.Events))
+}
+
+// HandleReplay serves a replay in JSON, at /api/replays/<replay_id>.
+func (s *ServerState) HandleReplay(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/replays/")
+	if id == "" || strings.Contains(id, "/") {
+		http.Error(w, "invalid replay ID", http.StatusBadRequest)
+		return
+	}
+	replay, err := s.Replays.Get(id)
+	if err != nil {
+		klog.Errorf("HandleReplay: Failed to get replay %s: %v", id, err)
+		http.Error(w, "failed to get replay", http.StatusInternalServerError)
+		return
+	}
+	if replay == nil {
+		http.Error(w, "replay not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(replay); err != nil {
+		klog.Errorf("HandleReplay: Failed to write response: %v", err)
+	}
+}
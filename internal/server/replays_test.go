@@ -0,0 +1,103 @@
+package server
+
+import (
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/janpfeifer/GoSpot/internal/game"
+)
+
+func TestDirReplayStore(t *testing.T) {
+	store, err := NewDirReplayStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	replay := &game.Replay{ID: game.NewReplayID(), TableID: "t1", Events: []game.GameEvent{{Type: game.EventDeal}}}
+	if err := store.Save(replay); err != nil {
+		t.Fatalf("Failed to save replay: %v", err)
+	}
+	got, err := store.Get(replay.ID)
+	if err != nil || got == nil || got.TableID != "t1" || len(got.Events) != 1 {
+		t.Fatalf("Expected the saved replay, got %+v, %v", got, err)
+	}
+	if got, err := store.Get("missing"); err != nil || got != nil {
+		t.Errorf("Expected no replay for an unknown ID, got %+v, %v", got, err)
+	}
+	if _, err := store.Get("../players"); err == nil {
+		t.Errorf("Expected an error for an ID that is not a file name")
+	}
+}
+
+func TestGameReplay(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		s := NewServerState()
+		tableID := "test-replay"
+		alice := &game.Player{ID: "p1", Name: "Alice", Symbol: 1}
+		bob := &game.Player{ID: "p2", Name: "Bob", Symbol: 2, Latency: 50 * time.Millisecond}
+		table := &game.Table{
+			ID:       tableID,
+			Name:     tableID,
+			Players:  []*game.Player{alice, bob},
+			Settings: game.DefaultTableSettings(),
+		}
+		table.Settings.CardsPerPlayer = 2
+		table.Settings.PlayerSymbolBonus = false
+		s.addTable(table, nil)
+
+		testLock(t, s, table)
+		s.handleGameStart(table, alice, &game.StartMessage{})
+		replayID := table.ReplayID
+		testUnlock(s, table)
+		if replayID == "" {
+			t.Fatalf("Expected the game to have a replay ID")
+		}
+
+		// Alice clicks a wrong symbol, then both Bob and Alice find their matches, and Alice plays to the end.
+		testLock(t, s, table)
+		wrong := botMistake(table, alice)
+		s.handleClick(table, alice, &game.ClickMessage{Symbol: wrong, Round: 1})
+		testUnlock(s, table)
+		time.Sleep(table.Settings.PenaltyDuration)
+		synctest.Wait()
+		for !table.Finished {
+			testLock(t, s, table)
+			round := table.Round
+			if symbol := botMatch(table, bob); symbol >= 0 {
+				s.handleClick(table, bob, &game.ClickMessage{Symbol: symbol, Round: round})
+			}
+			s.handleClick(table, alice, &game.ClickMessage{Symbol: botMatch(table, alice), Round: round})
+			testUnlock(s, table)
+			time.Sleep(time.Second)
+			synctest.Wait()
+		}
+
+		replay, err := s.Replays.Get(replayID)
+		if err != nil || replay == nil {
+			t.Fatalf("Expected the replay to be saved, got %v", err)
+		}
+		if len(replay.Players) != 2 || replay.WinnerID != table.WinnerID {
+			t.Errorf("Unexpected replay players %v or winner %q", replay.Players, replay.WinnerID)
+		}
+		counts := make(map[game.EventType]int)
+		for _, e := range replay.Events {
+			counts[e.Type]++
+			if e.Type == game.EventClick && e.PlayerID == alice.ID && e.Delay != bob.Latency {
+				t.Errorf("Expected Alice's clicks to be delayed by Bob's latency %v, got %v", bob.Latency, e.Delay)
+			}
+		}
+		if first := replay.Events[0]; first.Type != game.EventDeal || first.Snapshot == nil || len(first.Snapshot.TopCards) != 2 {
+			t.Errorf("Expected the replay to start with the deal, got %+v", first)
+		}
+		if counts[game.EventReject] != 1 || counts[game.EventFinish] != 2 || counts[game.EventGameOver] != 1 {
+			t.Errorf("Unexpected events: %v", counts)
+		}
+		// Each round is won by one of the two clicks.
+		if counts[game.EventRoundWin] != table.Round-1 || counts[game.EventClick] < counts[game.EventRoundWin] {
+			t.Errorf("Unexpected clicks and round wins: %v, %d rounds", counts, table.Round-1)
+		}
+		if steps := replay.Steps(); len(steps) != counts[game.EventRoundWin]+1 {
+			t.Errorf("Expected one step per card, plus the deal, got %d", len(steps))
+		}
+	})
+}
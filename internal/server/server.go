@@ -59,15 +59,94 @@ func (l *pipeListener) dial() (net.Conn, error) {
 	}
 }
 
+// Config configures the server started by RunWithConfig.
+type Config struct {
+	// Addr to listen on. If empty, it listens on an automatic port on the localhost interface.
+	// If NetPipeAddr, it listens on in-memory pipes, see ServerState.LocalDial.
+	Addr string
+
+	// Players keeps the statistics of the players. If nil, they are only kept in memory.
+	Players PlayerStore
+
+	// Leaderboard keeps the best solo games. If nil, they are only kept in memory.
+	Leaderboard *Leaderboard
+
+	// Replays keeps the logs of the finished games. If nil, the last ones are kept in memory.
+	Replays ReplayStore
+
+	// MatchLatencySpread, if not zero, is the maximum difference of latencies of the players of a quick match,
+	// see Matchmaker.LatencySpread.
+	MatchLatencySpread time.Duration
+
+	// JSONOnly disables the compact binary encoding: all messages are sent as JSON, to debug the protocol.
+	JSONOnly bool
+
+	// SnapshotFile, if set, is where the tables are saved when the server shuts down, and restored from when it
+	// starts, so the games in progress survive restarts.
+	SnapshotFile string
+
+	// InstanceID identifies this server among the instances that share their tables. If empty, a random one.
+	InstanceID string
+
+	// TableStore keeps which instance runs each table. If nil, the tables are kept in memory, and not shared.
+	TableStore TableStore
+
+	// Broker relays the clients of the tables run by other instances. If nil, only the instances of this
+	// process can be reached.
+	Broker Broker
+
+	// AdminToken, if set, enables the admin API and dashboard, at /admin, for the requests with this token.
+	AdminToken string
+}
+
+// shutdownTimeout is how long each phase of the shutdown can take: waiting for the HTTP requests in progress, and
+// then for the clients to be told the server is restarting.
+const shutdownTimeout = 5 * time.Second
+
 // Run starts the server and blocks until the context is canceled.
 // If addr is empty, it listens on an automatic port on the localhost interface.
 // It sends the actual address it's listening on to the started channel if it's not nil.
 func Run(ctx context.Context, addr string, started chan<- *ServerState) error {
+	return RunWithConfig(ctx, Config{Addr: addr}, started)
+}
+
+// RunWithConfig starts the server configured by cfg and blocks until the context is canceled.
+// It sends the actual address it's listening on to the started channel if it's not nil.
+func RunWithConfig(ctx context.Context, cfg Config, started chan<- *ServerState) error {
+	addr := cfg.Addr
 	if addr == "" {
 		addr = "127.0.0.1:0"
 	}
 
 	serverState := NewServerState()
+	if cfg.Players != nil {
+		serverState.Players = cfg.Players
+	}
+	if cfg.Leaderboard != nil {
+		serverState.Leaderboard = cfg.Leaderboard
+	}
+	if cfg.Replays != nil {
+		serverState.Replays = cfg.Replays
+	}
+	serverState.Matchmaker.LatencySpread = cfg.MatchLatencySpread
+	if cfg.JSONOnly {
+		serverState.Encodings = []game.Encoding{game.EncodingJSON}
+	}
+	if cfg.InstanceID != "" {
+		serverState.InstanceID = cfg.InstanceID
+	}
+	if cfg.TableStore != nil {
+		serverState.TableStore = cfg.TableStore
+	}
+	if cfg.Broker != nil {
+		serverState.Broker = cfg.Broker
+	}
+	serverState.AdminToken = cfg.AdminToken
+	if cfg.SnapshotFile != "" {
+		if err := serverState.restoreSnapshot(cfg.SnapshotFile); err != nil {
+			klog.Errorf("Failed to restore the tables: %v", err)
+		}
+	}
 	var ln net.Listener
 	var err error
 	if addr == NetPipeAddr {
@@ -85,6 +164,13 @@ func Run(ctx context.Context, addr string, started chan<- *ServerState) error {
 	}
 	actualAddr := ln.Addr().String()
 
+	// Connections relayed by the other instances sharing the tables.
+	instancesLn, err := serverState.listenInstances()
+	if err != nil {
+		_ = ln.Close()
+		return err
+	}
+
 	// Initialize global lobby state for server-side prerendering without panic
 	frontend.InitState()
 
@@ -97,6 +183,9 @@ func Run(ctx context.Context, addr string, started chan<- *ServerState) error {
 	// Register go-app routes so the server knows how to prerender them
 	app.Route("/", func() app.Composer { return &frontend.Home{} })
 	app.RouteWithRegexp("^/table/.*", func() app.Composer { return &frontend.Table{} })
+	app.Route("/profile", func() app.Composer { return &frontend.Profile{} })
+	app.Route("/leaderboard", func() app.Composer { return &frontend.Leaderboard{} })
+	app.RouteWithRegexp("^/replay/.*", func() app.Composer { return &frontend.ReplayViewer{} })
 
 	// The web assets and the compiled webassembly
 	// are served natively by the go-app framework
@@ -137,6 +226,25 @@ func Run(ctx context.Context, addr string, started chan<- *ServerState) error {
 	// Register WebSocket endpoint
 	mux.HandleFunc("/ws", serverState.HandleWS)
 
+	// Metrics, in the Prometheus text format.
+	mux.HandleFunc("/metrics", serverState.HandleMetrics)
+
+	// Admin dashboard and API, only enabled with an admin token.
+	mux.HandleFunc("/admin", serverState.HandleAdminPage)
+	mux.HandleFunc("/admin/api/", serverState.HandleAdmin)
+
+	// Register the open tables endpoint, used by the Home page
+	mux.HandleFunc("/api/tables", serverState.HandleOpenTables)
+
+	// Register the players' statistics endpoint, used by the /profile page
+	mux.HandleFunc("/api/players/", serverState.HandlePlayerStats)
+
+	// Register the solo leaderboard endpoint, used by the /leaderboard page
+	mux.HandleFunc("/api/leaderboard", serverState.HandleLeaderboard)
+
+	// Register the replays endpoint, used by the /replay/<id> page
+	mux.HandleFunc("/api/replays/", serverState.HandleReplay)
+
 	// Register test game endpoint
 	mux.HandleFunc("/test/game", serverState.HandleTestGame)
 	mux.HandleFunc("/test/game/", serverState.HandleTestGame)
@@ -157,12 +265,40 @@ func Run(ctx context.Context, addr string, started chan<- *ServerState) error {
 		}
 	}()
 
-	<-ctx.Done()
+	go func() {
+		klog.Infof("Instance %s accepting connections from other instances", serverState.InstanceID)
+		if err := srv.Serve(instancesLn); err != nil && err != http.ErrServerClosed {
+			klog.Infof("Server error: %v", err)
+		}
+	}()
+
+	go serverState.runMatchmaker(ctx)
 
-	// Graceful shutdown with 5 second timeout
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	<-ctx.Done()
 
+	// Graceful shutdown: the listeners are closed first, so the clients told to reconnect don't join this
+	// instance again. Each phase has its own deadline, so a slow one doesn't skip the next.
 	klog.Infof("Shutting down server...")
-	return srv.Shutdown(shutdownCtx)
+	httpCtx, cancelHTTP := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelHTTP()
+	err = srv.Shutdown(httpCtx)
+
+	// Save the tables, and tell their clients to reconnect to the next instance.
+	snap, outs := serverState.shutdownTables()
+	if cfg.SnapshotFile != "" {
+		if err := snap.save(cfg.SnapshotFile); err != nil {
+			klog.Errorf("Failed to save the tables: %v", err)
+		} else {
+			klog.Infof("Saved %d tables to %s", len(snap.Tables), cfg.SnapshotFile)
+		}
+	}
+	clientsCtx, cancelClients := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelClients()
+	for _, out := range outs {
+		select {
+		case <-out.done:
+		case <-clientsCtx.Done():
+		}
+	}
+	return err
 }
@@ -0,0 +1,219 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/janpfeifer/GoSpot/internal/game"
+	"k8s.io/klog/v2"
+)
+
+// snapshotVersion of the format of the snapshot file: snapshots of other versions are not restored.
+const snapshotVersion = 1
+
+// restartNotice is sent to the clients in the game.RestartMessage, when the server shuts down.
+const restartNotice = "Server restarting, reconnecting…"
+
+// snapshot is the state of the tables saved when the server shuts down, and restored by the next instance,
+// so the games in progress survive restarts (e.g.: redeploys).
+type snapshot struct {
+	Version int          `json:"version"`
+	SavedAt time.Time    `json:"saved_at"`
+	Tables  []savedTable `json:"tables"`
+}
+
+// savedTable is a table in a snapshot, including the fields that are never sent to the clients.
+// The server timers are not saved: a click pending when the server shut down is lost, and penalties are lifted.
+type savedTable struct {
+	Table   *game.Table        `json:"table"`
+	Players []savedPlayer      `json:"players"` // Hidden fields of Table.Players, in the same order
+	Pile    [][]int            `json:"pile,omitempty"`
+	Grid    [][]int            `json:"grid,omitempty"`
+	Chat    []game.ChatMessage `json:"chat,omitempty"`
+	Replay  *game.Replay       `json:"replay,omitempty"`
+}
+
+// savedPlayer holds the fields of a player that are never sent to the clients.
+type savedPlayer struct {
+	Hand          [][]int `json:"hand,omitempty"`
+	Matches       int     `json:"matches,omitempty"`
+	BonusDiscards int     `json:"bonus_discards,omitempty"`
+	CardsDealt    int     `json:"cards_dealt,omitempty"`
+}
+
+// saveTableLocked returns the state of the table to save in a snapshot.
+// Assumes the table is locked.
+func saveTableLocked(table *game.Table) savedTable {
+	saved := savedTable{
+		Table:  table,
+		Pile:   table.Pile,
+		Grid:   table.Grid,
+		Chat:   table.Chat,
+		Replay: table.Replay,
+	}
+	for _, p := range table.Players {
+		saved.Players = append(saved.Players, savedPlayer{
+			Hand:          p.Hand,
+			Matches:       p.Matches,
+			BonusDiscards: p.BonusDiscards,
+			CardsDealt:    p.CardsDealt,
+		})
+	}
+	return saved
+}
+
+// shutdownTables closes all tables when the server shuts down: their state is returned in a snapshot, and their
+// clients are told the server is restarting and disconnected, so they reconnect to the next instance.
+// It also returns the outboxes of the clients, which are done once the last messages are written.
+func (s *ServerState) shutdownTables() (*snapshot, []*outbox) {
+	s.mu.RLock()
+	tables := slices.Collect(maps.Values(s.Tables))
+	s.mu.RUnlock()
+
+	snap := &snapshot{Version: snapshotVersion, SavedAt: time.Now()}
+	restartMsg, _ := game.NewWsMessage(game.MsgTypeRestart, game.RestartMessage{Message: restartNotice})
+	var outs []*outbox
+	for _, table := range tables {
+		room := s.lockTable(table)
+		if room == nil {
+			continue
+		}
+		snap.Tables = append(snap.Tables, saveTableLocked(table))
+		for _, c := range room.clients {
+			c.send(restartMsg)
+			c.out.closeAfter(websocket.StatusServiceRestart, "Server restarting")
+			outs = append(outs, c.out)
+		}
+		// Once removed, the pending timers and the clients leaving the table find it's gone, and do nothing.
+		if table.ClickTimer != nil {
+			table.ClickTimer.Stop()
+		}
+		stopBotsLocked(table)
+		s.removeTableLocked(table)
+		room.mu.Unlock()
+	}
+	klog.Infof("shutdownTables: Closed %d tables, with %d connections", len(snap.Tables), len(outs))
+	return snap, outs
+}
+
+// save writes the snapshot to the file in path.
+// The tables were removed from the server, so they are no longer changed.
+func (snap *snapshot) save(path string) error {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	return writeFileAtomic(path, data)
+}
+
+// restoreSnapshot restores the tables saved in the snapshot file by the previous instance, if there is one.
+// The file is deleted once the tables are restored, so a later crash doesn't bring back stale games. A file that
+// can't be restored (corrupt, or of another version) is moved to path+".bad" instead.
+//
+// The players are given ReconnectGracePeriod to reconnect, as if they had just lost the connection, and the
+// downtime is not counted in the time they take to finish.
+func (s *ServerState) restoreSnapshot(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		klog.Infof("restoreSnapshot: %s doesn't exist, no tables to restore", path)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return errors.Join(fmt.Errorf("failed to parse snapshot %s: %w", path, err), setAsideSnapshot(path))
+	}
+	if snap.Version != snapshotVersion {
+		klog.Warningf("restoreSnapshot: Discarding snapshot %s of version %d, expected %d", path, snap.Version, snapshotVersion)
+		return setAsideSnapshot(path)
+	}
+
+	downtime := time.Since(snap.SavedAt)
+	tournaments := make(map[string]*game.Tournament)
+	locks := make(map[string]*sync.Mutex)
+	var restored int
+	for _, saved := range snap.Tables {
+		table := saved.Table
+		if table == nil {
+			continue
+		}
+		// The tables of a tournament share it, and its lock.
+		var mu *sync.Mutex
+		if t := table.Tournament; t != nil {
+			if shared := tournaments[t.ID]; shared != nil {
+				table.Tournament = shared
+			} else {
+				tournaments[t.ID] = t
+				locks[t.ID] = &sync.Mutex{}
+			}
+			mu = locks[t.ID]
+		}
+		s.addTable(table, mu)
+		room := s.lockTable(table)
+		if room == nil {
+			continue
+		}
+		s.restoreTableLocked(table, saved, downtime)
+		if !s.deleteIfAbandonedLocked(table) {
+			restored++
+		}
+		room.mu.Unlock()
+	}
+	klog.Infof("restoreSnapshot: Restored %d tables saved %v ago", restored, downtime)
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove snapshot %s: %w", path, err)
+	}
+	return nil
+}
+
+// setAsideSnapshot renames a snapshot file that can't be restored to path+".bad", so it's not restored again,
+// but can still be inspected.
+func setAsideSnapshot(path string) error {
+	if err := os.Rename(path, path+".bad"); err != nil {
+		return fmt.Errorf("failed to set aside snapshot %s: %w", path, err)
+	}
+	klog.Warningf("restoreSnapshot: Moved snapshot %s to %s.bad", path, path)
+	return nil
+}
+
+// restoreTableLocked restores the fields of the table not sent to the clients, and restarts its timers.
+// Assumes the table is locked.
+func (s *ServerState) restoreTableLocked(table *game.Table, saved savedTable, downtime time.Duration) {
+	table.Pile, table.Grid, table.Chat, table.Replay = saved.Pile, saved.Grid, saved.Chat, saved.Replay
+	// Spectators join again when they reconnect.
+	table.Spectators = nil
+	if table.Started && !table.Finished {
+		table.StartTime = table.StartTime.Add(downtime)
+		// The times of the events logged from now on are relative to the start of the game too.
+		if table.Replay != nil {
+			table.Replay.StartTime = table.Replay.StartTime.Add(downtime)
+		}
+		// Only tie-breaks are drawn after the deal, so a restored game may break ties differently than its replay.
+		table.Rand = game.NewRand(table.Seed)
+	}
+	for i, p := range table.Players {
+		if i < len(saved.Players) {
+			hidden := saved.Players[i]
+			p.Hand, p.Matches, p.BonusDiscards, p.CardsDealt = hidden.Hand, hidden.Matches, hidden.BonusDiscards, hidden.CardsDealt
+		}
+		p.InPenalty = false
+		if p.Bot != "" {
+			continue
+		}
+		p.Disconnected = true
+		p.DisconnectTimer = time.AfterFunc(ReconnectGracePeriod, func() {
+			s.expireDisconnected(table, p)
+		})
+	}
+	s.scheduleBotsLocked(table)
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/janpfeifer/GoSpot/internal/game"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	synctest.Test(t, func(t *testing.T) {
+		// The clients outlive the first server.
+		clientCtx, cancelClients := context.WithCancel(context.Background())
+		defer cancelClients()
+		tableID := "test-snapshot"
+		cfg := Config{Addr: NetPipeAddr, SnapshotFile: path}
+
+		// First instance: Alice and Bob start a game.
+		ctx, cancel := context.WithCancel(context.Background())
+		started := make(chan *ServerState, 1)
+		done := make(chan error, 1)
+		go func() { done <- RunWithConfig(ctx, cfg, started) }()
+		s := <-started
+		wsURL := "ws://" + s.Address + "/ws"
+
+		// Each connection reports how it was closed, once it got the restart message.
+		restarts := make(chan websocket.StatusCode, 2)
+		var conns []*websocket.Conn
+		for _, p := range []struct{ id, name string }{{"p1", "Alice"}, {"p2", "Bob"}} {
+			conn, err := testConnectAndJoin(clientCtx, s, wsURL, tableID, p.id, p.name, 0, 0)
+			if err != nil {
+				t.Fatalf("%s failed to join: %v", p.name, err)
+			}
+			defer conn.CloseNow()
+			conns = append(conns, conn)
+			go func() {
+				var restarting bool
+				for {
+					var msg game.WsMessage
+					if err := wsjson.Read(clientCtx, conn, &msg); err != nil {
+						if restarting {
+							restarts <- websocket.CloseStatus(err)
+						}
+						return
+					}
+					restarting = restarting || msg.Type == game.MsgTypeRestart
+				}
+			}()
+		}
+		startMsg, _ := game.NewWsMessage(game.MsgTypeStart, nil)
+		if err := wsjson.Write(clientCtx, conns[0], startMsg); err != nil {
+			t.Fatalf("Failed to start the game: %v", err)
+		}
+		synctest.Wait()
+
+		table, unlock := testLockTableID(s, tableID)
+		if table == nil || !table.Started {
+			unlock()
+			t.Fatalf("Expected the game to be started on table %s, got %v", tableID, table)
+		}
+		round, target := table.Round, slices.Clone(table.TargetCard)
+		hands := make(map[string][][]int)
+		for _, p := range table.Players {
+			hands[p.ID] = slices.Clone(p.Hand)
+		}
+		unlock()
+
+		// Shutting down saves the table, and tells the clients to reconnect.
+		cancel()
+		if err := <-done; err != nil {
+			t.Fatalf("Server failed to shut down: %v", err)
+		}
+		for range conns {
+			if status := <-restarts; status != websocket.StatusServiceRestart {
+				t.Errorf("Expected the connection to be closed with %v, got %v", websocket.StatusServiceRestart, status)
+			}
+		}
+
+		// Second instance, a minute later: the table is restored, and the players are given time to reconnect.
+		time.Sleep(time.Minute)
+		ctx, cancel = context.WithCancel(context.Background())
+		defer cancel()
+		go func() { done <- RunWithConfig(ctx, cfg, started) }()
+		s = <-started
+		wsURL = "ws://" + s.Address + "/ws"
+		if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Expected the snapshot to be deleted once restored, got %v", err)
+		}
+		table, unlock = testLockTableID(s, tableID)
+		if table == nil {
+			t.Fatalf("Expected table %s to be restored", tableID)
+		}
+		if !table.Started || table.Round != round || !slices.Equal(table.TargetCard, target) {
+			t.Errorf("Expected round %d with target card %v, got %s", round, target, table)
+		}
+		// The downtime is not counted in the game, nor in the times of its replay.
+		var replayStart time.Time
+		if table.Replay != nil {
+			replayStart = table.Replay.StartTime
+		}
+		if !replayStart.Equal(table.StartTime) || time.Since(table.StartTime) > time.Second {
+			t.Errorf("Expected the game and its replay to start later by the downtime, got %v and %v", table.StartTime, replayStart)
+		}
+		for _, p := range table.Players {
+			if !p.Disconnected || !slices.EqualFunc(p.Hand, hands[p.ID], slices.Equal) {
+				t.Errorf("Expected %s to be restored disconnected, with the same hand, got %+v", p.Name, p)
+			}
+		}
+		unlock()
+
+		// Alice resumes her seat, while Bob doesn't come back.
+		alice, err := testConnectAndJoin(clientCtx, s, wsURL, tableID, "p1", "Alice", 0, 0)
+		if err != nil {
+			t.Fatalf("Alice failed to reconnect: %v", err)
+		}
+		defer alice.CloseNow()
+		go func() {
+			for {
+				var msg game.WsMessage
+				if err := wsjson.Read(clientCtx, alice, &msg); err != nil {
+					return
+				}
+			}
+		}()
+		time.Sleep(ReconnectGracePeriod + time.Second)
+		synctest.Wait()
+		testLock(t, s, table)
+		defer testUnlock(s, table)
+		if len(table.Players) != 1 || table.Players[0].Disconnected ||
+			!slices.EqualFunc(table.Players[0].Hand, hands["p1"], slices.Equal) {
+			t.Errorf("Expected only Alice to be left, with her hand, got %s", table)
+		}
+	})
+}
+
+func TestSnapshotSetAside(t *testing.T) {
+	for name, data := range map[string]string{
+		"corrupt": `{"version": 1, "tables": [`,
+		"version": `{"version": 1000, "tables": []}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "snapshot.json")
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatalf("Failed to write snapshot: %v", err)
+			}
+			s := NewServerState()
+			err := s.restoreSnapshot(path)
+			if name == "corrupt" && err == nil {
+				t.Errorf("Expected an error restoring a corrupt snapshot")
+			}
+			if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Expected the snapshot to be moved, got %v", err)
+			}
+			if kept, err := os.ReadFile(path + ".bad"); err != nil || string(kept) != data {
+				t.Errorf("Expected the snapshot to be kept in %s.bad, got %q, %v", path, kept, err)
+			}
+		})
+	}
+}
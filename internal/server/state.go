@@ -1,14 +1,16 @@
 package server
 
 import (
-	"context"
+	"cmp"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/rand"
 	"net"
 	"net/http"
 	"net/url"
 	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -19,22 +21,77 @@ import (
 )
 
 // ServerState manages all active tables and WebSockets.
+//
+// Its lock mu only guards the registry of tables: each table has its own lock, see tableRoom.
 type ServerState struct {
-	Address      string
-	mu           sync.RWMutex
-	Tables       map[string]*game.Table
-	TableClients map[string]map[*websocket.Conn]string // TableID -> Conn -> PlayerID
+	Address string
+	mu      sync.RWMutex
+	Tables  map[string]*game.Table
+
+	// rooms holds the connections and the lock of each table in Tables.
+	rooms map[*game.Table]*tableRoom
+
+	// OutboxSize is the maximum number of messages queued to a connection: clients that fall further behind are
+	// disconnected. If 0, DefaultOutboxSize is used.
+	OutboxSize int
+
+	// Encodings the server supports: clients that don't request one of them use game.EncodingJSON.
+	Encodings []game.Encoding
+
+	// Features of the protocol the server supports, see game.NegotiateFeatures.
+	Features []game.Feature
+
+	// InstanceID identifies this server among the instances that share their tables.
+	InstanceID string
+
+	// TableStore keeps which instance runs each table.
+	TableStore TableStore
+
+	// Broker relays the clients of the tables run by other instances.
+	Broker Broker
+
+	// Metrics of the server, served at /metrics.
+	Metrics *Metrics
+
+	// AdminToken protects the admin API, see HandleAdmin. If empty, the admin API is disabled.
+	AdminToken string
 
 	// LocalDial allows local clients to connect directly to the server,
 	// bypassing TCP. It is only non-nil if the server was started with NetPipeAddr.
 	LocalDial func() (net.Conn, error)
+
+	// Players keeps the statistics of the players across games.
+	Players PlayerStore
+
+	// Leaderboard keeps the best solo games.
+	Leaderboard *Leaderboard
+
+	// Replays keeps the logs of the finished games.
+	Replays ReplayStore
+
+	// Matchmaker keeps the quick match queue.
+	Matchmaker *Matchmaker
+
+	// NewSeed returns the seed for each new game, see game.Table.Seed.
+	// If nil, seeds are drawn from the current time. Tests can set it to get reproducible games.
+	NewSeed func() int64
 }
 
 // NewServerState creates a new ServerState.
 func NewServerState() *ServerState {
 	return &ServerState{
-		Tables:       make(map[string]*game.Table),
-		TableClients: make(map[string]map[*websocket.Conn]string),
+		Tables:      make(map[string]*game.Table),
+		rooms:       make(map[*game.Table]*tableRoom),
+		Encodings:   game.Encodings,
+		Features:    game.Features,
+		InstanceID:  newInstanceID(),
+		TableStore:  NewMemoryTableStore(),
+		Broker:      NewMemoryBroker(),
+		Metrics:     NewMetrics(),
+		Players:     NewMemoryPlayerStore(),
+		Leaderboard: NewMemoryLeaderboard(),
+		Replays:     NewMemoryReplayStore(),
+		Matchmaker:  NewMatchmaker(),
 	}
 }
 
@@ -50,53 +107,130 @@ func (s *ServerState) HandleWS(w http.ResponseWriter, r *http.Request) {
 	}
 	defer conn.CloseNow()
 
-	// 1. Wait for join message from client
+	// 1. Wait for the handshake, if the client negotiates the protocol, and the join message from client
 	var wsMsg game.WsMessage
-	err = wsjson.Read(r.Context(), conn, &wsMsg)
+	readInitial := func() (any, error) {
+		if err := wsjson.Read(r.Context(), conn, &wsMsg); err != nil {
+			return nil, fmt.Errorf("failed to read initial msg: %w", err)
+		}
+		klog.Infof("HandleWS: Received initial message type: %s", wsMsg.Type)
+		s.Metrics.message(messageReceived, wsMsg.Type)
+		return wsMsg.Parse()
+	}
+	genericMsg, err := readInitial()
 	if err != nil {
-		klog.Errorf("HandleWS: Failed to read initial msg: %v", err)
+		klog.Errorf("HandleWS: %v", err)
 		return
 	}
-	klog.Infof("HandleWS: Received initial message type: %s", wsMsg.Type)
 
-	genericMsg, err := wsMsg.Parse()
-	if err != nil {
-		klog.Errorf("HandleWS: Failed to parse message: %v", err)
+	encoding := game.EncodingJSON
+	var features []game.Feature
+	hello, _ := genericMsg.(*game.HelloMessage)
+	var welcome game.WelcomeMessage
+	if hello != nil {
+		welcome = s.welcome(hello)
+		welcomeMsg, _ := game.NewWsMessage(game.MsgTypeWelcome, welcome)
+		_ = wsjson.Write(r.Context(), conn, welcomeMsg)
+		if welcome.Error != "" {
+			klog.Warningf("HandleWS: Refusing client version %s (protocol %d): %s", hello.Version, hello.ProtocolVersion, welcome.Error)
+			_ = conn.Close(websocket.StatusPolicyViolation, "Incompatible protocol version")
+			return
+		}
+		encoding, features = welcome.Encoding, welcome.Features
+		if genericMsg, err = readInitial(); err != nil {
+			klog.Errorf("HandleWS: %v", err)
+			return
+		}
+	} else if err := game.CheckProtocolVersion(0); err != nil {
+		klog.Warningf("HandleWS: Refusing client without handshake: %v", err)
+		errorMsg, _ := game.NewWsMessage(game.MsgTypeError, game.ErrorMessage{Message: err.Error()})
+		_ = wsjson.Write(r.Context(), conn, errorMsg)
+		_ = conn.Close(websocket.StatusPolicyViolation, "Incompatible protocol version")
 		return
 	}
 
+	// From now on, all messages to the client go through its outbox, so they are written in order.
+	out := newOutbox(conn, s.OutboxSize, s.Metrics)
+	defer out.stop()
+
 	// Parse JoinMessage:
 	var tableID string
 	var p game.Player
+	var spectator bool
 	switch msg := genericMsg.(type) {
 	case *game.JoinMessage:
 		tableID = msg.TableID
 		p = msg.Player
+		spectator = msg.Spectator
+	case *game.QueueMessage:
+		p = msg.Player
+		if p.ID == "" {
+			p.ID = game.NewPlayerID()
+		}
+		s.handleQueue(r.Context(), conn, out, p)
+		return
 	default:
-		klog.Errorf("HandleWS: Expected first message to be a Join message, got: %s", wsMsg.Type)
+		klog.Errorf("HandleWS: Expected first message to be a Join or Queue message, got: %s", wsMsg.Type)
 		return
 	}
 
 	// Ensure player ID
 	if p.ID == "" { // Should be generated by client, but fallback
-		p.ID = fmt.Sprintf("%d", time.Now().UnixNano())
+		p.ID = game.NewPlayerID()
+	}
+
+	// Tables run by other instances are relayed to them.
+	for owner := s.tableOwner(tableID); owner != s.InstanceID; owner = s.tableOwner(tableID) {
+		join := genericMsg.(*game.JoinMessage)
+		join.Player = p
+		err := s.relayTable(r.Context(), conn, out, owner, hello, welcome, join)
+		if !errors.Is(err, errInstanceUnreachable) {
+			if err != nil {
+				klog.Errorf("HandleWS: %v", err)
+			}
+			return
+		}
+		klog.Warningf("HandleWS: Instance %s running table %s doesn't answer, taking the table over", owner, tableID)
+		if err := s.TableStore.Release(tableID, owner); err != nil {
+			klog.Errorf("HandleWS: %v", err)
+			return
+		}
+	}
+	klog.Infof("HandleWS: Player %s (%s, Symbol: %d, spectator: %t) joining table %s", p.Name, p.ID, p.Symbol, spectator, tableID)
+	c := &client{
+		encoding: encoding,
+		deltas:   slices.Contains(features, game.FeatureDeltas),
+		out:      out,
 	}
-	klog.Infof("HandleWS: Player %s (%s, Symbol: %d) joining table %s", p.Name, p.ID, p.Symbol, tableID)
-	table, player := s.joinTable(tableID, p, conn)
-	klog.Infof("HandleWS: Table: %s", table)
+	s.Metrics.connected(p.ID)
+	defer s.Metrics.disconnected(p.ID)
+	table, player := s.joinTable(tableID, p, conn, c, spectator)
 
 	// Send initial Ping
-	pingMsg, _ := game.NewWsMessage(game.MsgTypePing, game.PingMessage{
-		ServerTime: time.Now().UnixNano(),
-	})
-	_ = wsjson.Write(r.Context(), conn, pingMsg)
+	if room := s.lockTable(table); room != nil {
+		klog.Infof("HandleWS: Table: %s", table)
+		c.sendEncoded(game.MsgTypePing, game.PingMessage{
+			ServerTime: time.Now().UnixNano(),
+		})
+
+		// Send the chat history of the table, in order.
+		for _, chat := range table.Chat {
+			chatMsg, err := game.NewWsMessage(game.MsgTypeChat, chat)
+			if err != nil {
+				klog.Errorf("HandleWS: Failed to create chat message: %v", err)
+				continue
+			}
+			c.send(chatMsg)
+		}
+		room.mu.Unlock()
+	}
 
 	// Disconnect handler
 	defer s.leaveTable(table, conn)
 
 	// 2. Read loop
 	for {
-		err = wsjson.Read(r.Context(), conn, &wsMsg)
+		wsMsg, err = readMessage(r.Context(), conn)
 		if websocket.CloseStatus(err) == websocket.StatusNormalClosure || websocket.CloseStatus(err) == websocket.StatusGoingAway {
 			klog.Infof("HandleWS: Client disconnected normally")
 			break
@@ -107,28 +241,42 @@ func (s *ServerState) HandleWS(w http.ResponseWriter, r *http.Request) {
 		}
 
 		klog.Infof("HandleWS: Received message type: %s", wsMsg.Type)
+		s.Metrics.message(messageReceived, wsMsg.Type)
 		s.tableHandleMessage(conn, table, player, wsMsg)
 	}
 }
 
-func (s *ServerState) joinTable(tableID string, p game.Player, conn *websocket.Conn) (*game.Table, *game.Player) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	table, ok := s.Tables[tableID]
-	if !ok {
-		klog.Infof("joinTable: Creating new table %s", tableID)
-		// Auto-create table
-		table = &game.Table{
-			ID:      tableID,
-			Name:    tableID, // Client can optionally rename later
-			Players: make([]*game.Player, 0),
+// joinTable adds the connection, with its client c, to the table, creating the table if needed.
+// It returns the table and the player (or spectator) associated with the connection.
+func (s *ServerState) joinTable(tableID string, p game.Player, conn *websocket.Conn, c *client, spectator bool) (*game.Table, *game.Player) {
+	var table *game.Table
+	var room *tableRoom
+	for room == nil {
+		s.mu.RLock()
+		table = s.Tables[tableID]
+		s.mu.RUnlock()
+		if table == nil {
+			// Claimed before locking s.mu, since the TableStore may be slow: registered tables are already claimed.
+			s.claimTable(tableID)
+		}
+		s.mu.Lock()
+		table = s.Tables[tableID]
+		if table == nil {
+			klog.Infof("joinTable: Creating new table %s", tableID)
+			// Auto-create table
+			table = &game.Table{
+				ID:       tableID,
+				Name:     tableID, // Client can optionally rename later
+				Players:  make([]*game.Player, 0),
+				Settings: game.DefaultTableSettings(),
+			}
+			s.addTableRegistryLocked(table, nil)
 		}
-		s.Tables[tableID] = table
-		s.TableClients[tableID] = make(map[*websocket.Conn]string)
+		s.mu.Unlock()
+		// The table may be removed before it's locked: then try again.
+		room = s.lockTable(table)
 	}
-
-	klog.Infof("joinTable: Adding player %q (Symbol: %d) to table %s", p.Name, p.Symbol, tableID)
+	defer room.mu.Unlock()
 
 	// Check if already in
 	var player *game.Player
@@ -138,6 +286,20 @@ func (s *ServerState) joinTable(tableID string, p game.Player, conn *websocket.C
 			break
 		}
 	}
+	if player == nil && table.Started && !spectator {
+		// There are no cards for players joining mid-game, they can only watch.
+		klog.Infof("joinTable: Game on table %s already started, %q joins as spectator", tableID, p.Name)
+		spectator = true
+	}
+	if player == nil && !spectator && len(table.Players) >= table.Settings.MaxPlayers {
+		klog.Infof("joinTable: Table %s is full (%d players), %q joins as spectator", tableID, len(table.Players), p.Name)
+		spectator = true
+	}
+	if spectator {
+		return table, s.joinAsSpectatorLocked(table, p, conn, c)
+	}
+
+	klog.Infof("joinTable: Adding player %q (Symbol: %d) to table %s", p.Name, p.Symbol, tableID)
 	if player == nil {
 		player = &game.Player{
 			ID:     p.ID,
@@ -149,8 +311,18 @@ func (s *ServerState) joinTable(tableID string, p game.Player, conn *websocket.C
 		// Update name and symbol in case they changed or were missing
 		player.Name = p.Name
 		player.Symbol = p.Symbol
+		if player.Disconnected {
+			klog.Infof("joinTable: Player %q reconnected to table %s with %d cards", p.Name, tableID, len(player.Hand))
+			player.Disconnected = false
+			if player.DisconnectTimer != nil {
+				player.DisconnectTimer.Stop()
+				player.DisconnectTimer = nil
+			}
+		}
 	}
-	s.TableClients[tableID][conn] = player.ID
+	c.playerID = player.ID
+	room.clients[conn] = c
+	s.autoStartLocked(table)
 
 	s.broadcastStateLocked(table)
 	if table.Started {
@@ -159,102 +331,402 @@ func (s *ServerState) joinTable(tableID string, p game.Player, conn *websocket.C
 	return table, player
 }
 
+// joinAsSpectatorLocked adds the connection, with its client c, to the table as a spectator.
+// Assumes the table is locked.
+func (s *ServerState) joinAsSpectatorLocked(table *game.Table, p game.Player, conn *websocket.Conn, c *client) *game.Player {
+	klog.Infof("joinTable: Adding spectator %q to table %s", p.Name, table.ID)
+	var spectator *game.Player
+	for _, sp := range table.Spectators {
+		if sp.ID == p.ID {
+			spectator = sp
+			break
+		}
+	}
+	if spectator == nil {
+		spectator = &game.Player{
+			ID:     p.ID,
+			Name:   p.Name,
+			Symbol: p.Symbol,
+		}
+		table.Spectators = append(table.Spectators, spectator)
+	}
+	c.playerID = spectator.ID
+	c.spectator = true
+	s.clientsLocked(table)[conn] = c
+
+	s.broadcastStateLocked(table)
+	if table.Started {
+		s.broadcastUpdateLocked(table, nil)
+	}
+	return spectator
+}
+
+// ReconnectGracePeriod is how long the seat (and hand) of a disconnected player is kept,
+// so they can reconnect and resume the game.
+var ReconnectGracePeriod = 60 * time.Second
+
 func (s *ServerState) leaveTable(table *game.Table, conn *websocket.Conn) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	room := s.lockTable(table)
+	if room == nil {
+		return
+	}
+	defer room.mu.Unlock()
 
-	clients, ok := s.TableClients[table.ID]
+	c, ok := room.clients[conn]
 	if !ok {
 		return
 	}
+	delete(room.clients, conn)
+	playerID := c.playerID
+
+	if c.spectator {
+		// Spectators have no seat to keep.
+		if !s.isSpectatingLocked(table, playerID) {
+			table.Spectators = slices.DeleteFunc(table.Spectators, func(p *game.Player) bool {
+				return p.ID == playerID
+			})
+		}
+		if !s.deleteIfAbandonedLocked(table) {
+			s.broadcastStateLocked(table)
+		}
+		return
+	}
 
-	playerID, ok := clients[conn]
-	if ok {
-		delete(clients, conn)
+	// Find the player
+	var player *game.Player
+	for _, p := range table.Players {
+		if p.ID == playerID {
+			player = p
+			break
+		}
+	}
 
-		// Find the player
-		var player *game.Player
-		for _, p := range table.Players {
-			if p.ID == playerID {
-				player = p
-				break
-			}
+	// The player may still be connected from elsewhere (e.g.: reconnected before the old connection timed out).
+	if player != nil && !s.isConnectedLocked(table, playerID) {
+		// Keep the seat (and the hand) for a grace period, so the player can reconnect.
+		klog.Infof("leaveTable: Player %s disconnected from table %s, keeping seat for %v", player.Name, table.ID, ReconnectGracePeriod)
+		player.Disconnected = true
+		if player.DisconnectTimer != nil {
+			player.DisconnectTimer.Stop()
 		}
+		player.DisconnectTimer = time.AfterFunc(ReconnectGracePeriod, func() {
+			s.expireDisconnected(table, player)
+		})
+	}
 
-		// Remove from players slice only if they haven't finished (Score > 0)
-		if player != nil && player.Score > 0 {
-			for i, p := range table.Players {
-				if p.ID == playerID {
-					table.Players = slices.Delete(table.Players, i, i+1)
-					break
-				}
-			}
-		} else if player != nil {
-			klog.Infof("Player %s disconnected but finished game, keeping in table.", player.Name)
+	if !s.deleteIfAbandonedLocked(table) {
+		s.broadcastStateLocked(table)
+	}
+}
+
+// isConnectedLocked returns whether the player has at least one active (non-spectator) connection to the table.
+// Assumes the table is locked.
+func (s *ServerState) isConnectedLocked(table *game.Table, playerID string) bool {
+	for _, c := range s.clientsLocked(table) {
+		if c.playerID == playerID && !c.spectator {
+			return true
 		}
+	}
+	return false
+}
 
-		// If table has no active connections, we delete it
-		if len(clients) == 0 {
-			klog.Infof("Table %s has no active connections, deleting.", table.ID)
-			delete(s.Tables, table.ID)
-			delete(s.TableClients, table.ID)
-		} else {
-			s.broadcastStateLocked(table)
+// isSpectatingLocked returns whether the spectator has at least one active spectator connection to the table.
+// Assumes the table is locked.
+func (s *ServerState) isSpectatingLocked(table *game.Table, spectatorID string) bool {
+	for _, c := range s.clientsLocked(table) {
+		if c.playerID == spectatorID && c.spectator {
+			return true
 		}
 	}
+	return false
 }
 
-// tableHandleMessage handles messages from a player in a table.
-// It is called from the locked tableHandleMessage function.
+// expireDisconnected is called when the reconnection grace period of a disconnected player expires.
+func (s *ServerState) expireDisconnected(table *game.Table, player *game.Player) {
+	room := s.lockTable(table)
+	if room == nil {
+		// Table is gone.
+		return
+	}
+	defer room.mu.Unlock()
+
+	if !player.Disconnected || player.DisconnectTimer == nil {
+		// Table is gone or player reconnected in the meantime.
+		return
+	}
+	player.DisconnectTimer = nil
+
+	// Remove from players slice only if they haven't finished, or if the game hasn't started.
+	if !table.Started || !player.Finished {
+		klog.Infof("expireDisconnected: Player %s did not reconnect, removing from table %s.", player.Name, table.ID)
+		table.Players = slices.DeleteFunc(table.Players, func(p *game.Player) bool {
+			return p == player
+		})
+		ensureHumanCreatorLocked(table)
+	} else {
+		klog.Infof("expireDisconnected: Player %s disconnected but finished game, keeping in table.", player.Name)
+	}
+
+	if !s.deleteIfAbandonedLocked(table) {
+		s.autoStartLocked(table)
+		s.broadcastStateLocked(table)
+	}
+}
+
+// deleteIfAbandonedLocked deletes the table if it has no active connections and no disconnected
+// players that may still reconnect. The tables of the rounds of a tournament are kept until it's over,
+// so the bots left play their games. It returns true if the table was deleted.
+// Assumes the table is locked.
+func (s *ServerState) deleteIfAbandonedLocked(table *game.Table) bool {
+	if len(s.clientsLocked(table)) > 0 {
+		return false
+	}
+	if t := table.Tournament; t != nil && t.ID != table.ID && !t.Over() && len(table.Players) > 0 {
+		return false
+	}
+	for _, p := range table.Players {
+		if p.DisconnectTimer != nil {
+			return false
+		}
+	}
+	klog.Infof("Table %s has no active connections, deleting.", table.ID)
+	s.removeTableLocked(table)
+	if table.ClickTimer != nil {
+		table.ClickTimer.Stop()
+	}
+	stopBotsLocked(table)
+	return true
+}
+
+// tableHandleMessage handles messages from a player in a table, with the table locked.
 func (s *ServerState) tableHandleMessage(conn *websocket.Conn, table *game.Table, player *game.Player, wsMsg game.WsMessage) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	room := s.lockTable(table)
+	if room == nil {
+		klog.Infof("tableHandleMessage: Table %s is gone, ignoring %s message", table.ID, wsMsg.Type)
+		return
+	}
+	defer room.mu.Unlock()
+	c := room.clients[conn]
+	if c == nil {
+		return
+	}
 
 	msgAny, err := wsMsg.Parse()
 	if err != nil {
+		if wsMsg.Ignorable(err) {
+			klog.V(1).Infof("tableHandleMessage: Ignoring optional message: %v", err)
+			return
+		}
 		klog.Errorf("tableHandleMessage: Failed to parse message: %v", err)
+		if errors.Is(err, game.ErrUnknownMessageType) {
+			c.sendEncoded(game.MsgTypeError, game.ErrorMessage{
+				Message: fmt.Sprintf("The server doesn't support %q messages, please reload the page", wsMsg.Type)})
+		}
 		return
 	}
+	spectator := c.spectator
 	switch msg := msgAny.(type) {
 	case *game.StartMessage:
+		if spectator {
+			klog.Errorf("tableHandleMessage: Spectator %s can't start the game on table %s", player.Name, table.ID)
+			return
+		}
 		s.handleGameStart(table, player, msg)
 
 	case *game.CancelMessage:
 		// Only creator (first player) can cancel
-		if len(table.Players) > 0 && table.Players[0].ID == player.ID {
+		if !spectator && len(table.Players) > 0 && table.Players[0].ID == player.ID {
 			klog.Infof("tableHandleMessage: Creator %s cancelled table %s", player.Name, table.ID)
 			// Notify everyone
 			errorMsg, _ := game.NewWsMessage(game.MsgTypeError, game.ErrorMessage{
 				Message: "Table was cancelled by creator.",
 			})
-			for c := range s.TableClients[table.ID] {
-				go func(conn *websocket.Conn) {
-					ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
-					defer cancel()
-					_ = wsjson.Write(ctx, conn, errorMsg)
-					_ = conn.Close(websocket.StatusNormalClosure, "Table cancelled")
-				}(c)
+			for _, other := range room.clients {
+				other.send(errorMsg)
+				other.out.closeAfter(websocket.StatusNormalClosure, "Table cancelled")
 			}
 			// Cleanup table
-			delete(s.Tables, table.ID)
-			delete(s.TableClients, table.ID)
+			stopBotsLocked(table)
+			s.removeTableLocked(table)
 		}
 	case *game.PongMessage:
 		rtt := time.Now().UnixNano() - msg.ServerTime
 		player.Latency = time.Duration(rtt / 2)
+		s.Metrics.observeRTT(player.ID, time.Duration(rtt))
 		klog.Infof("tableHandleMessage: Player %s latency: %v", player.Name, player.Latency)
 
 		// Update in table.Players slice
-		for _, tp := range table.Players {
-			if tp.ID == player.ID {
-				tp.Latency = player.Latency
-				break
+		if !spectator {
+			for _, tp := range table.Players {
+				if tp.ID == player.ID {
+					tp.Latency = player.Latency
+					break
+				}
 			}
 		}
 		s.broadcastStateLocked(table)
+	case *game.ChatMessage:
+		s.handleChat(c, table, player, msg)
+	case *game.SettingsMessage:
+		if spectator {
+			klog.Errorf("tableHandleMessage: Spectator %s can't change the settings of table %s", player.Name, table.ID)
+			return
+		}
+		s.handleSettings(table, player, msg)
+	case *game.AddBotMessage:
+		if spectator {
+			klog.Errorf("tableHandleMessage: Spectator %s can't add bots to table %s", player.Name, table.ID)
+			return
+		}
+		s.handleAddBot(table, player, msg)
+	case *game.RemoveBotMessage:
+		if spectator {
+			klog.Errorf("tableHandleMessage: Spectator %s can't remove bots from table %s", player.Name, table.ID)
+			return
+		}
+		s.handleRemoveBot(table, player, msg)
+	case *game.VisibilityMessage:
+		if spectator {
+			klog.Errorf("tableHandleMessage: Spectator %s can't change the visibility of table %s", player.Name, table.ID)
+			return
+		}
+		s.handleVisibility(table, player, msg)
+	case *game.TeamMessage:
+		if spectator {
+			klog.Errorf("tableHandleMessage: Spectator %s can't change teams on table %s", player.Name, table.ID)
+			return
+		}
+		s.handleTeam(table, player, msg)
+	case *game.ResyncMessage:
+		klog.Infof("tableHandleMessage: %s asked for the full state of table %s", player.Name, table.ID)
+		s.resyncLocked(c, table)
 	case *game.ClickMessage:
-		s.handleClick(table, player, msg)
+		reject := &game.RejectMessage{Symbol: msg.Symbol, Reason: game.RejectSpectator}
+		if !spectator {
+			reject = s.handleClick(table, player, msg)
+		}
+		if reject != nil {
+			rejectMsg, err := game.NewWsMessage(game.MsgTypeReject, reject)
+			if err != nil {
+				klog.Errorf("tableHandleMessage: Failed to create reject message: %v", err)
+				return
+			}
+			c.send(rejectMsg)
+		}
+	}
+}
+
+// Chat rate limit: a player can send at most ChatRateLimit messages within ChatRateWindow.
+const (
+	ChatRateLimit  = 5
+	ChatRateWindow = 10 * time.Second
+)
+
+// handleChat rebroadcasts a chat message from a player to the table, and adds it to the table's history.
+// It is called from the locked tableHandleMessage function.
+func (s *ServerState) handleChat(c *client, table *game.Table, player *game.Player, msg *game.ChatMessage) {
+	text := strings.TrimSpace(msg.Text)
+	if text == "" {
+		return
+	}
+	if runes := []rune(text); len(runes) > game.MaxChatLength {
+		text = string(runes[:game.MaxChatLength])
 	}
+
+	// Rate limit: only count the messages within the window.
+	now := time.Now()
+	player.RecentChats = slices.DeleteFunc(player.RecentChats, func(t time.Time) bool {
+		return now.Sub(t) >= ChatRateWindow
+	})
+	if len(player.RecentChats) >= ChatRateLimit {
+		klog.Infof("handleChat: Player %s is sending chat messages too fast on table %s", player.Name, table.ID)
+		noticeMsg, err := game.NewWsMessage(game.MsgTypeChat, game.ChatMessage{
+			Text:      "You are sending messages too fast, please wait a few seconds.",
+			Timestamp: now,
+			System:    true,
+		})
+		if err == nil {
+			c.send(noticeMsg)
+		}
+		return
+	}
+	player.RecentChats = append(player.RecentChats, now)
+
+	chat := game.ChatMessage{
+		SenderID:     player.ID,
+		SenderName:   player.Name,
+		SenderSymbol: player.Symbol,
+		Text:         text,
+		Timestamp:    now,
+	}
+	table.Chat = append(table.Chat, chat)
+	if excess := len(table.Chat) - game.ChatHistorySize; excess > 0 {
+		table.Chat = slices.Delete(table.Chat, 0, excess)
+	}
+
+	chatMsg, err := game.NewWsMessage(game.MsgTypeChat, chat)
+	if err != nil {
+		klog.Errorf("handleChat: Failed to create chat message: %v", err)
+		return
+	}
+	for _, other := range s.clientsLocked(table) {
+		other.send(chatMsg)
+	}
+}
+
+// handleSettings changes the settings of the table: only the creator can do it, and only before the game starts.
+// Invalid settings are ignored, and the current ones are broadcast back, so the creator's view is reverted.
+// It is called from the locked tableHandleMessage function.
+func (s *ServerState) handleSettings(table *game.Table, player *game.Player, msg *game.SettingsMessage) {
+	if len(table.Players) == 0 || table.Players[0].ID != player.ID {
+		klog.Errorf("handleSettings: Only creator can change the settings of table %s", table.ID)
+		return
+	}
+	if table.Started {
+		klog.Errorf("handleSettings: Can't change the settings of table %s, game already started", table.ID)
+		return
+	}
+	settings := msg.Settings
+	err := settings.Validate()
+	if err == nil && settings.MaxPlayers < len(table.Players) {
+		err = fmt.Errorf("maximum number of players %d is smaller than the %d players already seated",
+			settings.MaxPlayers, len(table.Players))
+	}
+	if err != nil {
+		klog.Errorf("handleSettings: Invalid settings for table %s: %v", table.ID, err)
+	} else {
+		klog.Infof("handleSettings: Table %s settings changed to %+v", table.ID, settings)
+		table.Settings = settings
+	}
+	s.broadcastStateLocked(table)
+}
+
+// handleTeam changes the team of a player: players can change their own, and the creator anyone's (including bots),
+// only before the game starts.
+// It is called from the locked tableHandleMessage function.
+func (s *ServerState) handleTeam(table *game.Table, player *game.Player, msg *game.TeamMessage) {
+	if table.Started {
+		klog.Errorf("handleTeam: Can't change teams on table %s, game already started", table.ID)
+		return
+	}
+	if msg.Team < 0 || msg.Team > game.MaxTeams {
+		klog.Errorf("handleTeam: Invalid team %d on table %s", msg.Team, table.ID)
+		return
+	}
+	targetID := cmp.Or(msg.PlayerID, player.ID)
+	if targetID != player.ID && table.Players[0].ID != player.ID {
+		klog.Errorf("handleTeam: Only creator can change the team of other players on table %s", table.ID)
+		return
+	}
+	idx := slices.IndexFunc(table.Players, func(p *game.Player) bool { return p.ID == targetID })
+	if idx < 0 {
+		klog.Errorf("handleTeam: Player %s not found on table %s", targetID, table.ID)
+		return
+	}
+	target := table.Players[idx]
+	klog.Infof("handleTeam: Player %s joins team %s on table %s", target.Name, game.TeamName(msg.Team), table.ID)
+	target.Team = msg.Team
+	s.broadcastStateLocked(table)
 }
 
 func (s *ServerState) handleGameStart(table *game.Table, startingPlayer *game.Player, msg *game.StartMessage) {
@@ -264,8 +736,48 @@ func (s *ServerState) handleGameStart(table *game.Table, startingPlayer *game.Pl
 		klog.Errorf("handleGameStart: Only creator can start game on table %s", table.ID)
 		return
 	}
+	mode := table.Mode()
+	if minPlayers := mode.Info().MinPlayers; len(table.Players) < minPlayers {
+		klog.Errorf("handleGameStart: Table %s needs at least %d players for %s", table.ID, minPlayers, mode.Info().Name)
+		return
+	}
+	if table.Tournament != nil && table.Tournament.ID == table.ID {
+		klog.Errorf("handleGameStart: Table %s is the lobby of a tournament already started", table.ID)
+		return
+	}
+	if size := table.Settings.TournamentTableSize; size > 0 && len(table.Players) > size {
+		players := make([]game.TournamentPlayer, 0, len(table.Players))
+		for _, p := range table.Players {
+			players = append(players, game.TournamentPlayer{ID: p.ID, Name: p.Name, Symbol: p.Symbol, Bot: p.Bot})
+		}
+		go s.startTournament(table, players)
+		return
+	}
+	if len(table.Players) >= table.Settings.DeckSize() {
+		klog.Errorf("handleGameStart: Table %s has too many players (%d) for a deck of %d cards",
+			table.ID, len(table.Players), table.Settings.DeckSize())
+		return
+	}
+	if mode.Info().Teams {
+		table.BalanceTeams()
+		if err := table.CheckTeams(); err != nil {
+			klog.Errorf("handleGameStart: Table %s can't start: %v", table.ID, err)
+			return
+		}
+	}
+	if table.Settings.MatchGames <= 1 {
+		table.Match = nil
+	} else if table.Match == nil || table.Match.Over() {
+		table.Match = game.NewMatch(table.Settings.MatchGames)
+	}
 
 	table.WinnerID = ""
+	table.WinnerTeam = 0
+	table.Finished = false
+	table.TargetCard = nil
+	table.Pile = nil
+	table.Grid = nil
+	table.Deal = 0
 	table.PendingClick = nil
 	if table.ClickTimer != nil {
 		table.ClickTimer.Stop()
@@ -273,58 +785,102 @@ func (s *ServerState) handleGameStart(table *game.Table, startingPlayer *game.Pl
 	}
 
 	for _, p := range table.Players {
-		p.TimeTaken = ""
+		p.TimeTaken = 0
+		p.Finished = false
+		p.Score = 0
+		p.Hand = nil
+		p.Matches = 0
+		p.BonusDiscards = 0
+		p.CardsDealt = 0
 		p.InPenalty = false
+		p.PenaltyUntil = time.Time{}
+		if p.PenaltyTimer != nil {
+			p.PenaltyTimer.Stop()
+			p.PenaltyTimer = nil
+		}
 	}
 
-	deck := game.GenerateStandardDeck()
-	deck.Shuffle()
-
-	// 1. Initial Target Card
-	table.TargetCard = deck[0]
-	deck = deck[1:]
-
-	// 2. Distribute Hand
-	numPlayers := len(table.Players)
-	if numPlayers == 1 {
-		// Single-player mode: Give exactly 10 cards
-		table.Players[0].Hand = deck[:10]
-		table.Players[0].Score = 10
+	if s.NewSeed != nil {
+		table.Seed = s.NewSeed()
 	} else {
-		cardsPerPlayer := len(deck) / numPlayers
-		for i, p := range table.Players {
-			p.Hand = deck[i*cardsPerPlayer : (i+1)*cardsPerPlayer]
-			p.Score = len(p.Hand)
-		}
+		table.Seed = time.Now().UnixNano()
+	}
+	table.Rand = game.NewRand(table.Seed)
+	deck, err := game.GenerateDeck(table.Settings.DeckOrder, table.Rand)
+	if err != nil {
+		klog.Errorf("handleGameStart: Table %s can't start: %v", table.ID, err)
+		return
+	}
+	deck.Shuffle(table.Rand)
+	klog.Infof("handleGameStart: Table %s dealing %s with seed %d", table.ID, mode.Info().Name, table.Seed)
+	mode.Deal(table, deck)
+	for _, p := range table.Players {
+		p.CardsDealt = len(p.Hand)
 	}
 
 	table.Started = true
 	table.StartTime = time.Now()
+	s.Metrics.gameStarted()
 	table.Round = 1
+	table.Replay = game.NewReplay(table)
+	table.ReplayID = table.Replay.ID
 	s.broadcastStateLocked(table)
 	s.broadcastUpdateLocked(table, nil)
 	s.broadcastPingLocked(table)
+	s.scheduleBotsLocked(table)
 }
 
 // handleClick message from player: it's called from the locked tableHandleMessage function.
-func (s *ServerState) handleClick(table *game.Table, player *game.Player, msg *game.ClickMessage) {
+// It returns a non-nil RejectMessage, to be sent back to the player, if the click was rejected.
+func (s *ServerState) handleClick(table *game.Table, player *game.Player, msg *game.ClickMessage) *game.RejectMessage {
 	klog.Infof("handleClick: Player %s clicked symbol %d", player.Name, msg.Symbol)
+	now := time.Now()
+	reject := func(reason game.RejectReason) *game.RejectMessage {
+		s.Metrics.click(clickInvalid)
+		logEventLocked(table, game.GameEvent{
+			Type:       game.EventReject,
+			PlayerID:   player.ID,
+			Symbol:     msg.Symbol,
+			ClickRound: msg.Round,
+			Latency:    player.Latency,
+			Reason:     reason,
+		}, now)
+		return &game.RejectMessage{
+			Symbol:           msg.Symbol,
+			Reason:           reason,
+			PenaltyRemaining: max(player.PenaltyUntil.Sub(now), 0),
+		}
+	}
 	if !table.Started {
 		klog.Errorf("handleClick: Player %s clicked symbol %d on non-started table", player.Name, msg.Symbol)
-		return
+		return reject(game.RejectNotStarted)
+	}
+	if table.Finished {
+		klog.Errorf("handleClick: Player %s clicked symbol %d after the game was over", player.Name, msg.Symbol)
+		return reject(game.RejectGameOver)
+	}
+	if now.Before(player.PenaltyUntil) {
+		klog.Errorf("handleClick: Player %s clicked symbol %d while in penalty for another %v",
+			player.Name, msg.Symbol, player.PenaltyUntil.Sub(now))
+		return reject(game.RejectInPenalty)
 	}
-	if len(player.Hand) == 0 {
-		klog.Errorf("handleClick: Player %s clicked symbol %d with no cards", player.Name, msg.Symbol)
-		return // Cannot click if they have no cards
+	if msg.Round != 0 && msg.Round != table.Round {
+		// The player was looking at an old target card: not their fault, no penalty.
+		klog.Infof("handleClick: Player %s clicked symbol %d on round %d, but table is on round %d",
+			player.Name, msg.Symbol, msg.Round, table.Round)
+		return reject(game.RejectStaleRound)
 	}
 
-	// 1. Validate that the symbol exists in both the TargetCard and the player's top card.
-	validTarget := slices.Contains(table.TargetCard, msg.Symbol)
-	validTop := slices.Contains(player.Hand[0], msg.Symbol)
-	if !validTarget || !validTop {
-		klog.Errorf("tableHandleMessage: Player %s made an invalid click", player.Name)
-		// Ignore the click, relying on the frontend to handle the visual penalty.
-		return
+	// 1. Validate the click according to the rules of the game mode.
+	switch reason := table.Mode().ValidateClick(table, player, msg.Symbol); reason {
+	case "":
+	case game.RejectWrongSymbol:
+		klog.Errorf("handleClick: Player %s made an invalid click", player.Name)
+		s.startPenaltyLocked(table, player)
+		return reject(reason)
+	default:
+		klog.Errorf("handleClick: Player %s clicked symbol %d, rejected: %s", player.Name, msg.Symbol, reason)
+		return reject(reason)
 	}
 
 	// Calculate latency compensation
@@ -337,10 +893,23 @@ func (s *ServerState) handleClick(table *game.Table, player *game.Player, msg *g
 
 	delay := max(maxLatency-player.Latency, 0)
 	processTime := time.Now().Add(delay)
-	klog.Infof("tableHandleMessage: Player %s valid click on %d. Delay %v, Target process %v", player.Name, msg.Symbol, delay, processTime)
+	klog.Infof("handleClick: Player %s valid click on %d. Delay %v, Target process %v", player.Name, msg.Symbol, delay, processTime)
+	s.Metrics.click(clickValid)
+	s.Metrics.observeDelay(delay)
+	logEventLocked(table, game.GameEvent{
+		Type:       game.EventClick,
+		PlayerID:   player.ID,
+		Symbol:     msg.Symbol,
+		ClickRound: msg.Round,
+		Latency:    player.Latency,
+		Delay:      delay,
+	}, now)
 
 	// Check if we should override the pending click
 	if table.PendingClick == nil || processTime.Before(table.PendingClick.ProcessTime) {
+		if table.PendingClick != nil {
+			s.Metrics.click(clickSuperseded)
+		}
 		table.PendingClick = &game.PendingClick{
 			PlayerID:    player.ID,
 			ProcessTime: processTime,
@@ -358,13 +927,59 @@ func (s *ServerState) handleClick(table *game.Table, player *game.Player, msg *g
 				s.processWinningClick(table, processTime)
 			})
 		}
+	} else {
+		s.Metrics.click(clickSuperseded)
+	}
+	return nil
+}
+
+// startPenaltyLocked puts the player in penalty for the table's PenaltyDuration, during which their clicks
+// are rejected, and broadcasts it so other players see it.
+// Assumes the table is locked.
+func (s *ServerState) startPenaltyLocked(table *game.Table, player *game.Player) {
+	duration := table.Settings.PenaltyDuration
+	if duration <= 0 {
+		// Penalties disabled for this table.
+		return
+	}
+	player.InPenalty = true
+	player.PenaltyUntil = time.Now().Add(duration)
+	if player.PenaltyTimer != nil {
+		player.PenaltyTimer.Stop()
+	}
+	player.PenaltyTimer = time.AfterFunc(duration, func() {
+		s.endPenalty(table, player)
+	})
+	s.broadcastStateLocked(table)
+}
+
+// endPenalty is called when the penalty timer of a player expires.
+func (s *ServerState) endPenalty(table *game.Table, player *game.Player) {
+	room := s.lockTable(table)
+	if room == nil {
+		return
+	}
+	defer room.mu.Unlock()
+
+	if !player.InPenalty || time.Now().Before(player.PenaltyUntil) {
+		// Penalty was already cleared (game restarted) or extended.
+		return
+	}
+	player.InPenalty = false
+	player.PenaltyTimer = nil
+	s.broadcastStateLocked(table)
+	if player.Bot != "" {
+		s.scheduleBotLocked(table, player)
 	}
 }
 
 // processWinningClick is called when the delay timer for a winning click has expired.
 func (s *ServerState) processWinningClick(table *game.Table, expectedProcessTime time.Time) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	room := s.lockTable(table)
+	if room == nil {
+		return
+	}
+	defer room.mu.Unlock()
 
 	if !table.Started {
 		return
@@ -389,80 +1004,114 @@ func (s *ServerState) processWinningClick(table *game.Table, expectedProcessTime
 			break
 		}
 	}
-	if clicker == nil || len(clicker.Hand) == 0 {
+	mode := table.Mode()
+	if clicker == nil || table.Finished || mode.ValidateClick(table, clicker, click.Symbol) != "" {
 		return
 	}
 
-	duration := time.Since(table.StartTime)
-	minutes := int(duration.Minutes())
-	seconds := int(duration.Seconds()) % 60
-	timeToClick := fmt.Sprintf("%02d:%02d", minutes, seconds)
+	now := time.Now()
+	duration := now.Sub(table.StartTime)
 
-	// Discard cards (3 if matched player symbol, 1 otherwise)
-	scoringIDs := []string{clicker.ID}
-	var finishers []string // players who finished their hand with this click.
-
-	numToDiscard := 1
-	if click.Symbol == clicker.Symbol {
-		numToDiscard = game.BonusDiscards
+	bonusBefore := make(map[*game.Player]int, len(table.Players))
+	finishedBefore := make(map[*game.Player]bool, len(table.Players))
+	for _, p := range table.Players {
+		bonusBefore[p] = p.BonusDiscards
+		finishedBefore[p] = p.Finished
 	}
-	if numToDiscard >= len(clicker.Hand) {
-		numToDiscard = len(clicker.Hand)
-		finishers = append(finishers, clicker.ID)
-		clicker.TimeTaken = timeToClick
+	scoringIDs := mode.ApplyWin(table, clicker, click.Symbol)
+	clicker.Matches++
+	if mode.GameOver(table) {
+		klog.Infof("processWinningClick: Game over on table %s, winner %s", table.ID, table.WinnerID)
+		table.Finished = true
+		for _, p := range table.Players {
+			p.Finished = true
+		}
+		s.Metrics.gameFinished()
 	}
-
-	// The target card becomes the last discarded card from the player's hand.
-	table.TargetCard = clicker.Hand[numToDiscard-1]
-	clicker.Hand = clicker.Hand[numToDiscard:]
-	clicker.Score = len(clicker.Hand)
-
-	// Give bonus discards to other players whose matching symbol was clicked
 	for _, p := range table.Players {
-		if p.ID == clicker.ID {
-			continue
-		}
-		if p.Symbol != click.Symbol || len(p.Hand) == 0 {
-			continue
+		if p.Finished && p.TimeTaken == 0 {
+			p.TimeTaken = duration
 		}
-
-		// Issue bonus discard to player with matching symbol:
-		bonusDiscards := game.BonusDiscards
-		if bonusDiscards >= len(p.Hand) {
-			bonusDiscards = len(p.Hand)
-			finishers = append(finishers, p.ID)
-			p.TimeTaken = timeToClick
-		}
-		p.Hand = p.Hand[bonusDiscards:]
-		p.Score = len(p.Hand)
-		scoringIDs = append(scoringIDs, p.ID)
 	}
-
-	// Check if any player finished the game, if they were the first.
-	if table.WinnerID == "" && len(finishers) > 0 {
-		// Break the tie randomly.
-		table.WinnerID = finishers[rand.Intn(len(finishers))]
+	s.logWinLocked(table, clicker, click.Symbol, bonusBefore, finishedBefore, now)
+	if table.Finished {
+		go s.recordGame(table.ID, gameResultsLocked(table), soloEntryLocked(table))
+		s.recordMatchLocked(table)
 	}
 	table.Round++
 	table.PendingClick = nil // Reset
 
-	klog.Infof("processWinningClick: Player %s wins round %d on table %s! Discarded %d cards.",
-		clicker.Name, table.Round, table.ID, numToDiscard)
+	klog.Infof("processWinningClick: Player %s wins round %d on table %s!", clicker.Name, table.Round, table.ID)
 
 	s.broadcastStateLocked(table)
 	s.broadcastUpdateLocked(table, scoringIDs)
+	s.scheduleBotsLocked(table)
+}
+
+// logEventLocked appends the event, that happened at time now, to the log of the current game of the table, if any.
+// Assumes the table is locked.
+func logEventLocked(table *game.Table, event game.GameEvent, now time.Time) {
+	if table.Replay != nil {
+		table.Replay.Log(table, event, now)
+	}
+}
+
+// logWinLocked logs the winning click of the round, with the bonus discards and the players that finished because
+// of it, given their state before the click was applied.
+// If the game is over, it saves its replay.
+// Assumes the table is locked.
+func (s *ServerState) logWinLocked(table *game.Table, clicker *game.Player, symbol int,
+	bonusBefore map[*game.Player]int, finishedBefore map[*game.Player]bool, now time.Time) {
+	if table.Replay == nil {
+		return
+	}
+	logEventLocked(table, game.GameEvent{
+		Type:     game.EventRoundWin,
+		PlayerID: clicker.ID,
+		Symbol:   symbol,
+		Snapshot: game.NewReplaySnapshot(table),
+	}, now)
+	for _, p := range table.Players {
+		if discards := p.BonusDiscards - bonusBefore[p]; discards > 0 {
+			logEventLocked(table, game.GameEvent{Type: game.EventBonus, PlayerID: p.ID, Symbol: symbol, Discards: discards}, now)
+		}
+	}
+	for _, p := range table.Players {
+		if p.Finished && !finishedBefore[p] {
+			logEventLocked(table, game.GameEvent{Type: game.EventFinish, PlayerID: p.ID}, now)
+		}
+	}
+	if table.Finished {
+		logEventLocked(table, game.GameEvent{Type: game.EventGameOver, PlayerID: table.WinnerID}, now)
+		table.Replay.WinnerID = table.WinnerID
+		go s.saveReplay(table.Replay)
+		table.Replay = nil
+	}
 }
 
 // broadcastUpdateLocked broadcasts individual game updates (top card, target card) to each client.
-// Assumes s.mu is locked.
+// Assumes the table is locked.
 func (s *ServerState) broadcastUpdateLocked(table *game.Table, scoringIDs []string) {
-	for conn, playerID := range s.TableClients[table.ID] {
-		// Find player hand
-		var player *game.Player
+	// Cards that are public in the game mode are the same for everyone.
+	var topCards map[string][]int
+	if table.Mode().Info().PublicTopCards {
+		topCards = make(map[string][]int, len(table.Players))
 		for _, p := range table.Players {
-			if p.ID == playerID {
-				player = p
-				break
+			if len(p.Hand) > 0 {
+				topCards[p.ID] = p.Hand[0]
+			}
+		}
+	}
+
+	for _, c := range s.clientsLocked(table) {
+		// Find player hand: spectators have none.
+		var player *game.Player
+		if !c.spectator {
+			for _, p := range table.Players {
+				if p.ID == c.playerID {
+					player = p
+					break
+				}
 			}
 		}
 
@@ -471,27 +1120,22 @@ func (s *ServerState) broadcastUpdateLocked(table *game.Table, scoringIDs []stri
 			topCard = player.Hand[0]
 		}
 
-		updateMsg, err := game.NewWsMessage(game.MsgTypeUpdate, game.UpdateMessage{
+		c.sendEncoded(game.MsgTypeUpdate, game.UpdateMessage{
 			TargetCard: table.TargetCard,
 			TopCard:    topCard,
 			Round:      table.Round,
 			ScoringIDs: scoringIDs,
+			TopCards:   topCards,
+			Grid:       table.Grid,
+			PileSize:   len(table.Pile),
 		})
-		if err != nil {
-			klog.Errorf("broadcastUpdateLocked: Failed to create update message: %v", err)
-			continue
-		}
-
-		go func(c *websocket.Conn, um game.WsMessage) {
-			ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
-			defer cancel()
-			_ = wsjson.Write(ctx, c, um)
-		}(conn, updateMsg)
 	}
 }
 
-// broadcastStateLocked broadcasts table state to all connections. Assumes m.mu is locked.
+// broadcastStateLocked broadcasts table state to all connections, as a new revision: connections that accept
+// deltas are only sent what changed since the previous one. Assumes the table is locked.
 func (s *ServerState) broadcastStateLocked(table *game.Table) {
+	table.Revision++
 	stateMsg, err := game.NewWsMessage(game.MsgTypeState, game.StateMessage{
 		Table: *table,
 	})
@@ -499,50 +1143,84 @@ func (s *ServerState) broadcastStateLocked(table *game.Table) {
 		klog.Errorf("broadcastStateLocked: Failed to create state message: %v", err)
 		return
 	}
+	var delta *game.StateDeltaMessage
+	snapshot, err := game.NewStateSnapshot(table)
+	if err != nil {
+		klog.Errorf("broadcastStateLocked: Failed to take snapshot of table %s: %v", table.ID, err)
+	} else if table.Snapshot != nil {
+		delta = table.Snapshot.Diff(snapshot)
+	}
+	table.Snapshot = snapshot
 
-	for conn := range s.TableClients[table.ID] {
-		// Non-blocking quick send, or run in goroutines if slow
-		go func(c *websocket.Conn, tm game.WsMessage) {
-			ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
-			defer cancel()
-			_ = wsjson.Write(ctx, c, tm)
-		}(conn, stateMsg)
+	// The delta is encoded once for each of the encodings negotiated by the clients.
+	type encodedDelta struct {
+		data     []byte
+		isBinary bool
+		err      error
+	}
+	deltas := make(map[game.Encoding]encodedDelta)
+	for _, c := range s.clientsLocked(table) {
+		if c.deltas && c.synced && delta != nil {
+			encoded, ok := deltas[c.encoding]
+			if !ok {
+				encoded.data, encoded.isBinary, encoded.err = game.EncodeMessage(c.encoding, game.MsgTypeStateDelta, *delta)
+				if encoded.err != nil {
+					klog.Errorf("broadcastStateLocked: Failed to encode state delta message: %v", encoded.err)
+				}
+				deltas[c.encoding] = encoded
+			}
+			if encoded.err == nil {
+				c.out.send(game.MsgTypeStateDelta, encoded.data, encoded.isBinary)
+				continue
+			}
+		}
+		if c.deltas {
+			c.synced = true
+		}
+		c.send(stateMsg)
 	}
 }
 
-// broadcastPingLocked sends a ping to all connections on the table to measure latency.
-// Assumes s.mu is locked.
-func (s *ServerState) broadcastPingLocked(table *game.Table) {
-	pingMsg, _ := game.NewWsMessage(game.MsgTypePing, game.PingMessage{
-		ServerTime: time.Now().UnixNano(),
+// resyncLocked sends the full state of the table, at its current revision, to a client that missed some delta.
+// Assumes the table is locked.
+func (s *ServerState) resyncLocked(c *client, table *game.Table) {
+	stateMsg, err := game.NewWsMessage(game.MsgTypeState, game.StateMessage{
+		Table: *table,
 	})
+	if err != nil {
+		klog.Errorf("resyncLocked: Failed to create state message: %v", err)
+		return
+	}
+	c.synced = true
+	c.send(stateMsg)
+}
 
-	for conn := range s.TableClients[table.ID] {
-		go func(c *websocket.Conn, pm game.WsMessage) {
-			ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
-			defer cancel()
-			_ = wsjson.Write(ctx, c, pm)
-		}(conn, pingMsg)
+// broadcastPingLocked sends a ping to all connections on the table to measure latency.
+// Assumes the table is locked.
+func (s *ServerState) broadcastPingLocked(table *game.Table) {
+	ping := game.PingMessage{ServerTime: time.Now().UnixNano()}
+	for _, c := range s.clientsLocked(table) {
+		c.sendEncoded(game.MsgTypePing, ping)
 	}
 }
 
 // HandleTestGame sets up a test game with 10 players and redirects to the table.
 func (s *ServerState) HandleTestGame(w http.ResponseWriter, r *http.Request) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	tableID := "ThreeStooges"
 	klog.Infof("HandleTestGame: Setting up test game on table %s", tableID)
 
 	table := &game.Table{
-		ID:      tableID,
-		Name:    tableID,
-		Players: make([]*game.Player, 0, 10),
+		ID:       tableID,
+		Name:     tableID,
+		Players:  make([]*game.Player, 0, 10),
+		Settings: game.DefaultTableSettings(),
 	}
-	s.Tables[tableID] = table
-
-	// Reset any existing connections to prevent accumulated bugs across tests
-	s.TableClients[tableID] = make(map[*websocket.Conn]string)
+	// Locked before it's registered, so nobody sees it half set up. Any existing table (and its connections)
+	// is replaced, to prevent accumulated bugs across tests.
+	mu := &sync.Mutex{}
+	mu.Lock()
+	defer mu.Unlock()
+	s.addTable(table, mu)
 
 	symbols := rand.Perm(57) // Assuming up to 57 symbols
 
@@ -3,7 +3,6 @@ package server
 import (
 	"context"
 	"fmt"
-	"sync"
 	"testing"
 	"testing/synctest"
 	"time"
@@ -25,28 +24,15 @@ func TestLatencyCompensationClick(t *testing.T) {
 		const tableName = "test_table"
 		wsURL := "ws://" + startAddr + "/ws"
 
-		var conn1, conn2 *websocket.Conn
-		var wg sync.WaitGroup
-		wg.Add(2)
-		go func() {
-			defer wg.Done()
-			conn, err := testConnectAndJoin(ctx, serverState, wsURL, tableName, "p1", "FastPlayer", 0, 0)
-			if err != nil {
-				t.Errorf("FastPlayer failed to join: %v", err)
-			}
-			conn1 = conn
-			fmt.Println("FastPlayer connected")
-		}()
-		go func() {
-			defer wg.Done()
-			conn, err := testConnectAndJoin(ctx, serverState, wsURL, tableName, "p2", "SlowPlayer", 0, 10*time.Millisecond)
-			if err != nil {
-				t.Errorf("SlowPlayer failed to join: %v", err)
-			}
-			conn2 = conn
-			fmt.Println("SlowPlayer connected")
-		}()
-		wg.Wait()
+		// FastPlayer must join first: it is the table creator, and the only one allowed to start the game.
+		conn1, err := testConnectAndJoin(ctx, serverState, wsURL, tableName, "p1", "FastPlayer", 0, 0)
+		if err != nil {
+			t.Fatalf("FastPlayer failed to join: %v", err)
+		}
+		conn2, err := testConnectAndJoin(ctx, serverState, wsURL, tableName, "p2", "SlowPlayer", 0, 10*time.Millisecond)
+		if err != nil {
+			t.Fatalf("SlowPlayer failed to join: %v", err)
+		}
 		fmt.Println("Connected")
 
 		// Drain incoming messages from both connections in background goroutines.
@@ -69,18 +55,16 @@ func TestLatencyCompensationClick(t *testing.T) {
 
 		// Wait for table to start
 		for {
-			serverState.mu.Lock()
-			table := serverState.Tables[tableName]
+			table, unlock := testLockTableID(serverState, tableName)
 			if table != nil && table.Started {
-				serverState.mu.Unlock()
+				unlock()
 				break
 			}
-			serverState.mu.Unlock()
+			unlock()
 			time.Sleep(1 * time.Millisecond)
 		}
 
-		serverState.mu.Lock()
-		table := serverState.Tables[tableName]
+		table, unlock := testLockTableID(serverState, tableName)
 		if table == nil {
 			t.Fatalf("Table not found")
 		}
@@ -119,7 +103,7 @@ func TestLatencyCompensationClick(t *testing.T) {
 
 		fastMatch := findMatch(fastPlayer.Hand[0])
 		slowMatch := findMatch(slowPlayer.Hand[0])
-		serverState.mu.Unlock()
+		unlock()
 
 		if fastMatch == -1 || slowMatch == -1 {
 			t.Fatalf("Could not find matching cards")
@@ -142,8 +126,8 @@ func TestLatencyCompensationClick(t *testing.T) {
 		// Enough time for timer to fire
 		time.Sleep(100 * time.Millisecond)
 
-		serverState.mu.Lock()
-		defer serverState.mu.Unlock()
+		testLock(t, serverState, table)
+		defer testUnlock(serverState, table)
 
 		if table.Round != 2 {
 			t.Fatalf("Expected round to increment to 2, got %d", table.Round)
@@ -152,6 +136,20 @@ func TestLatencyCompensationClick(t *testing.T) {
 		if len(slowPlayer.Hand) >= len(fastPlayer.Hand) {
 			t.Fatalf("Slow player should have won, but fast player won. Slow hand len: %d, Fast hand len: %d", len(slowPlayer.Hand), len(fastPlayer.Hand))
 		}
+
+		// Both clicks were valid, and the fast player's one was superseded.
+		metrics := testMetrics(t, serverState)
+		for series, want := range map[string]string{
+			`gospot_clicks_total{result="valid"}`:          "2",
+			`gospot_clicks_total{result="superseded"}`:     "1",
+			`gospot_click_delay_seconds_bucket{le="0"}`:    "1",
+			`gospot_click_delay_seconds_bucket{le="0.01"}`: "2",
+			`gospot_click_delay_seconds_sum`:               "0.008",
+		} {
+			if got := metrics[series]; got != want {
+				t.Errorf("Expected %s to be %s, got %q", series, want, got)
+			}
+		}
 		synctest.Wait()
 	})
 }
@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"slices"
+	"strings"
 	"testing"
+	"testing/synctest"
 	"time"
 
 	"github.com/coder/websocket"
@@ -14,6 +17,25 @@ import (
 )
 
 func testConnectAndJoin(ctx context.Context, serverState *ServerState, wsURL string, tableID string, playerID string, playerName string, symbol int, delay time.Duration) (*websocket.Conn, error) {
+	return testConnectAndSendJoin(ctx, serverState, wsURL, game.JoinMessage{
+		TableID: tableID,
+		Player: game.Player{
+			ID:     playerID,
+			Name:   playerName,
+			Symbol: symbol,
+		},
+	}, delay)
+}
+
+// testConnectAndSendJoin connects to the server, sends the given join message and answers the initial ping.
+func testConnectAndSendJoin(ctx context.Context, serverState *ServerState, wsURL string, join game.JoinMessage, delay time.Duration) (*websocket.Conn, error) {
+	return testConnectWithHello(ctx, serverState, wsURL, nil, join, delay)
+}
+
+// testConnectWithHello is like testConnectAndSendJoin, but first makes the handshake with the given hello message,
+// if not nil. Only JSON messages are expected, so hello shouldn't ask for other encodings.
+func testConnectWithHello(ctx context.Context, serverState *ServerState, wsURL string, hello *game.HelloMessage, join game.JoinMessage, delay time.Duration) (*websocket.Conn, error) {
+	tableID, playerName := join.TableID, join.Player.Name
 	opts := &websocket.DialOptions{}
 	if serverState != nil && serverState.LocalDial != nil {
 		opts.HTTPClient = &http.Client{
@@ -31,14 +53,30 @@ func testConnectAndJoin(ctx context.Context, serverState *ServerState, wsURL str
 		return nil, fmt.Errorf("dial error: %w", err)
 	}
 
-	joinMsg, err := game.NewWsMessage(game.MsgTypeJoin, game.JoinMessage{
-		TableID: tableID,
-		Player: game.Player{
-			ID:     playerID,
-			Name:   playerName,
-			Symbol: symbol,
-		},
-	})
+	if hello != nil {
+		helloMsg, _ := game.NewWsMessage(game.MsgTypeHello, *hello)
+		if err := wsjson.Write(ctx, conn, helloMsg); err != nil {
+			conn.CloseNow()
+			return nil, fmt.Errorf("failed to write HelloMessage: %w", err)
+		}
+		var welcomeMsg game.WsMessage
+		if err := wsjson.Read(ctx, conn, &welcomeMsg); err != nil {
+			conn.CloseNow()
+			return nil, fmt.Errorf("failed to read WelcomeMessage: %w", err)
+		}
+		p, err := welcomeMsg.Parse()
+		welcome, ok := p.(*game.WelcomeMessage)
+		if err != nil || !ok {
+			conn.CloseNow()
+			return nil, fmt.Errorf("expected WelcomeMessage, got %s (%v)", welcomeMsg.Type, err)
+		}
+		if welcome.Error != "" {
+			conn.CloseNow()
+			return nil, fmt.Errorf("handshake refused: %s", welcome.Error)
+		}
+	}
+
+	joinMsg, err := game.NewWsMessage(game.MsgTypeJoin, join)
 	if err != nil {
 		if conn != nil {
 			conn.CloseNow()
@@ -61,7 +99,8 @@ func testConnectAndJoin(ctx context.Context, serverState *ServerState, wsURL str
 			conn.CloseNow()
 			return nil, fmt.Errorf("failed to read message: %w", err)
 		}
-		if pingMsg.Type == game.MsgTypeState {
+		if pingMsg.Type == game.MsgTypeState || pingMsg.Type == game.MsgTypeStateDelta || pingMsg.Type == game.MsgTypeUpdate {
+			// Broadcasts triggered by joining may arrive before the ping.
 			continue
 		}
 		if pingMsg.Type != game.MsgTypePing {
@@ -102,6 +141,40 @@ func testConnectAndJoin(ctx context.Context, serverState *ServerState, wsURL str
 	return conn, nil
 }
 
+// testLock locks the table, failing the test if it's no longer registered.
+func testLock(t testing.TB, s *ServerState, table *game.Table) {
+	t.Helper()
+	if s.lockTable(table) == nil {
+		t.Fatalf("Table %s is not registered", table.ID)
+	}
+}
+
+// testUnlock unlocks a table locked with testLock.
+func testUnlock(s *ServerState, table *game.Table) {
+	s.roomOf(table).mu.Unlock()
+}
+
+// testTable returns the table with the given ID, or nil. It doesn't lock the table.
+func testTable(s *ServerState, tableID string) *game.Table {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Tables[tableID]
+}
+
+// testLockTableID locks the table with the given ID, and returns it with the function to unlock it.
+// If there is no such table, it returns nil, and unlock does nothing.
+func testLockTableID(s *ServerState, tableID string) (table *game.Table, unlock func()) {
+	table = testTable(s, tableID)
+	if table == nil {
+		return nil, func() {}
+	}
+	room := s.lockTable(table)
+	if room == nil {
+		return nil, func() {}
+	}
+	return table, room.mu.Unlock
+}
+
 func TestTableWebsocket(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -266,11 +339,9 @@ func TestHandleTestGame(t *testing.T) {
 	}
 
 	// Verify server state
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	table, exists := s.Tables["ThreeStooges"]
-	if !exists {
+	table, unlock := testLockTableID(s, "ThreeStooges")
+	defer unlock()
+	if table == nil {
 		t.Fatalf("Table ThreeStooges was not created in server state")
 	}
 
@@ -308,8 +379,6 @@ func TestHandleTestGame(t *testing.T) {
 
 func TestBonus(t *testing.T) {
 	s := NewServerState()
-	s.mu.Lock()
-
 	tableID := "test-bonus"
 	table := &game.Table{
 		ID:         tableID,
@@ -318,9 +387,10 @@ func TestBonus(t *testing.T) {
 		Round:      1,
 		Players:    make([]*game.Player, 0),
 		TargetCard: []int{1, 2, 3}, // Random target card for test, must contain clicked symbol
+		Settings:   game.DefaultTableSettings(),
 	}
-	s.Tables[tableID] = table
-	s.TableClients[tableID] = make(map[*websocket.Conn]string)
+	s.addTable(table, nil)
+	testLock(t, s, table)
 
 	clicker := &game.Player{
 		ID:     "clicker",
@@ -371,20 +441,608 @@ func TestBonus(t *testing.T) {
 		Round:       1,
 	}
 
-	s.mu.Unlock()
+	testUnlock(s, table)
 	s.processWinningClick(table, processTime)
 
 	if len(clicker.Hand) != 4 {
 		t.Errorf("Expected clicker to have 4 cards (discarded 1), got %d", len(clicker.Hand))
 	}
-	expectedOther1Hand := 5 - game.BonusDiscards
+	expectedOther1Hand := 5 - table.Settings.BonusDiscards
 	if expectedOther1Hand < 0 {
 		expectedOther1Hand = 0
 	}
 	if len(other1.Hand) != expectedOther1Hand {
-		t.Errorf("Expected other1 to have %d cards (discarded %d), got %d", expectedOther1Hand, game.BonusDiscards, len(other1.Hand))
+		t.Errorf("Expected other1 to have %d cards (discarded %d), got %d", expectedOther1Hand, table.Settings.BonusDiscards, len(other1.Hand))
 	}
 	if len(other2.Hand) != 5 {
 		t.Errorf("Expected other2 to have 5 cards (discarded 0), got %d", len(other2.Hand))
 	}
 }
+
+func TestGameStartSeed(t *testing.T) {
+	s := NewServerState()
+	s.NewSeed = func() int64 { return 1234 }
+
+	newTable := func(id string) *game.Table {
+		table := &game.Table{
+			ID:   id,
+			Name: id,
+			Players: []*game.Player{
+				{ID: "p1", Name: "Alice", Symbol: 1},
+				{ID: "p2", Name: "Bob", Symbol: 2},
+			},
+			Settings: game.DefaultTableSettings(),
+		}
+		s.addTable(table, nil)
+		testLock(t, s, table)
+		defer testUnlock(s, table)
+		s.handleGameStart(table, table.Players[0], &game.StartMessage{})
+		return table
+	}
+
+	table1 := newTable("seed-1")
+	table2 := newTable("seed-2")
+
+	if table1.Seed != 1234 || table2.Seed != 1234 {
+		t.Fatalf("Expected seed 1234 recorded on both tables, got %d and %d", table1.Seed, table2.Seed)
+	}
+	if !slices.Equal(table1.TargetCard, table2.TargetCard) {
+		t.Errorf("Expected same target card, got %v and %v", table1.TargetCard, table2.TargetCard)
+	}
+	for i := range table1.Players {
+		if !slices.EqualFunc(table1.Players[i].Hand, table2.Players[i].Hand, slices.Equal) {
+			t.Errorf("Expected player %s to be dealt the same hand on both tables", table1.Players[i].Name)
+		}
+	}
+}
+
+func TestWrongClickPenalty(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		s := NewServerState()
+		tableID := "test-penalty"
+		player := &game.Player{
+			ID:   "p1",
+			Name: "Clicker",
+			Hand: [][]int{{1, 4, 5}, {6, 7, 8}},
+		}
+		table := &game.Table{
+			ID:         tableID,
+			Name:       tableID,
+			Started:    true,
+			Round:      1,
+			Players:    []*game.Player{player},
+			TargetCard: []int{1, 2, 3},
+			Settings:   game.DefaultTableSettings(),
+		}
+		s.addTable(table, nil)
+
+		penaltyDuration := table.Settings.PenaltyDuration
+		click := func(symbol, round int) *game.RejectMessage {
+			testLock(t, s, table)
+			defer testUnlock(s, table)
+			return s.handleClick(table, player, &game.ClickMessage{Symbol: symbol, Round: round})
+		}
+
+		// Wrong symbol: rejected and penalized.
+		reject := click(4, 1)
+		if reject == nil || reject.Reason != game.RejectWrongSymbol {
+			t.Fatalf("Expected wrong symbol rejection, got %+v", reject)
+		}
+		if reject.PenaltyRemaining != penaltyDuration {
+			t.Errorf("Expected penalty of %v, got %v", penaltyDuration, reject.PenaltyRemaining)
+		}
+		if !player.InPenalty {
+			t.Errorf("Expected player to be in penalty")
+		}
+
+		// Right symbol, but still in penalty.
+		time.Sleep(penaltyDuration / 2)
+		reject = click(1, 1)
+		if reject == nil || reject.Reason != game.RejectInPenalty {
+			t.Fatalf("Expected in penalty rejection, got %+v", reject)
+		}
+		if reject.PenaltyRemaining != penaltyDuration/2 {
+			t.Errorf("Expected remaining penalty of %v, got %v", penaltyDuration/2, reject.PenaltyRemaining)
+		}
+
+		// Penalty expires.
+		time.Sleep(penaltyDuration / 2)
+		synctest.Wait()
+		testLock(t, s, table)
+		inPenalty := player.InPenalty
+		testUnlock(s, table)
+		if inPenalty {
+			t.Errorf("Expected penalty to have expired")
+		}
+
+		// Clients that don't report the round are still validated.
+		reject = click(4, 0)
+		if reject == nil || reject.Reason != game.RejectWrongSymbol {
+			t.Fatalf("Expected wrong symbol rejection, got %+v", reject)
+		}
+		time.Sleep(penaltyDuration)
+		synctest.Wait()
+
+		// Click on a stale round: rejected without penalty.
+		reject = click(4, 3)
+		if reject == nil || reject.Reason != game.RejectStaleRound {
+			t.Fatalf("Expected stale round rejection, got %+v", reject)
+		}
+		if reject.PenaltyRemaining != 0 || player.InPenalty {
+			t.Errorf("Expected no penalty for a stale round click")
+		}
+
+		// Valid click is accepted.
+		if reject = click(1, 1); reject != nil {
+			t.Fatalf("Expected valid click to be accepted, got %+v", reject)
+		}
+		synctest.Wait()
+		testLock(t, s, table)
+		defer testUnlock(s, table)
+		if table.Round != 2 || len(player.Hand) != 1 {
+			t.Errorf("Expected player to win round 1, got round %d and %d cards", table.Round, len(player.Hand))
+		}
+	})
+}
+
+func TestChat(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	started := make(chan *ServerState, 1)
+	go Run(ctx, "", started)
+	s := <-started
+	wsURL := "ws://" + s.Address + "/ws"
+	tableID := "test-chat"
+
+	conn1, err := testConnectAndJoin(ctx, s, wsURL, tableID, "p1", "Alice", 1, 0)
+	if err != nil {
+		t.Fatalf("Player 1 failed to join: %v", err)
+	}
+	defer conn1.CloseNow()
+
+	// readChat reads messages until it gets a chat message.
+	readChat := func(conn *websocket.Conn) *game.ChatMessage {
+		for {
+			var msg game.WsMessage
+			if err := wsjson.Read(ctx, conn, &msg); err != nil {
+				t.Fatalf("Failed to read chat message: %v", err)
+			}
+			if msg.Type != game.MsgTypeChat {
+				continue
+			}
+			p, err := msg.Parse()
+			if err != nil {
+				t.Fatalf("Failed to parse chat message: %v", err)
+			}
+			return p.(*game.ChatMessage)
+		}
+	}
+
+	// Long messages are truncated, and the sender is filled in by the server.
+	longText := strings.Repeat("a", game.MaxChatLength+10)
+	for i := range ChatRateLimit + 1 {
+		text := fmt.Sprintf("hello %d", i)
+		if i == 0 {
+			text = longText
+		}
+		chatMsg, _ := game.NewWsMessage(game.MsgTypeChat, game.ChatMessage{Text: text})
+		if err := wsjson.Write(ctx, conn1, chatMsg); err != nil {
+			t.Fatalf("Failed to send chat message: %v", err)
+		}
+	}
+	var received []*game.ChatMessage
+	for range ChatRateLimit + 1 {
+		received = append(received, readChat(conn1))
+	}
+	var numSystem int
+	for _, chat := range received {
+		if chat.System {
+			numSystem++
+			continue
+		}
+		if chat.SenderID != "p1" || chat.SenderName != "Alice" || chat.SenderSymbol != 1 {
+			t.Errorf("Unexpected sender in chat message: %+v", chat)
+		}
+		if strings.HasPrefix(chat.Text, "a") && len(chat.Text) != game.MaxChatLength {
+			t.Errorf("Expected long chat message to be truncated to %d, got %d", game.MaxChatLength, len(chat.Text))
+		}
+	}
+	if numSystem != 1 {
+		t.Errorf("Expected exactly one rate limit notice, got %d", numSystem)
+	}
+
+	// A player joining receives the history.
+	conn2, err := testConnectAndJoin(ctx, s, wsURL, tableID, "p2", "Bob", 2, 0)
+	if err != nil {
+		t.Fatalf("Player 2 failed to join: %v", err)
+	}
+	defer conn2.CloseNow()
+	for i := range ChatRateLimit {
+		chat := readChat(conn2)
+		if i > 0 && chat.Text != fmt.Sprintf("hello %d", i) {
+			t.Errorf("Expected history message %d to be %q, got %q", i, fmt.Sprintf("hello %d", i), chat.Text)
+		}
+	}
+}
+
+func TestReconnectResumesSeat(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		started := make(chan *ServerState, 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go Run(ctx, NetPipeAddr, started)
+		s := <-started
+		wsURL := "ws://" + s.Address + "/ws"
+		const tableID = "test-reconnect"
+
+		drainConn := func(conn *websocket.Conn) {
+			for {
+				var msg game.WsMessage
+				if err := wsjson.Read(ctx, conn, &msg); err != nil {
+					return
+				}
+			}
+		}
+		join := func(playerID, name string) *websocket.Conn {
+			conn, err := testConnectAndJoin(ctx, s, wsURL, tableID, playerID, name, 0, 0)
+			if err != nil {
+				t.Fatalf("%s failed to join: %v", name, err)
+			}
+			go drainConn(conn)
+			synctest.Wait()
+			return conn
+		}
+		var table *game.Table
+		findPlayer := func(playerID string) *game.Player {
+			for _, p := range table.Players {
+				if p.ID == playerID {
+					return p
+				}
+			}
+			return nil
+		}
+
+		conn1 := join("p1", "Alice")
+		conn2 := join("p2", "Bob")
+		startMsg, _ := game.NewWsMessage(game.MsgTypeStart, nil)
+		_ = wsjson.Write(ctx, conn1, startMsg)
+		synctest.Wait()
+
+		table = testTable(s, tableID)
+		testLock(t, s, table)
+		bob := findPlayer("p2")
+		if bob == nil || len(bob.Hand) == 0 {
+			testUnlock(s, table)
+			t.Fatalf("Expected Bob to be dealt cards")
+		}
+		hand := slices.Clone(bob.Hand)
+		testUnlock(s, table)
+
+		// Bob loses connection: seat and hand are kept.
+		conn2.CloseNow()
+		synctest.Wait()
+		testLock(t, s, table)
+		if p := findPlayer("p2"); p != bob || !bob.Disconnected || !slices.EqualFunc(bob.Hand, hand, slices.Equal) {
+			t.Errorf("Expected Bob to be kept as disconnected with the same hand, got %+v", p)
+		}
+		testUnlock(s, table)
+
+		// Bob reconnects within the grace period.
+		time.Sleep(ReconnectGracePeriod / 2)
+		conn3 := join("p2", "Bob")
+		time.Sleep(ReconnectGracePeriod)
+		synctest.Wait()
+		testLock(t, s, table)
+		if p := findPlayer("p2"); p != bob || bob.Disconnected || !slices.EqualFunc(bob.Hand, hand, slices.Equal) {
+			t.Errorf("Expected Bob to resume the same seat and hand, got %+v", p)
+		}
+		testUnlock(s, table)
+
+		// Bob leaves for good: after the grace period they are removed.
+		conn3.CloseNow()
+		time.Sleep(ReconnectGracePeriod + time.Second)
+		synctest.Wait()
+		testLock(t, s, table)
+		if p := findPlayer("p2"); p != nil {
+			t.Errorf("Expected Bob to be removed after the grace period, got %+v", p)
+		}
+		testUnlock(s, table)
+		if testTable(s, tableID) != table {
+			t.Errorf("Expected table to be kept while Alice is connected")
+		}
+
+		// Alice leaves as well: the table is deleted after the grace period.
+		conn1.CloseNow()
+		synctest.Wait()
+		if testTable(s, tableID) != table {
+			t.Errorf("Expected table to be kept during the grace period")
+		}
+		time.Sleep(ReconnectGracePeriod + time.Second)
+		synctest.Wait()
+		if testTable(s, tableID) != nil {
+			t.Errorf("Expected table to be deleted after the grace period")
+		}
+	})
+}
+
+func TestSpectator(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	started := make(chan *ServerState, 1)
+	go Run(ctx, "", started)
+	s := <-started
+	wsURL := "ws://" + s.Address + "/ws"
+	tableID := "test-spectator"
+
+	conn1, err := testConnectAndJoin(ctx, s, wsURL, tableID, "p1", "Alice", 1, 0)
+	if err != nil {
+		t.Fatalf("Player failed to join: %v", err)
+	}
+	defer conn1.CloseNow()
+	connWatch, err := testConnectAndSendJoin(ctx, s, wsURL, game.JoinMessage{
+		TableID:   tableID,
+		Player:    game.Player{ID: "w1", Name: "Grandma"},
+		Spectator: true,
+	}, 0)
+	if err != nil {
+		t.Fatalf("Spectator failed to join: %v", err)
+	}
+	defer connWatch.CloseNow()
+
+	startMsg, _ := game.NewWsMessage(game.MsgTypeStart, nil)
+	if err := wsjson.Write(ctx, conn1, startMsg); err != nil {
+		t.Fatalf("Failed to send start message: %v", err)
+	}
+
+	// readUntil reads messages until one of the given type is received, and returns its payload.
+	readUntil := func(conn *websocket.Conn, msgType game.MessageType) any {
+		for {
+			var msg game.WsMessage
+			if err := wsjson.Read(ctx, conn, &msg); err != nil {
+				t.Fatalf("Failed to read %s message: %v", msgType, err)
+			}
+			if msg.Type != msgType {
+				continue
+			}
+			p, err := msg.Parse()
+			if err != nil {
+				t.Fatalf("Failed to parse %s message: %v", msgType, err)
+			}
+			return p
+		}
+	}
+
+	// Spectator gets the target card but no top card.
+	update := readUntil(connWatch, game.MsgTypeUpdate).(*game.UpdateMessage)
+	if len(update.TargetCard) == 0 || len(update.TopCard) != 0 {
+		t.Fatalf("Expected spectator to get the target card only, got %+v", update)
+	}
+
+	// Spectator clicks are rejected.
+	clickMsg, _ := game.NewWsMessage(game.MsgTypeClick, game.ClickMessage{Symbol: update.TargetCard[0]})
+	if err := wsjson.Write(ctx, connWatch, clickMsg); err != nil {
+		t.Fatalf("Failed to send click message: %v", err)
+	}
+	reject := readUntil(connWatch, game.MsgTypeReject).(*game.RejectMessage)
+	if reject.Reason != game.RejectSpectator {
+		t.Errorf("Expected spectator rejection, got %+v", reject)
+	}
+
+	// Joining mid-game makes one a spectator.
+	connLate, err := testConnectAndJoin(ctx, s, wsURL, tableID, "p2", "Bob", 2, 0)
+	if err != nil {
+		t.Fatalf("Late player failed to join: %v", err)
+	}
+	defer connLate.CloseNow()
+	for {
+		state := readUntil(connLate, game.MsgTypeState).(*game.StateMessage)
+		if len(state.Table.Spectators) < 2 {
+			continue
+		}
+		if len(state.Table.Players) != 1 || len(state.Table.Spectators) != 2 {
+			t.Fatalf("Expected 1 player and 2 spectators, got %d players and %d spectators",
+				len(state.Table.Players), len(state.Table.Spectators))
+		}
+		break
+	}
+
+	table, unlock := testLockTableID(s, tableID)
+	defer unlock()
+	if len(table.Players) != 1 || len(table.Players[0].Hand) == 0 {
+		t.Errorf("Expected only Alice to be dealt cards, got %s", table)
+	}
+}
+
+func TestTableSettings(t *testing.T) {
+	s := NewServerState()
+	tableID := "test-settings"
+	creator := &game.Player{ID: "p1", Name: "Alice", Symbol: 1}
+	other := &game.Player{ID: "p2", Name: "Bob", Symbol: 2}
+	table := &game.Table{
+		ID:       tableID,
+		Name:     tableID,
+		Players:  []*game.Player{creator, other},
+		Settings: game.DefaultTableSettings(),
+	}
+	s.addTable(table, nil)
+	testLock(t, s, table)
+	defer testUnlock(s, table)
+
+	settings := game.DefaultTableSettings()
+	settings.DeckOrder = 3
+	settings.CardsPerPlayer = 4
+	settings.PlayerSymbolBonus = false
+
+	// Only the creator can change the settings.
+	s.handleSettings(table, other, &game.SettingsMessage{Settings: settings})
+	if table.Settings != game.DefaultTableSettings() {
+		t.Fatalf("Expected settings changed by non-creator to be ignored, got %+v", table.Settings)
+	}
+
+	// Invalid settings are ignored.
+	invalid := settings
+	invalid.MaxPlayers = 1 // There are already 2 players seated.
+	s.handleSettings(table, creator, &game.SettingsMessage{Settings: invalid})
+	if table.Settings != game.DefaultTableSettings() {
+		t.Fatalf("Expected invalid settings to be ignored, got %+v", table.Settings)
+	}
+
+	s.handleSettings(table, creator, &game.SettingsMessage{Settings: settings})
+	if table.Settings != settings {
+		t.Fatalf("Expected settings %+v, got %+v", settings, table.Settings)
+	}
+
+	// Game is dealt with the settings.
+	s.handleGameStart(table, creator, &game.StartMessage{})
+	if len(table.TargetCard) != settings.DeckOrder+1 {
+		t.Errorf("Expected cards with %d symbols, got target card %v", settings.DeckOrder+1, table.TargetCard)
+	}
+	for _, p := range table.Players {
+		if len(p.Hand) != settings.CardsPerPlayer {
+			t.Errorf("Expected %s to be dealt %d cards, got %d", p.Name, settings.CardsPerPlayer, len(p.Hand))
+		}
+	}
+
+	// Settings can't be changed after the game started.
+	s.handleSettings(table, creator, &game.SettingsMessage{Settings: game.DefaultTableSettings()})
+	if table.Settings != settings {
+		t.Errorf("Expected settings not to change after the game started, got %+v", table.Settings)
+	}
+
+	// Player symbol bonus is disabled: matching one's own symbol discards only one card.
+	symbol := other.Hand[0][0]
+	other.Symbol = symbol
+	table.TargetCard = []int{symbol}
+	processTime := time.Now()
+	table.PendingClick = &game.PendingClick{
+		PlayerID:    other.ID,
+		ProcessTime: processTime,
+		Symbol:      symbol,
+		Round:       table.Round,
+	}
+	testUnlock(s, table)
+	s.processWinningClick(table, processTime)
+	testLock(t, s, table)
+	if len(other.Hand) != settings.CardsPerPlayer-1 {
+		t.Errorf("Expected %s to discard only 1 card, got %d cards left", other.Name, len(other.Hand))
+	}
+}
+
+func TestTableMaxPlayers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	started := make(chan *ServerState, 1)
+	go Run(ctx, "", started)
+	s := <-started
+	wsURL := "ws://" + s.Address + "/ws"
+	tableID := "test-max-players"
+
+	conn1, err := testConnectAndJoin(ctx, s, wsURL, tableID, "p1", "Alice", 1, 0)
+	if err != nil {
+		t.Fatalf("Player failed to join: %v", err)
+	}
+	defer conn1.CloseNow()
+
+	settings := game.DefaultTableSettings()
+	settings.MaxPlayers = 1
+	settingsMsg, _ := game.NewWsMessage(game.MsgTypeSettings, game.SettingsMessage{Settings: settings})
+	if err := wsjson.Write(ctx, conn1, settingsMsg); err != nil {
+		t.Fatalf("Failed to send settings message: %v", err)
+	}
+	for {
+		var msg game.WsMessage
+		if err := wsjson.Read(ctx, conn1, &msg); err != nil {
+			t.Fatalf("Failed to read message: %v", err)
+		}
+		if msg.Type != game.MsgTypeState {
+			continue
+		}
+		p, _ := msg.Parse()
+		if p.(*game.StateMessage).Table.Settings.MaxPlayers == 1 {
+			break
+		}
+	}
+
+	conn2, err := testConnectAndJoin(ctx, s, wsURL, tableID, "p2", "Bob", 2, 0)
+	if err != nil {
+		t.Fatalf("Second player failed to join: %v", err)
+	}
+	defer conn2.CloseNow()
+
+	table, unlock := testLockTableID(s, tableID)
+	defer unlock()
+	if len(table.Players) != 1 || len(table.Spectators) != 1 || table.Spectators[0].ID != "p2" {
+		t.Errorf("Expected Bob to join as spectator of a full table, got %s", table)
+	}
+}
+
+func TestGameModes(t *testing.T) {
+	s := NewServerState()
+	tableID := "test-modes"
+	alice := &game.Player{ID: "p1", Name: "Alice", Symbol: 1}
+	table := &game.Table{
+		ID:       tableID,
+		Name:     tableID,
+		Players:  []*game.Player{alice},
+		Settings: game.DefaultTableSettings(),
+	}
+	table.Settings.Mode = game.ModePoisonedGift
+	table.Settings.PenaltyDuration = 0 // So wrong clicks can be tested one after the other.
+	s.addTable(table, nil)
+	testLock(t, s, table)
+
+	// Poisoned Gift needs at least 2 players.
+	s.handleGameStart(table, alice, &game.StartMessage{})
+	if table.Started {
+		t.Fatalf("Expected Poisoned Gift not to start with a single player")
+	}
+
+	bob := &game.Player{ID: "p2", Name: "Bob", Symbol: 2}
+	table.Players = append(table.Players, bob)
+	s.handleGameStart(table, alice, &game.StartMessage{})
+	if !table.Started || len(alice.Hand) != 1 || len(bob.Hand) != 1 {
+		t.Fatalf("Expected Poisoned Gift to start with a single card per player, got %s", table)
+	}
+
+	// Only symbols matching the target card and another player's card are valid.
+	var symbol int
+	for _, sym := range table.TargetCard {
+		if slices.Contains(bob.Hand[0], sym) {
+			symbol = sym
+		} else if reject := s.handleClick(table, alice, &game.ClickMessage{Symbol: sym}); reject == nil || reject.Reason != game.RejectWrongSymbol {
+			t.Fatalf("Expected click on %d to be rejected, got %+v", sym, reject)
+		}
+	}
+
+	// Give the last card of the pile to Bob: game over.
+	table.Pile = nil
+	processTime := time.Now()
+	table.PendingClick = &game.PendingClick{
+		PlayerID:    alice.ID,
+		ProcessTime: processTime,
+		Symbol:      symbol,
+		Round:       table.Round,
+	}
+	testUnlock(s, table)
+	s.processWinningClick(table, processTime)
+	testLock(t, s, table)
+	defer testUnlock(s, table)
+
+	if len(bob.Hand) != 2 || bob.Score != 2 {
+		t.Errorf("Expected Bob to receive the gift, got %d cards (score %d)", len(bob.Hand), bob.Score)
+	}
+	if !table.Finished || table.WinnerID != alice.ID {
+		t.Errorf("Expected game over with Alice as winner, got finished=%t, winner=%q", table.Finished, table.WinnerID)
+	}
+	for _, p := range table.Players {
+		if !p.Finished || p.TimeTaken == 0 {
+			t.Errorf("Expected %s to be finished with a time, got finished=%t, time=%v", p.Name, p.Finished, p.TimeTaken)
+		}
+	}
+	if reject := s.handleClick(table, bob, &game.ClickMessage{Symbol: symbol}); reject == nil || reject.Reason != game.RejectGameOver {
+		t.Errorf("Expected click after game over to be rejected, got %+v", reject)
+	}
+}
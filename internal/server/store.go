@@ -0,0 +1,141 @@
+package server
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"k8s.io/klog/v2"
+)
+
+// TableStore keeps which server instance runs each table, so several instances can share the tables: a client
+// joining a table run by another instance is relayed to it through the Broker, see ServerState.relayTable.
+//
+// Each instance only lists its own tables in the lobby, and keeps its own quick match queue.
+//
+// Implementations must be safe for concurrent use.
+type TableStore interface {
+	// Claim makes instance the owner of the table, unless it already has one, and returns the owner.
+	// Claiming a table already owned by instance returns instance.
+	Claim(tableID, instance string) (owner string, err error)
+
+	// Release forgets the owner of the table, if it is instance. Instances release the tables they delete, and
+	// the tables of an instance that no longer answers, so another can take them over.
+	Release(tableID, instance string) error
+}
+
+// MemoryTableStore is a TableStore kept in memory: it's only shared by the instances of the same process, and
+// with only one instance (the default) every table is run by it.
+type MemoryTableStore struct {
+	mu     sync.Mutex
+	owners map[string]string
+}
+
+var _ TableStore = (*MemoryTableStore)(nil)
+
+// NewMemoryTableStore creates an empty MemoryTableStore.
+func NewMemoryTableStore() *MemoryTableStore {
+	return &MemoryTableStore{owners: make(map[string]string)}
+}
+
+// Claim implements TableStore.
+func (m *MemoryTableStore) Claim(tableID, instance string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if owner, ok := m.owners[tableID]; ok {
+		return owner, nil
+	}
+	m.owners[tableID] = instance
+	return instance, nil
+}
+
+// Release implements TableStore.
+func (m *MemoryTableStore) Release(tableID, instance string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.owners[tableID] == instance {
+		delete(m.owners, tableID)
+	}
+	return nil
+}
+
+// DirTableStore is a TableStore that keeps the owner of each table in a file of a directory, shared by the
+// instances running on the same machine (or on a shared file system).
+//
+// Claims are atomic: the file of the table is created with a hard link, which fails if it already exists.
+type DirTableStore struct {
+	dir string
+}
+
+var _ TableStore = (*DirTableStore)(nil)
+
+// NewDirTableStore creates a DirTableStore that keeps the owners of the tables in dir, creating it if needed.
+func NewDirTableStore(dir string) (*DirTableStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create tables directory: %w", err)
+	}
+	return &DirTableStore{dir: dir}, nil
+}
+
+// path returns the file of the table. Table IDs are chosen by the players, so they are hex encoded.
+func (d *DirTableStore) path(tableID string) string {
+	return filepath.Join(d.dir, hex.EncodeToString([]byte(tableID))+".owner")
+}
+
+// Claim implements TableStore.
+func (d *DirTableStore) Claim(tableID, instance string) (string, error) {
+	path := d.path(tableID)
+	tmp, err := os.CreateTemp(d.dir, ".claim.*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to claim table %s: %w", tableID, err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.WriteString(instance)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to claim table %s: %w", tableID, err)
+	}
+	if err := os.Link(tmp.Name(), path); err == nil {
+		klog.V(1).Infof("DirTableStore: Instance %s claimed table %s", instance, tableID)
+		return instance, nil
+	} else if !errors.Is(err, fs.ErrExist) {
+		return "", fmt.Errorf("failed to claim table %s: %w", tableID, err)
+	}
+	owner, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Released in the meantime: try again.
+		return d.Claim(tableID, instance)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read the owner of table %s: %w", tableID, err)
+	}
+	return string(owner), nil
+}
+
+// Release implements TableStore.
+//
+// The owner is checked before removing the file, so a table released and claimed again by another instance in
+// between would be released too: at worst, both instances then run a table with the same ID.
+func (d *DirTableStore) Release(tableID, instance string) error {
+	path := d.path(tableID)
+	owner, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read the owner of table %s: %w", tableID, err)
+	}
+	if string(owner) != instance {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to release table %s: %w", tableID, err)
+	}
+	return nil
+}
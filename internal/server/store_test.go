@@ -0,0 +1,66 @@
+package server
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestDirTableStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewDirTableStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	// Another store on the same directory, as used by another instance.
+	other, err := NewDirTableStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	const tableID = "Table/../with odd characters"
+	if owner, err := store.Claim(tableID, "one"); err != nil || owner != "one" {
+		t.Fatalf("Expected instance one to claim the table, got %q, %v", owner, err)
+	}
+	if owner, err := store.Claim(tableID, "one"); err != nil || owner != "one" {
+		t.Errorf("Expected claiming again to keep instance one, got %q, %v", owner, err)
+	}
+	if owner, err := other.Claim(tableID, "two"); err != nil || owner != "one" {
+		t.Errorf("Expected the table to be owned by instance one, got %q, %v", owner, err)
+	}
+
+	// Only the owner releases the table.
+	if err := other.Release(tableID, "two"); err != nil {
+		t.Fatalf("Failed to release: %v", err)
+	}
+	if owner, _ := other.Claim(tableID, "two"); owner != "one" {
+		t.Errorf("Expected the table to still be owned by instance one, got %q", owner)
+	}
+	if err := other.Release(tableID, "one"); err != nil {
+		t.Fatalf("Failed to release: %v", err)
+	}
+	if owner, err := other.Claim(tableID, "two"); err != nil || owner != "two" {
+		t.Errorf("Expected instance two to take the table over, got %q, %v", owner, err)
+	}
+	if err := store.Release("unknown", "one"); err != nil {
+		t.Errorf("Expected releasing an unknown table to do nothing, got %v", err)
+	}
+
+	// Concurrent claims agree on a single owner.
+	owners := make([]string, 20)
+	var wg sync.WaitGroup
+	for i := range owners {
+		wg.Go(func() {
+			var err error
+			owners[i], err = store.Claim("contended", string(rune('a'+i)))
+			if err != nil {
+				t.Errorf("Failed to claim: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+	for _, owner := range owners {
+		if owner != owners[0] {
+			t.Fatalf("Expected a single owner, got %q", owners)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/coder/websocket"
+	"github.com/janpfeifer/GoSpot/internal/game"
+	"k8s.io/klog/v2"
+)
+
+// tableRoom is what the server keeps of a table besides its game state: the connections to it, and the lock that
+// guards both. Each table is handled under its own lock, so a busy table doesn't slow down the others, while
+// ServerState.mu only guards the registry of tables.
+//
+// The tables of a tournament share one lock, since their games update the same game.Tournament.
+//
+// Functions with the Locked suffix that take a table assume its lock is held. When both are needed, the table
+// lock is taken first: s.mu is only held for short lookups, never while waiting for a table lock.
+type tableRoom struct {
+	mu      *sync.Mutex
+	clients map[*websocket.Conn]*client
+}
+
+// client is a connection to a table. Its fields are guarded by the lock of the table.
+type client struct {
+	playerID  string
+	spectator bool
+	encoding  game.Encoding // Negotiated in the handshake, see ServerState.welcome.
+	deltas    bool          // Accepts state deltas, see game.StateDeltaMessage.
+	synced    bool          // Was sent the full state, so the next ones can be sent as deltas.
+	out       *outbox
+}
+
+// addTable registers the table with the lock mu, or a new lock if nil, replacing any table with the same ID.
+// It returns the table replaced, if any.
+func (s *ServerState) addTable(table *game.Table, mu *sync.Mutex) (replaced *game.Table) {
+	s.claimTable(table.ID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addTableRegistryLocked(table, mu)
+}
+
+// freeTableID returns tableID if no table has it, or else tableID followed by the first number that makes it free.
+func (s *ServerState) freeTableID(tableID string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	free := tableID
+	for i := 2; s.Tables[free] != nil; i++ {
+		free = fmt.Sprintf("%s %d", tableID, i)
+	}
+	return free
+}
+
+// addTableRegistryLocked is like addTable, but assumes s.mu is locked, and that the table was claimed with
+// claimTable before locking it.
+func (s *ServerState) addTableRegistryLocked(table *game.Table, mu *sync.Mutex) (replaced *game.Table) {
+	if mu == nil {
+		mu = &sync.Mutex{}
+	}
+	replaced = s.Tables[table.ID]
+	if replaced != nil {
+		delete(s.rooms, replaced)
+	}
+	s.Tables[table.ID] = table
+	s.rooms[table] = &tableRoom{mu: mu, clients: make(map[*websocket.Conn]*client)}
+	return replaced
+}
+
+// removeTableLocked removes the table from the registry: it's no longer found, and its pending timers, that
+// check it with lockTable, do nothing.
+func (s *ServerState) removeTableLocked(table *game.Table) {
+	s.mu.Lock()
+	registered := s.Tables[table.ID] == table
+	if registered {
+		delete(s.Tables, table.ID)
+	}
+	delete(s.rooms, table)
+	s.mu.Unlock()
+	if registered {
+		s.releaseTable(table.ID)
+	}
+}
+
+// claimTable records in the TableStore that this instance runs the table. It's called without s.mu locked,
+// since the store may be slow (e.g.: DirTableStore does file I/O).
+func (s *ServerState) claimTable(tableID string) {
+	if owner, err := s.TableStore.Claim(tableID, s.InstanceID); err != nil || owner != s.InstanceID {
+		// Clients joining it from other instances are relayed to its owner instead.
+		klog.Warningf("claimTable: Table %s is run by instance %q (%v), it's only reachable here", tableID, owner, err)
+	}
+}
+
+// releaseTable forgets in the TableStore that this instance runs the table, once removed. Like claimTable,
+// it's called without s.mu locked.
+func (s *ServerState) releaseTable(tableID string) {
+	if err := s.TableStore.Release(tableID, s.InstanceID); err != nil {
+		klog.Errorf("releaseTable: %v", err)
+	}
+	// A table with the same ID may have been created in the meantime: it keeps the claim.
+	s.mu.RLock()
+	recreated := s.Tables[tableID] != nil
+	s.mu.RUnlock()
+	if recreated {
+		s.claimTable(tableID)
+	}
+}
+
+// roomOf returns the room of the table, or nil if the table is no longer registered.
+func (s *ServerState) roomOf(table *game.Table) *tableRoom {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rooms[table]
+}
+
+// lockTable locks the table, and returns its room. It returns nil, without locking anything, if the table is
+// no longer registered (e.g.: it was deleted while waiting for the lock).
+func (s *ServerState) lockTable(table *game.Table) *tableRoom {
+	room := s.roomOf(table)
+	if room == nil {
+		return nil
+	}
+	room.mu.Lock()
+	if s.roomOf(table) != room {
+		room.mu.Unlock()
+		klog.V(1).Infof("lockTable: Table %s was removed while waiting for its lock", table.ID)
+		return nil
+	}
+	return room
+}
+
+// clientsLocked returns the connections to the table, by connection.
+// Assumes the table is locked.
+func (s *ServerState) clientsLocked(table *game.Table) map[*websocket.Conn]*client {
+	if room := s.roomOf(table); room != nil {
+		return room.clients
+	}
+	return nil
+}
+
+// tablesSharing returns the registered tables that share the lock mu, that is, the tables of the same tournament.
+func (s *ServerState) tablesSharing(mu *sync.Mutex) []*game.Table {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var tables []*game.Table
+	for table, room := range s.rooms {
+		if room.mu == mu {
+			tables = append(tables, table)
+		}
+	}
+	return tables
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/janpfeifer/GoSpot/internal/game"
+)
+
+// BenchmarkConcurrentTables measures how many tables the server handles at the same time: on each iteration, the
+// creator of every table changes its settings, and waits for the new state to be broadcast to the table.
+func BenchmarkConcurrentTables(b *testing.B) {
+	const numTables = 200
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	started := make(chan *ServerState, 1)
+	go Run(ctx, NetPipeAddr, started)
+	s := <-started
+	wsURL := "ws://" + s.Address + "/ws"
+
+	// readStates reads the messages of the connection, and sends the CardsPerPlayer of each state to states, if set.
+	readStates := func(conn *websocket.Conn, states chan<- int) {
+		if states != nil {
+			defer close(states)
+		}
+		for {
+			var msg game.WsMessage
+			if err := wsjson.Read(ctx, conn, &msg); err != nil {
+				return
+			}
+			if msg.Type != game.MsgTypeState || states == nil {
+				continue
+			}
+			p, err := msg.Parse()
+			if err != nil {
+				continue
+			}
+			states <- p.(*game.StateMessage).Table.Settings.CardsPerPlayer
+		}
+	}
+
+	type benchTable struct {
+		creator *websocket.Conn
+		states  chan int
+	}
+	tables := make([]benchTable, numTables)
+	for i := range tables {
+		tableID := fmt.Sprintf("bench-%d", i)
+		creator, err := testConnectAndJoin(ctx, s, wsURL, tableID, "p1", "Alice", 1, 0)
+		if err != nil {
+			b.Fatalf("Alice failed to join table %s: %v", tableID, err)
+		}
+		defer creator.CloseNow()
+		other, err := testConnectAndJoin(ctx, s, wsURL, tableID, "p2", "Bob", 2, 0)
+		if err != nil {
+			b.Fatalf("Bob failed to join table %s: %v", tableID, err)
+		}
+		defer other.CloseNow()
+		tables[i] = benchTable{creator: creator, states: make(chan int, 16)}
+		go readStates(creator, tables[i].states)
+		go readStates(other, nil)
+	}
+
+	for i := 0; b.Loop(); i++ {
+		settings := game.DefaultTableSettings()
+		settings.CardsPerPlayer = 6 + i%2
+		settingsMsg, _ := game.NewWsMessage(game.MsgTypeSettings, game.SettingsMessage{Settings: settings})
+		var wg sync.WaitGroup
+		for _, table := range tables {
+			wg.Go(func() {
+				if err := wsjson.Write(ctx, table.creator, settingsMsg); err != nil {
+					b.Errorf("Failed to send settings: %v", err)
+					return
+				}
+				// Older states are skipped, until the one with the new settings.
+				for cards := range table.states {
+					if cards == settings.CardsPerPlayer {
+						return
+					}
+				}
+				b.Errorf("Connection closed before the new settings were received")
+			})
+		}
+		wg.Wait()
+	}
+	b.ReportMetric(float64(b.N*numTables)/b.Elapsed().Seconds(), "updates/s")
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"slices"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/janpfeifer/GoSpot/internal/game"
+)
+
+func TestTeamGame(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		s := NewServerState()
+		tableID := "test-teams"
+		var bots []*game.Player
+		for i, name := range []string{"R2-D2", "C-3PO", "BB-8", "K-2SO"} {
+			bots = append(bots, &game.Player{ID: string(rune('a' + i)), Name: name, Symbol: i + 1, Bot: game.BotHard})
+		}
+		table := &game.Table{
+			ID:       tableID,
+			Name:     tableID,
+			Players:  bots,
+			Settings: game.DefaultTableSettings(),
+		}
+		table.Settings.Mode = game.ModeTeamTower
+		table.Settings.CardsPerPlayer = 5
+		s.addTable(table, nil)
+		teams := func() (teams []int) {
+			for _, p := range table.Players {
+				teams = append(teams, p.Team)
+			}
+			return teams
+		}
+
+		testLock(t, s, table)
+		// The creator can change anyone's team, the other players only their own.
+		s.handleTeam(table, bots[0], &game.TeamMessage{Team: 1})
+		s.handleTeam(table, bots[0], &game.TeamMessage{PlayerID: "c", Team: 1})
+		s.handleTeam(table, bots[1], &game.TeamMessage{Team: 2})
+		s.handleTeam(table, bots[1], &game.TeamMessage{PlayerID: "c", Team: 2})
+		s.handleTeam(table, bots[3], &game.TeamMessage{Team: game.MaxTeams + 1})
+		if want := []int{1, 2, 1, 0}; !slices.Equal(teams(), want) {
+			t.Errorf("Expected teams %v, got %v", want, teams())
+		}
+
+		// Players without a team are assigned one when the game starts.
+		s.handleGameStart(table, bots[0], nil)
+		if want := []int{1, 2, 1, 2}; !slices.Equal(teams(), want) {
+			t.Errorf("Expected teams %v after the start, got %v", want, teams())
+		}
+		if !table.Started {
+			t.Fatalf("Expected the game to start")
+		}
+		testUnlock(s, table)
+
+		time.Sleep(10 * time.Minute)
+		synctest.Wait()
+
+		testLock(t, s, table)
+		defer testUnlock(s, table)
+		if !table.Finished || table.WinnerTeam == 0 {
+			t.Fatalf("Expected the game to be won by a team, got winner team %d", table.WinnerTeam)
+		}
+		for _, p := range table.Players {
+			if p.Team == table.WinnerTeam && len(p.Hand) != 0 {
+				t.Errorf("Expected %s of the winning team to have no cards left, got %d", p.Name, len(p.Hand))
+			}
+		}
+
+		// A game with a single team can't start.
+		table.Started = false
+		for _, p := range table.Players {
+			p.Team = 1
+		}
+		s.handleGameStart(table, bots[0], nil)
+		if table.Started {
+			t.Errorf("Expected the game not to start with a single team")
+		}
+	})
+}
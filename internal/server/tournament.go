@@ -0,0 +1,124 @@
+package server
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+
+	"github.com/janpfeifer/GoSpot/internal/game"
+	"k8s.io/klog/v2"
+)
+
+// startTournament seeds the players of the lobby table across the tables of the first round of a tournament,
+// see game.Tournament. Players are seeded by their lifetime wins.
+// It doesn't need the table locked, and can be run in its own goroutine, so the player store is not accessed with
+// the lock held.
+func (s *ServerState) startTournament(lobby *game.Table, players []game.TournamentPlayer) {
+	wins := make(map[string]int, len(players))
+	for _, p := range players {
+		if p.Bot != "" {
+			continue
+		}
+		stats, err := s.Players.Get(p.ID)
+		if err != nil {
+			klog.Errorf("startTournament: Failed to get the statistics of player %s: %v", p.ID, err)
+			continue
+		}
+		if stats != nil {
+			wins[p.ID] = stats.Wins
+		}
+	}
+	slices.SortStableFunc(players, func(a, b game.TournamentPlayer) int {
+		return cmp.Compare(wins[b.ID], wins[a.ID])
+	})
+
+	room := s.lockTable(lobby)
+	if room == nil {
+		// Table is gone.
+		return
+	}
+	defer room.mu.Unlock()
+	if lobby.Tournament != nil || lobby.Started {
+		// The tournament was started in the meantime.
+		return
+	}
+	t := game.NewTournament(lobby.ID, lobby.Settings, players)
+	klog.Infof("startTournament: Table %s starts a tournament of %d players on %d tables",
+		lobby.ID, len(players), len(t.CurrentRound().Tables))
+	lobby.Tournament = t
+	s.seatTournamentRoundLocked(t, room.mu)
+	s.broadcastStateLocked(lobby)
+}
+
+// seatTournamentRoundLocked creates the tables of the current round of the tournament, changing their IDs if taken.
+// The players of each table are sent there by their client, once they see the new round in the table state.
+// The tables share the lock mu of the tables of the tournament, which must be held by the caller.
+func (s *ServerState) seatTournamentRoundLocked(t *game.Tournament, mu *sync.Mutex) {
+	round := t.CurrentRound()
+	for i := range round.Tables {
+		tt := &round.Tables[i]
+		// The IDs of the tables are chosen by the players, so one of theirs may already have the ID of the round's.
+		tt.TableID = s.freeTableID(tt.TableID)
+		players := make([]game.Player, 0, len(tt.PlayerIDs))
+		for _, id := range tt.PlayerIDs {
+			tp := t.Player(id)
+			players = append(players, game.Player{ID: tp.ID, Name: tp.Name, Symbol: tp.Symbol, Bot: tp.Bot})
+		}
+		table := s.newSeatedTableLocked(tt.TableID, t.Settings, players, mu)
+		table.Tournament = t
+		// Tables with only bots start right away.
+		s.autoStartLocked(table)
+	}
+}
+
+// recordMatchLocked records the game that just finished in the match of the table, if any, and, once the match
+// (or the single game) is over, the finish order in the tournament the table is part of, if any.
+// Assumes the table is locked.
+func (s *ServerState) recordMatchLocked(table *game.Table) {
+	order := table.FinishOrder()
+	ids := make([]string, len(order))
+	for i, p := range order {
+		ids[i] = p.ID
+	}
+	if table.Match != nil {
+		table.Match.Record(order)
+		if !table.Match.Over() {
+			klog.Infof("recordMatchLocked: Table %s played game %d of %d of the match", table.ID, table.Match.Played, table.Match.Games)
+			return
+		}
+		klog.Infof("recordMatchLocked: Table %s match won by %s", table.ID, table.Match.WinnerID)
+		ids = table.Match.Standings()
+	}
+	if table.Tournament != nil {
+		s.recordTournamentLocked(table, ids)
+	}
+}
+
+// recordTournamentLocked records the finish order of a table of the current round of its tournament.
+// If the round is over, it seats the next one. Every table of the tournament is sent the new state.
+// Assumes the table is locked.
+func (s *ServerState) recordTournamentLocked(table *game.Table, order []string) {
+	room := s.roomOf(table)
+	if room == nil {
+		return
+	}
+	t := table.Tournament
+	if t.Record(table.ID, order) {
+		klog.Infof("recordTournamentLocked: Tournament %s starts round %d", t.ID, len(t.Rounds))
+		s.seatTournamentRoundLocked(t, room.mu)
+	}
+	if t.Over() {
+		klog.Infof("recordTournamentLocked: Tournament %s won by %s", t.ID, t.WinnerID)
+	}
+	// Only the tables sharing the lock can be part of the tournament, and be accessed with it.
+	for _, other := range s.tablesSharing(room.mu) {
+		if other.Tournament != t {
+			continue
+		}
+		if t.Over() && other != table && s.deleteIfAbandonedLocked(other) {
+			// Tables left only with bots are no longer needed.
+			continue
+		}
+		s.broadcastStateLocked(other)
+	}
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/janpfeifer/GoSpot/internal/game"
+)
+
+func TestMatchGames(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		s := NewServerState()
+		tableID := "test-match"
+		bot1 := &game.Player{ID: "b1", Name: "R2-D2", Symbol: 1, Bot: game.BotHard}
+		bot2 := &game.Player{ID: "b2", Name: "C-3PO", Symbol: 2, Bot: game.BotEasy}
+		table := &game.Table{
+			ID:       tableID,
+			Name:     tableID,
+			Players:  []*game.Player{bot1, bot2},
+			Settings: game.DefaultTableSettings(),
+		}
+		table.Settings.CardsPerPlayer = 5
+		table.Settings.MatchGames = 5
+		s.addTable(table, nil)
+
+		for played := 1; played <= 5; played++ {
+			testLock(t, s, table)
+			if table.Match != nil && table.Match.Over() {
+				testUnlock(s, table)
+				break
+			}
+			s.handleGameStart(table, bot1, nil)
+			testUnlock(s, table)
+			time.Sleep(10 * time.Minute)
+			synctest.Wait()
+
+			testLock(t, s, table)
+			if !table.Finished || table.Match == nil || table.Match.Played != played {
+				testUnlock(s, table)
+				t.Fatalf("Expected game %d of the match to be over, got match %+v", played, table.Match)
+			}
+			testUnlock(s, table)
+		}
+
+		testLock(t, s, table)
+		defer testUnlock(s, table)
+		match := table.Match
+		if !match.Over() || match.WinnerID != match.Standings()[0] {
+			t.Fatalf("Expected the match to be over, got %+v", match)
+		}
+		if total := match.Points["b1"] + match.Points["b2"]; total != match.Played*(game.FinishPoints(0)+game.FinishPoints(1)) {
+			t.Errorf("Expected %d games worth of points, got %d", match.Played, total)
+		}
+
+		// Starting again starts a new match.
+		s.handleGameStart(table, bot1, nil)
+		if table.Match == match || table.Match.Played != 0 {
+			t.Errorf("Expected a new match to be started")
+		}
+	})
+}
+
+func TestTournamentRounds(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		s := NewServerState()
+		tableID := "test-tournament"
+		alice := &game.Player{ID: "p1", Name: "Alice", Symbol: 1}
+		lobby := &game.Table{
+			ID:       tableID,
+			Name:     tableID,
+			Players:  []*game.Player{alice},
+			Settings: game.DefaultTableSettings(),
+		}
+		lobby.Settings.CardsPerPlayer = 5
+		lobby.Settings.TournamentTableSize = 3
+		lobby.Settings.TournamentAdvance = 1
+		s.addTable(lobby, nil)
+		// The tables of the tournament share the lock of the lobby, which is deleted once the tournament is over.
+		mu := s.roomOf(lobby).mu
+		// Players already created a table with the ID of the first one of the tournament.
+		taken := &game.Table{
+			ID:       tableID + " R1-1",
+			Name:     tableID + " R1-1",
+			Players:  []*game.Player{{ID: "p9", Name: "Zoe", Symbol: 9}},
+			Settings: game.DefaultTableSettings(),
+		}
+		s.addTable(taken, nil)
+
+		mu.Lock()
+		for range 5 {
+			s.handleAddBot(lobby, alice, &game.AddBotMessage{Level: game.BotHard})
+		}
+		s.handleGameStart(lobby, alice, nil)
+		mu.Unlock()
+		synctest.Wait()
+
+		mu.Lock()
+		tournament := lobby.Tournament
+		if tournament == nil || lobby.Started || len(tournament.CurrentRound().Tables) != 2 {
+			mu.Unlock()
+			t.Fatalf("Expected a tournament on 2 tables to be started from the lobby, got %+v", tournament)
+		}
+		if testTable(s, taken.ID) != taken || tournament.CurrentRound().Tables[0].TableID != taken.ID+" 2" {
+			t.Errorf("Expected the first table of the tournament to get a free ID, got %+v", tournament.CurrentRound())
+		}
+		aliceTable := testTable(s, tournament.TableOf(alice.ID))
+		if aliceTable == nil || aliceTable.Tournament != tournament || aliceTable.Started {
+			mu.Unlock()
+			t.Fatalf("Expected Alice's table to wait for her to join, got %v", aliceTable)
+		}
+		for _, tt := range tournament.CurrentRound().Tables {
+			if table := testTable(s, tt.TableID); table != aliceTable && !table.Started {
+				t.Errorf("Expected table %s with only bots to start right away", tt.TableID)
+			}
+		}
+		mu.Unlock()
+
+		// Alice doesn't show up: her table is played by the bots, and the winners of both tables play the final.
+		time.Sleep(s.Matchmaker.JoinTimeout + 30*time.Minute)
+		synctest.Wait()
+
+		mu.Lock()
+		defer mu.Unlock()
+		if !tournament.Over() || len(tournament.Rounds) != 2 {
+			t.Fatalf("Expected the tournament to be over after 2 rounds, got %+v", tournament)
+		}
+		if tournament.Player(tournament.WinnerID).Bot == "" {
+			t.Errorf("Expected a bot to win the tournament, got %q", tournament.WinnerID)
+		}
+		final := testTable(s, tournament.CurrentRound().Tables[0].TableID)
+		if final == nil || !final.Finished || final.WinnerID != tournament.WinnerID {
+			t.Errorf("Expected the final table to be won by the winner of the tournament, got %v", final)
+		}
+	})
+}